@@ -12,6 +12,7 @@ type Address struct {
 	LastTransactionTime uint64
 	TransAsset          uint64
 	TransNep5           uint64
+	TransNft            uint64
 }
 
 // Asset db model.
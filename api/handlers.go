@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"squirrel/block"
+	"squirrel/rpc"
+	"squirrel/smartcontract"
+	"strconv"
+	"strings"
+)
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/status" {
+		respond(w, nil, notFound("not found"))
+		return
+	}
+
+	respond(w, newStatusView(s.reader.GetCounter(), rpc.BestHeight.Get()), nil)
+}
+
+// handleBlock serves /blocks/{index or hash}.
+func (s *server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/blocks/")
+	if len(parts) != 1 {
+		respond(w, nil, notFound("not found"))
+		return
+	}
+
+	v, err := s.getBlock(parts[0])
+	respond(w, v, err)
+}
+
+func (s *server) getBlock(key string) (interface{}, error) {
+	var b *block.Block
+	var err error
+
+	if index, parseErr := strconv.ParseUint(key, 10, 32); parseErr == nil {
+		b, err = s.reader.GetBlock(uint(index))
+	} else {
+		hash, ok := normalizeHash(key)
+		if !ok {
+			return nil, badRequest("invalid block index or hash")
+		}
+		b, err = s.reader.GetBlockByHash(hash)
+	}
+
+	if err != nil || b == nil {
+		return nil, orNotFound(err, "block not found")
+	}
+
+	return newBlockView(b), nil
+}
+
+// handleTx serves /txs/{txid} and /txs/{txid}/disassembly.
+func (s *server) handleTx(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/txs/")
+
+	switch {
+	case len(parts) == 1:
+		v, err := s.getTx(parts[0])
+		respond(w, v, err)
+	case len(parts) == 2 && parts[1] == "disassembly":
+		v, err := s.getTxDisassembly(parts[0])
+		respond(w, v, err)
+	default:
+		respond(w, nil, notFound("not found"))
+	}
+}
+
+func (s *server) getTx(key string) (interface{}, error) {
+	txID, ok := normalizeHash(key)
+	if !ok {
+		return nil, badRequest("invalid txid")
+	}
+
+	t, err := s.reader.GetTx(txID)
+	if err != nil || t == nil {
+		return nil, orNotFound(err, "transaction not found")
+	}
+
+	vins, err := s.reader.GetVins([]string{txID})
+	if err != nil {
+		return nil, err
+	}
+
+	vouts, err := s.reader.GetVouts([]string{txID})
+	if err != nil {
+		return nil, err
+	}
+
+	return newTxView(t, s.reader.GetTxAttrs(txID), vins[txID], vouts[txID]), nil
+}
+
+// getTxDisassembly returns the disassembled script of an invocation transaction.
+func (s *server) getTxDisassembly(key string) (interface{}, error) {
+	txID, ok := normalizeHash(key)
+	if !ok {
+		return nil, badRequest("invalid txid")
+	}
+
+	t, err := s.reader.GetTx(txID)
+	if err != nil || t == nil {
+		return nil, orNotFound(err, "transaction not found")
+	}
+	if t.Script == "" {
+		return nil, notFound("transaction has no script")
+	}
+
+	// Instructions before an invalid one are still served.
+	instructions, err := smartcontract.Disassemble(t.Script)
+	return newDisassemblyView(t.TxID, instructions, err), nil
+}
+
+// handleAddress serves /addresses/{address} and /addresses/{address}/txs.
+func (s *server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/addresses/")
+	if len(parts[0]) == 0 || len(parts[0]) > 128 {
+		respond(w, nil, badRequest("invalid address"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		v, err := s.getAddress(parts[0])
+		respond(w, v, err)
+	case len(parts) == 2 && parts[1] == "txs":
+		v, err := s.getAddrTxs(r, parts[0])
+		respond(w, v, err)
+	default:
+		respond(w, nil, notFound("not found"))
+	}
+}
+
+func (s *server) getAddress(address string) (interface{}, error) {
+	a, err := s.reader.GetAddress(address)
+	if err != nil || a == nil {
+		return nil, orNotFound(err, "address not found")
+	}
+
+	assets, err := s.reader.GetAddrAssets(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAddressView(a, assets), nil
+}
+
+func (s *server) getAddrTxs(r *http.Request, address string) (interface{}, error) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.reader.GetAddrTxs(address, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	views := []*addrTxView{}
+	var lastID uint
+	for _, t := range txs {
+		views = append(views, newAddrTxView(t))
+		lastID = t.ID
+	}
+
+	return newPage(views, len(txs), limit, lastID), nil
+}
+
+// handleNep5 serves /nep5/{asset_id} and /nep5/{asset_id}/transfers.
+func (s *server) handleNep5(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/nep5/")
+	assetID, ok := normalizeAssetID(parts[0])
+	if !ok {
+		respond(w, nil, badRequest("invalid asset id"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		v, err := s.getNep5(assetID)
+		respond(w, v, err)
+	case len(parts) == 2 && parts[1] == "transfers":
+		v, err := s.getNep5Transfers(r, assetID)
+		respond(w, v, err)
+	default:
+		respond(w, nil, notFound("not found"))
+	}
+}
+
+func (s *server) getNep5(assetID string) (interface{}, error) {
+	n, err := s.reader.GetNep5Asset(assetID)
+	if err != nil || n == nil {
+		return nil, orNotFound(err, "asset not found")
+	}
+
+	return newNep5View(n), nil
+}
+
+func (s *server) getNep5Transfers(r *http.Request, assetID string) (interface{}, error) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := s.reader.GetNep5Transfers(assetID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	views := []*transferView{}
+	var lastID uint
+	for _, t := range transfers {
+		views = append(views, newNep5TransferView(t))
+		lastID = t.ID
+	}
+
+	return newPage(views, len(transfers), limit, lastID), nil
+}
+
+// handleNft serves /nft/{asset_id} and /nft/{asset_id}/transfers.
+func (s *server) handleNft(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/nft/")
+	assetID, ok := normalizeAssetID(parts[0])
+	if !ok {
+		respond(w, nil, badRequest("invalid asset id"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		v, err := s.getNft(assetID)
+		respond(w, v, err)
+	case len(parts) == 2 && parts[1] == "transfers":
+		v, err := s.getNftTransfers(r, assetID)
+		respond(w, v, err)
+	default:
+		respond(w, nil, notFound("not found"))
+	}
+}
+
+func (s *server) getNft(assetID string) (interface{}, error) {
+	n, err := s.reader.GetNftAsset(assetID)
+	if err != nil || n == nil {
+		return nil, orNotFound(err, "asset not found")
+	}
+
+	return newNftView(n), nil
+}
+
+func (s *server) getNftTransfers(r *http.Request, assetID string) (interface{}, error) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := s.reader.GetNftTransfers(assetID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	views := []*transferView{}
+	var lastID uint
+	for _, t := range transfers {
+		views = append(views, newNftTransferView(t))
+		lastID = t.ID
+	}
+
+	return newPage(views, len(transfers), limit, lastID), nil
+}
+
+// orNotFound returns err, or a not found error if err is nil.
+func orNotFound(err error, message string) error {
+	if err != nil {
+		return err
+	}
+	return notFound(message)
+}
+
+// normalizeHash returns the stored form('0x' + lowercase hex) of a block hash or txid.
+func normalizeHash(hash string) (string, bool) {
+	hash = strings.ToLower(strings.TrimPrefix(hash, "0x"))
+	if len(hash) != 64 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+
+	return "0x" + hash, true
+}
+
+// normalizeAssetID returns the stored form(lowercase hex without '0x') of a contract asset id.
+func normalizeAssetID(assetID string) (string, bool) {
+	assetID = strings.ToLower(strings.TrimPrefix(assetID, "0x"))
+	if len(assetID) != 40 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(assetID); err != nil {
+		return "", false
+	}
+
+	return assetID, true
+}
@@ -0,0 +1,178 @@
+// Package api serves indexed data over http as json.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"squirrel/db"
+	"squirrel/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Serve starts the api server and blocks until ctx is cancelled.
+// The watchlist is served if reader is also a db.Watchlist.
+func Serve(ctx context.Context, listen string, reader db.Reader) {
+	srv := &http.Server{
+		Addr:         listen,
+		Handler:      NewHandler(reader),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Query api listening on %s\n", listen)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Error.Printf("Query api stopped: %v\n", err)
+	}
+}
+
+// NewHandler returns the http handler of all api endpoints.
+// The watchlist is served if reader is also a db.Watchlist.
+func NewHandler(reader db.Reader) http.Handler {
+	s := &server{reader: reader}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/blocks/", s.handleBlock)
+	mux.HandleFunc("/txs/", s.handleTx)
+	mux.HandleFunc("/addresses/", s.handleAddress)
+	mux.HandleFunc("/nep5/", s.handleNep5)
+	mux.HandleFunc("/nft/", s.handleNft)
+
+	root := http.NewServeMux()
+	root.Handle("/", getOnly(mux))
+
+	if w, ok := reader.(db.Watchlist); ok {
+		s.watchlist = w
+		root.HandleFunc("/watchlist", s.handleWatchlist)
+		root.HandleFunc("/watchlist/", s.handleWatchlist)
+	}
+
+	return recoverer(root)
+}
+
+type server struct {
+	reader    db.Reader
+	watchlist db.Watchlist
+}
+
+// apiError is an error with the http status code to respond.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) error {
+	return &apiError{status: http.StatusBadRequest, message: message}
+}
+
+func notFound(message string) error {
+	return &apiError{status: http.StatusNotFound, message: message}
+}
+
+// page is the response of paginated endpoints.
+// NextCursor is empty if there are no more items.
+type page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+// pageParams parses 'cursor' and 'limit' query parameters.
+func pageParams(r *http.Request) (uint, int, error) {
+	var cursor uint64
+	var limit = defaultLimit
+	var err error
+
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err = strconv.ParseUint(c, 10, 32)
+		if err != nil || cursor == 0 {
+			return 0, 0, badRequest("invalid cursor")
+		}
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, badRequest("limit must be between 1 and " + strconv.Itoa(maxLimit))
+		}
+	}
+
+	return uint(cursor), limit, nil
+}
+
+// newPage returns a page of items, lastID is pk of the last item.
+func newPage(items interface{}, count int, limit int, lastID uint) *page {
+	p := &page{Items: items}
+	if count == limit {
+		p.NextCursor = strconv.FormatUint(uint64(lastID), 10)
+	}
+	return p
+}
+
+// pathParts splits the request path after prefix.
+func pathParts(r *http.Request, prefix string) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/")
+}
+
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "internal error"
+		if e, ok := err.(*apiError); ok {
+			status = e.status
+			message = e.message
+		} else {
+			log.Error.Printf("Query api error: %v\n", err)
+		}
+
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	json.NewEncoder(w).Encode(v)
+}
+
+func getOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			respond(w, nil, &apiError{status: http.StatusMethodNotAllowed, message: "method not allowed"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverer turns panics of db queries into internal errors.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error.Printf("Query api panic on %s: %v\n", r.URL.Path, p)
+				respond(w, nil, &apiError{status: http.StatusInternalServerError, message: "internal error"})
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"squirrel/addr"
+	"squirrel/block"
+	"squirrel/db"
+	"squirrel/log"
+	"squirrel/nep5"
+	"squirrel/nft"
+	"squirrel/tx"
+	"testing"
+)
+
+const (
+	testAssetID = "af7c7328eee5a275a3bcaee2bf0cf662b5e739be"
+	testTxID    = "0x40493fe6739aee8019fcd0c22c87b5811255e4ac5e09fb895119c4de495226a8"
+)
+
+// fakeReader serves 5 transfers of testAssetID and nothing else.
+type fakeReader struct {
+	db.Reader
+}
+
+func (fakeReader) GetBlock(index uint) (*block.Block, error) {
+	return nil, nil
+}
+
+func (fakeReader) GetNep5Transfers(assetID string, cursor uint, limit int) ([]*nep5.Transaction, error) {
+	transfers := []*nep5.Transaction{}
+	if assetID != testAssetID {
+		return transfers, nil
+	}
+
+	for id := uint(5); id > 0 && len(transfers) < limit; id-- {
+		if cursor == 0 || id < cursor {
+			transfers = append(transfers, &nep5.Transaction{ID: id, AssetID: assetID, Value: big.NewFloat(1.5)})
+		}
+	}
+	return transfers, nil
+}
+
+func (fakeReader) GetNftTransfers(assetID string, cursor uint, limit int) ([]*nft.Transaction, error) {
+	panic("db error")
+}
+
+func (fakeReader) GetAddrTxs(address string, cursor uint, limit int) ([]*addr.Tx, error) {
+	return []*addr.Tx{}, nil
+}
+
+func (fakeReader) GetTx(txID string) (*tx.Transaction, error) {
+	if txID != testTxID {
+		return nil, nil
+	}
+	return &tx.Transaction{TxID: txID, Type: "InvocationTransaction", Script: "00c1046e616d6567be39e7b562f60cbfe2aebca375a2e5ee28737caf"}, nil
+}
+
+func get(t *testing.T, path string) (int, map[string]interface{}) {
+	rec := httptest.NewRecorder()
+	NewHandler(fakeReader{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: invalid json response: %v", path, err)
+	}
+	return rec.Code, body
+}
+
+func TestPagination(t *testing.T) {
+	var ids []string
+	cursor := ""
+
+	for i := 0; i < 5; i++ {
+		code, body := get(t, "/nep5/"+testAssetID+"/transfers?limit=2&cursor="+cursor)
+		if code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", code, body)
+		}
+
+		for _, item := range body["items"].([]interface{}) {
+			ids = append(ids, item.(map[string]interface{})["value"].(string))
+		}
+
+		cursor = body["next_cursor"].(string)
+		if cursor == "" {
+			break
+		}
+	}
+
+	if len(ids) != 5 {
+		t.Errorf("Expected 5 transfers from all pages, got %d", len(ids))
+	}
+	if ids[0] != "1.5" {
+		t.Errorf("Expected value '1.5', got '%s'", ids[0])
+	}
+}
+
+func TestTxDisassembly(t *testing.T) {
+	code, body := get(t, "/txs/"+testTxID+"/disassembly")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %v", code, body)
+	}
+
+	instructions := body["instructions"].([]interface{})
+	if len(instructions) != 4 {
+		t.Fatalf("Expected 4 instructions, got %d", len(instructions))
+	}
+
+	appCall := instructions[3].(map[string]interface{})
+	if appCall["opcode"] != "APPCALL" || appCall["script_hash"] != "0x"+testAssetID {
+		t.Errorf("Expected APPCALL of %s, got %v", testAssetID, appCall)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	log.Init()
+	defer os.Remove("error.log")
+
+	cases := map[string]int{
+		"/blocks/100":         http.StatusNotFound,
+		"/blocks/0xzz":        http.StatusBadRequest,
+		"/txs/" + testAssetID: http.StatusBadRequest,
+		"/txs/0x" + testAssetID + testAssetID[:24]:                   http.StatusNotFound,
+		"/txs/" + testTxID + "/script":                               http.StatusNotFound,
+		"/nep5/" + testAssetID + "/transfers?limit=1000":             http.StatusBadRequest,
+		"/nep5/" + testAssetID + "/holders":                          http.StatusNotFound,
+		"/nft/" + testAssetID + "/transfers":                         http.StatusInternalServerError,
+		"/addresses/AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc/txs?cursor=x": http.StatusBadRequest,
+	}
+
+	for path, expected := range cases {
+		code, body := get(t, path)
+		if code != expected {
+			t.Errorf("%s: expected status %d, got %d", path, expected, code)
+		}
+		if _, ok := body["error"]; !ok {
+			t.Errorf("%s: error message missing", path)
+		}
+	}
+}
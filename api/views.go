@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/hex"
+	"squirrel/addr"
+	"squirrel/block"
+	"squirrel/db"
+	"squirrel/nep5"
+	"squirrel/nft"
+	"squirrel/smartcontract"
+	"squirrel/tx"
+	"squirrel/util"
+)
+
+// Amounts are encoded as decimal strings to keep their precision.
+
+type statusView struct {
+	BlockHeight       int  `json:"block_height"`
+	BestHeight        int  `json:"best_height"`
+	LastTxPk          uint `json:"last_tx_pk"`
+	LastAssetTxPk     uint `json:"last_asset_tx_pk"`
+	LastTxPkForNep5   uint `json:"last_tx_pk_for_nep5"`
+	LastTxPkForNft    uint `json:"last_tx_pk_for_nft"`
+	LastTxPkForSC     uint `json:"last_tx_pk_for_sc"`
+	LastTxPkForGas    uint `json:"last_tx_pk_gas_balance"`
+	Nep5TxPkForAddrTx uint `json:"nep5_tx_pk_for_addr_tx"`
+	NftTxPkForAddrTx  uint `json:"nft_tx_pk_for_addr_tx"`
+}
+
+func newStatusView(c db.Counter, bestHeight int) *statusView {
+	return &statusView{
+		BlockHeight:       c.LastBlockIndex,
+		BestHeight:        bestHeight,
+		LastTxPk:          c.LastTxPk,
+		LastAssetTxPk:     c.LastAssetTxPk,
+		LastTxPkForNep5:   c.LastTxPkForNep5,
+		LastTxPkForNft:    c.LastTxPkForNft,
+		LastTxPkForSC:     c.LastTxPkForSC,
+		LastTxPkForGas:    c.LastTxPkGasBalacne,
+		Nep5TxPkForAddrTx: c.Nep5TxPkForAddrTx,
+		NftTxPkForAddrTx:  c.NftTxPkForAddrTx,
+	}
+}
+
+type blockView struct {
+	Index              uint   `json:"index"`
+	Hash               string `json:"hash"`
+	Size               int    `json:"size"`
+	Version            uint   `json:"version"`
+	PreviousBlockHash  string `json:"previous_block_hash"`
+	MerkleRoot         string `json:"merkle_root"`
+	Time               uint64 `json:"time"`
+	Nonce              string `json:"nonce"`
+	NextConsensus      string `json:"next_consensus"`
+	ScriptInvocation   string `json:"script_invocation"`
+	ScriptVerification string `json:"script_verification"`
+	NextBlockHash      string `json:"next_block_hash"`
+}
+
+func newBlockView(b *block.Block) *blockView {
+	return &blockView{
+		Index:              b.Index,
+		Hash:               b.Hash,
+		Size:               b.Size,
+		Version:            b.Version,
+		PreviousBlockHash:  b.PreviousBlockHash,
+		MerkleRoot:         b.MerkleRoot,
+		Time:               b.Time,
+		Nonce:              b.Nonce,
+		NextConsensus:      b.NextConsensus,
+		ScriptInvocation:   b.ScriptInvocation,
+		ScriptVerification: b.ScriptVerification,
+		NextBlockHash:      b.NextBlockhash,
+	}
+}
+
+type txView struct {
+	TxID       string     `json:"txid"`
+	BlockIndex uint       `json:"block_index"`
+	BlockTime  uint64     `json:"block_time"`
+	Size       uint       `json:"size"`
+	Type       string     `json:"type"`
+	Version    uint       `json:"version"`
+	SysFee     string     `json:"sys_fee"`
+	NetFee     string     `json:"net_fee"`
+	Nonce      int64      `json:"nonce"`
+	Script     string     `json:"script"`
+	Gas        string     `json:"gas"`
+	Attributes []attrView `json:"attributes"`
+	Vins       []vinView  `json:"vins"`
+	Vouts      []voutView `json:"vouts"`
+}
+
+type attrView struct {
+	Usage string `json:"usage"`
+	Data  string `json:"data"`
+}
+
+type vinView struct {
+	TxID string `json:"txid"`
+	Vout uint16 `json:"vout"`
+}
+
+type voutView struct {
+	N       uint16 `json:"n"`
+	AssetID string `json:"asset_id"`
+	Value   string `json:"value"`
+	Address string `json:"address"`
+}
+
+func newTxView(t *tx.Transaction, attrs []*tx.TransactionAttribute, vins []*tx.TransactionVin, vouts []*tx.TransactionVout) *txView {
+	v := &txView{
+		TxID:       t.TxID,
+		BlockIndex: t.BlockIndex,
+		BlockTime:  t.BlockTime,
+		Size:       t.Size,
+		Type:       t.Type,
+		Version:    t.Version,
+		SysFee:     util.BigFloatToString(t.SysFee),
+		NetFee:     util.BigFloatToString(t.NetFee),
+		Nonce:      t.Nonce,
+		Script:     t.Script,
+		Gas:        util.BigFloatToString(t.Gas),
+		Attributes: []attrView{},
+		Vins:       []vinView{},
+		Vouts:      []voutView{},
+	}
+
+	for _, attr := range attrs {
+		v.Attributes = append(v.Attributes, attrView{Usage: attr.Usage, Data: attr.Data})
+	}
+	for _, vin := range vins {
+		v.Vins = append(v.Vins, vinView{TxID: vin.TxID, Vout: vin.Vout})
+	}
+	for _, vout := range vouts {
+		v.Vouts = append(v.Vouts, voutView{
+			N:       vout.N,
+			AssetID: vout.AssetID,
+			Value:   util.BigFloatToString(vout.Value),
+			Address: vout.Address,
+		})
+	}
+
+	return v
+}
+
+type disassemblyView struct {
+	TxID         string            `json:"txid"`
+	Instructions []instructionView `json:"instructions"`
+	Text         string            `json:"text"`
+	Error        string            `json:"error,omitempty"`
+}
+
+type instructionView struct {
+	Offset     int    `json:"offset"`
+	OpCode     string `json:"opcode"`
+	Operand    string `json:"operand,omitempty"`
+	JumpTarget *int   `json:"jump_target,omitempty"`
+	ScriptHash string `json:"script_hash,omitempty"`
+}
+
+func newDisassemblyView(txID string, instructions []*smartcontract.Instruction, err error) *disassemblyView {
+	v := &disassemblyView{
+		TxID:         txID,
+		Instructions: []instructionView{},
+		Text:         smartcontract.Format(instructions),
+	}
+	if err != nil {
+		v.Error = err.Error()
+	}
+
+	for _, i := range instructions {
+		iv := instructionView{
+			Offset:  i.Offset,
+			OpCode:  i.OpCode.String(),
+			Operand: hex.EncodeToString(i.Operand),
+		}
+		if target, ok := i.JumpTarget(); ok {
+			iv.JumpTarget = &target
+		}
+		if scriptHash, ok := i.CalledScriptHash(); ok && len(scriptHash) > 0 {
+			iv.ScriptHash = "0x" + util.GetAssetIDFromScriptHash(scriptHash)
+		}
+		v.Instructions = append(v.Instructions, iv)
+	}
+
+	return v
+}
+
+type addressView struct {
+	Address             string        `json:"address"`
+	CreatedAt           uint64        `json:"created_at"`
+	LastTransactionTime uint64        `json:"last_transaction_time"`
+	TransAsset          uint64        `json:"trans_asset"`
+	TransNep5           uint64        `json:"trans_nep5"`
+	TransNft            uint64        `json:"trans_nft"`
+	Balances            []balanceView `json:"balances"`
+}
+
+type balanceView struct {
+	AssetID             string `json:"asset_id"`
+	Balance             string `json:"balance"`
+	Transactions        uint64 `json:"transactions"`
+	LastTransactionTime uint64 `json:"last_transaction_time"`
+}
+
+func newAddressView(a *addr.Address, assets []*addr.Asset) *addressView {
+	v := &addressView{
+		Address:             a.Address,
+		CreatedAt:           a.CreatedAt,
+		LastTransactionTime: a.LastTransactionTime,
+		TransAsset:          a.TransAsset,
+		TransNep5:           a.TransNep5,
+		TransNft:            a.TransNft,
+		Balances:            []balanceView{},
+	}
+
+	for _, asset := range assets {
+		v.Balances = append(v.Balances, balanceView{
+			AssetID:             asset.AssetID,
+			Balance:             util.BigFloatToString(asset.Balance),
+			Transactions:        asset.Transactions,
+			LastTransactionTime: asset.LastTransactionTime,
+		})
+	}
+
+	return v
+}
+
+type addrTxView struct {
+	TxID      string `json:"txid"`
+	BlockTime uint64 `json:"block_time"`
+	AssetType string `json:"asset_type"`
+}
+
+func newAddrTxView(t *addr.Tx) *addrTxView {
+	return &addrTxView{
+		TxID:      t.TxID,
+		BlockTime: t.BlockTime,
+		AssetType: t.AssetType,
+	}
+}
+
+type tokenView struct {
+	AssetID          string `json:"asset_id"`
+	AdminAddress     string `json:"admin_address"`
+	Name             string `json:"name"`
+	Symbol           string `json:"symbol"`
+	Decimals         uint8  `json:"decimals"`
+	TotalSupply      string `json:"total_supply"`
+	TxID             string `json:"txid"`
+	BlockIndex       uint   `json:"block_index"`
+	BlockTime        uint64 `json:"block_time"`
+	Addresses        uint64 `json:"addresses"`
+	HoldingAddresses uint64 `json:"holding_addresses"`
+	Transfers        uint64 `json:"transfers"`
+}
+
+func newNep5View(n *nep5.Nep5) *tokenView {
+	return &tokenView{
+		AssetID:          n.AssetID,
+		AdminAddress:     n.AdminAddress,
+		Name:             n.Name,
+		Symbol:           n.Symbol,
+		Decimals:         n.Decimals,
+		TotalSupply:      util.BigFloatToString(n.TotalSupply),
+		TxID:             n.TxID,
+		BlockIndex:       n.BlockIndex,
+		BlockTime:        n.BlockTime,
+		Addresses:        n.Addresses,
+		HoldingAddresses: n.HoldingAddresses,
+		Transfers:        n.Transfers,
+	}
+}
+
+func newNftView(n *nft.Nft) *tokenView {
+	return &tokenView{
+		AssetID:          n.AssetID,
+		AdminAddress:     n.AdminAddress,
+		Name:             n.Name,
+		Symbol:           n.Symbol,
+		Decimals:         n.Decimals,
+		TotalSupply:      util.BigFloatToString(n.TotalSupply),
+		TxID:             n.TxID,
+		BlockIndex:       n.BlockIndex,
+		BlockTime:        n.BlockTime,
+		Addresses:        n.Addresses,
+		HoldingAddresses: n.HoldingAddresses,
+		Transfers:        n.Transfers,
+	}
+}
+
+type transferView struct {
+	TxID       string `json:"txid"`
+	AssetID    string `json:"asset_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TokenID    string `json:"token_id,omitempty"`
+	Value      string `json:"value"`
+	BlockIndex uint   `json:"block_index"`
+	BlockTime  uint64 `json:"block_time"`
+}
+
+func newNep5TransferView(t *nep5.Transaction) *transferView {
+	return &transferView{
+		TxID:       t.TxID,
+		AssetID:    t.AssetID,
+		From:       t.From,
+		To:         t.To,
+		Value:      util.BigFloatToString(t.Value),
+		BlockIndex: t.BlockIndex,
+		BlockTime:  t.BlockTime,
+	}
+}
+
+func newNftTransferView(t *nft.Transaction) *transferView {
+	return &transferView{
+		TxID:       t.TxID,
+		AssetID:    t.AssetID,
+		From:       t.From,
+		To:         t.To,
+		TokenID:    t.TokenID,
+		Value:      util.BigFloatToString(t.Value),
+		BlockIndex: t.BlockIndex,
+		BlockTime:  t.BlockTime,
+	}
+}
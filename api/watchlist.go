@@ -0,0 +1,188 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"squirrel/config"
+	"squirrel/watch"
+	"strings"
+)
+
+// maxWatchBody is the max size of request bodies of /watchlist.
+const maxWatchBody = 64 * 1024
+
+// watchRequest is the body of PUT /watchlist/{address}.
+type watchRequest struct {
+	AssetIDs []string `json:"asset_ids"`
+	Webhook  string   `json:"webhook"`
+}
+
+type watchEventView struct {
+	*watch.Event
+	Webhook       string `json:"webhook"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	DeliveredAt   int64  `json:"delivered_at"`
+	LastError     string `json:"last_error"`
+}
+
+func newWatchEventView(e *watch.Event) *watchEventView {
+	return &watchEventView{
+		Event:         e,
+		Webhook:       e.Webhook,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		DeliveredAt:   e.DeliveredAt,
+		LastError:     e.LastError,
+	}
+}
+
+// handleWatchlist serves /watchlist, /watchlist/{address} and /watchlist/{address}/events,
+// all of them require the token of config 'watchlist.token'.
+func (s *server) handleWatchlist(w http.ResponseWriter, r *http.Request) {
+	c := config.GetWatchlistConfig()
+	if !c.Enabled {
+		respond(w, nil, notFound("watchlist is disabled"))
+		return
+	}
+	if c.Token == "" {
+		respond(w, nil, &apiError{status: http.StatusForbidden, message: "watchlist api is disabled without a token"})
+		return
+	}
+	if !authorized(r, c.Token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		respond(w, nil, &apiError{status: http.StatusUnauthorized, message: "unauthorized"})
+		return
+	}
+
+	parts := pathParts(r, "/watchlist")
+	var v interface{}
+	var err error
+
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		if !allowMethods(w, r, http.MethodGet) {
+			return
+		}
+		v, err = watch.Load(s.watchlist)
+	case len(parts) == 1:
+		if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodPut {
+			v, err = s.putWatch(r, parts[0])
+		} else {
+			v, err = s.deleteWatch(parts[0])
+		}
+	case len(parts) == 2 && parts[1] == "events":
+		if !allowMethods(w, r, http.MethodGet) {
+			return
+		}
+		v, err = s.getWatchEvents(r, parts[0])
+	default:
+		err = notFound("not found")
+	}
+
+	respond(w, v, err)
+}
+
+func authorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
+
+// allowMethods responds with 405 and returns false if the request method is not one of methods.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	respond(w, nil, &apiError{status: http.StatusMethodNotAllowed, message: "method not allowed"})
+	return false
+}
+
+// watchedByConfig tells if the address is watched by config, which cannot be changed through the api.
+func watchedByConfig(address string) bool {
+	for _, a := range config.GetWatchlistConfig().Addresses {
+		if a.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *server) putWatch(r *http.Request, address string) (interface{}, error) {
+	var req watchRequest
+	body := http.MaxBytesReader(nil, r.Body, maxWatchBody)
+	if err := json.NewDecoder(body).Decode(&req); err != nil && err != io.EOF {
+		return nil, badRequest("invalid request body")
+	}
+
+	e := &watch.Entry{Address: address, AssetIDs: req.AssetIDs, Webhook: req.Webhook}
+	if e.AssetIDs == nil {
+		e.AssetIDs = []string{}
+	}
+	if !e.Valid() {
+		return nil, badRequest("invalid address, asset ids or webhook")
+	}
+	if watchedByConfig(address) {
+		return nil, &apiError{status: http.StatusConflict, message: "address is watched by config"}
+	}
+
+	if err := s.watchlist.PutWatchedAddress(e); err != nil {
+		return nil, err
+	}
+	if _, err := watch.Load(s.watchlist); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (s *server) deleteWatch(address string) (interface{}, error) {
+	if watchedByConfig(address) {
+		return nil, &apiError{status: http.StatusConflict, message: "address is watched by config"}
+	}
+
+	deleted, err := s.watchlist.DeleteWatchedAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, notFound("address is not watched")
+	}
+	if _, err := watch.Load(s.watchlist); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"address": address, "deleted": true}, nil
+}
+
+func (s *server) getWatchEvents(r *http.Request, address string) (interface{}, error) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.watchlist.GetWatchEvents(address, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	views := []*watchEventView{}
+	var lastID uint
+	for _, e := range events {
+		views = append(views, newWatchEventView(e))
+		lastID = e.ID
+	}
+
+	return newPage(views, len(events), limit, lastID), nil
+}
@@ -54,6 +54,19 @@ func (b *BlockBuffer) GetNextPending() int {
 	return b.nextHeight
 }
 
+// GetNextPendings returns the next n fetching block indexes.
+func (b *BlockBuffer) GetNextPendings(n int) []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	indexes := make([]int, n)
+	for i := range indexes {
+		b.nextHeight++
+		indexes[i] = b.nextHeight
+	}
+	return indexes
+}
+
 // Put adds the given block into buffer and update maxHeight.
 func (b *BlockBuffer) Put(block *rpc.RawBlock) {
 	b.mu.Lock()
@@ -72,3 +85,14 @@ func (b *BlockBuffer) Size() int {
 
 	return len(b.buffer)
 }
+
+// Reset drops all buffered blocks and rewinds the buffer to the given height,
+// used after a chain reorganisation was rolled back.
+func (b *BlockBuffer) Reset(height int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maxHeight = height
+	b.nextHeight = height
+	b.buffer = make(map[int]*rpc.RawBlock)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"squirrel/config"
+	"squirrel/db"
+	"squirrel/log"
+	"squirrel/rpc"
+	"strings"
+	"text/tabwriter"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, `Usage: squirrel [command] [flags]
+
+Commands:
+  run                   index the chain with the selected tasks(default)
+  migrate               upgrade the database schema
+  repair-nep5-decimals  rescale nep5 transfers stored with 8 decimals
+  reset [-yes] <task>   delete all data of a task so it starts from beginning: %s
+  verify                report counts and sums which differ from the stored records
+  status                print counters of tasks against the chain height
+  config check          check the config file
+
+Flags, -config applies to all commands and others to run:
+`, strings.Join(db.ResetTasks(), ", "))
+	flag.PrintDefaults()
+}
+
+// usageError prints the error with usage and exits with status 2, like invalid flags.
+func usageError(format string, args ...interface{}) {
+	fmt.Fprintf(flag.CommandLine.Output(), format+"\n\n", args...)
+	flag.Usage()
+	os.Exit(2)
+}
+
+// commandFlags returns the flags of a command, -config is accepted after every command.
+func commandFlags(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "Path of the config file")
+	return fs
+}
+
+// parseCommandFlags parses flags of a command which takes no arguments.
+func parseCommandFlags(command string, args []string) {
+	fs := commandFlags(command)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError("unexpected arguments of %s %v", command, fs.Args())
+	}
+}
+
+// resetTask executes the reset statements of a task in one transaction,
+// they are only printed unless confirmed by -yes.
+func resetTask(args []string) {
+	fs := commandFlags("reset")
+	confirmed := fs.Bool("yes", false, "Execute the statements, which are only printed otherwise")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		panic(fmt.Errorf("reset requires one task: %s", strings.Join(db.ResetTasks(), ", ")))
+	}
+
+	task := fs.Arg(0)
+	stmts, ok := db.ResetStatements(task)
+	if !ok {
+		panic(fmt.Errorf("task '%s' cannot be reset, must be one of %s", task, strings.Join(db.ResetTasks(), ", ")))
+	}
+
+	if !*confirmed {
+		fmt.Printf("Statements to reset task %s:\n\n", task)
+		for _, stmt := range stmts {
+			fmt.Printf("%s;\n", stmt)
+		}
+		fmt.Printf("\nStop the indexer and run again with -yes to execute them in one transaction.\n")
+		return
+	}
+
+	if err := openStore().ResetTask(task); err != nil {
+		panic(err)
+	}
+
+	log.Printf("Task %s is reset, it starts from beginning on next run\n", task)
+}
+
+// verify prints the mismatches found by the checks of sqls/verification.sql,
+// and exits with status 1 if there is any.
+func verify() {
+	store := openStore()
+	if config.GetNetwork() == config.NetworkN3 {
+		panic(fmt.Errorf("verify does not support Neo N3"))
+	}
+
+	mismatches, err := store.Verify()
+	if err != nil {
+		panic(err)
+	}
+
+	if len(mismatches) == 0 {
+		fmt.Println("No mismatch found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSUBJECT\tSTORED\tCOMPUTED")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Check, m.Subject, m.Stored, m.Computed)
+	}
+	w.Flush()
+
+	os.Exit(1)
+}
+
+// status prints counters of tasks, with the rpc best height or the highest tx pk they are heading to.
+func status() {
+	store := openStore()
+	bestHeight := rpc.RefreshServers(context.Background())
+
+	profile := config.GetProfile()
+	fmt.Printf("Network: %s %s(magic=%d)\n\n", config.GetNetwork(), profile.Name, profile.Magic)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tCOUNTER\tVALUE\tTARGET")
+
+	if config.GetNetwork() == config.NetworkN3 {
+		fmt.Fprintf(w, "block\tn3_block\t%d\t%d\n", store.GetN3LastHeight(), bestHeight)
+		w.Flush()
+		return
+	}
+
+	c := store.GetCounter()
+	maxTxPk := store.GetHighestTxPk()
+
+	fmt.Fprintf(w, "block\tlast_block_index\t%d\t%d\n", c.LastBlockIndex, bestHeight)
+	fmt.Fprintf(w, "tx\tlast_tx_pk\t%d\t%d\n", c.LastTxPk, maxTxPk)
+	fmt.Fprintf(w, "asset_tx\tlast_asset_tx_pk\t%d\t%d\n", c.LastAssetTxPk, maxTxPk)
+	fmt.Fprintf(w, "gas_balance\tlast_tx_pk_gas_balance\t%d\t%d\n", c.LastTxPkGasBalacne, maxTxPk)
+	fmt.Fprintf(w, "nep5\tlast_tx_pk_for_nep5\t%d\t%d\n", c.LastTxPkForNep5, maxTxPk)
+	fmt.Fprintf(w, "nep5\tapp_log_idx\t%d\t\n", c.AppLogIdx)
+	fmt.Fprintf(w, "nft\tlast_tx_pk_for_nft\t%d\t%d\n", c.LastTxPkForNft, maxTxPk)
+	fmt.Fprintf(w, "nft\tnft_app_log_idx\t%d\t\n", c.NftAppLogIdx)
+	fmt.Fprintf(w, "sc\tlast_tx_pk_for_sc\t%d\t%d\n", c.LastTxPkForSC, maxTxPk)
+	fmt.Fprintf(w, "addr_tx\tnep5_tx_pk_for_addr_tx\t%d\t\n", c.Nep5TxPkForAddrTx)
+	fmt.Fprintf(w, "addr_tx\tnft_tx_pk_for_addr_tx\t%d\t\n", c.NftTxPkForAddrTx)
+	w.Flush()
+}
+
+// checkConfig reports if the config file is valid, and exits with status 1 if not.
+func checkConfig(args []string) {
+	if len(args) == 0 || args[0] != "check" {
+		usageError("unknown config command %v, must be 'config check'", args)
+	}
+	parseCommandFlags("config check", args[1:])
+
+	config.SetFile(configPath)
+	path, err := config.Check()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Config %s is valid.\n", path)
+}
@@ -5,32 +5,176 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
 	"squirrel/log"
 	"strings"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
+// Networks supported by config 'network'.
+const (
+	NetworkLegacy = "legacy"
+	NetworkN3     = "n3"
+)
+
+// Tasks which can be enabled in config 'tasks'.
+const (
+	// TaskBlock stores blocks and transactions, it is the only task of Neo N3.
+	TaskBlock = "block"
+	// TaskTx applies utxo of stored transactions to addresses and assets.
+	TaskTx = "tx"
+	// TaskNep5 stores nep5 assets and transfers.
+	TaskNep5 = "nep5"
+	// TaskNft stores nft assets and transfers.
+	TaskNft = "nft"
+	// TaskAddrTx records nep5 and nft transfers of addresses.
+	TaskAddrTx = "addr_tx"
+	// TaskAssetTx records global assets involved in transactions of addresses.
+	TaskAssetTx = "asset_tx"
+	// TaskGasBalance records daily GAS balance changes of addresses.
+	TaskGasBalance = "gas_balance"
+	// TaskSC stores smart contracts created by transactions.
+	TaskSC = "sc"
+)
+
+// TaskNames lists all tasks in the order they are started.
+var TaskNames = []string{TaskBlock, TaskTx, TaskNep5, TaskNft, TaskAddrTx, TaskAssetTx, TaskGasBalance, TaskSC}
+
+// defaultTasks are enabled if config 'tasks' is missing.
+var defaultTasks = []string{TaskBlock, TaskTx, TaskNep5, TaskAddrTx}
+
 type config struct {
-	// MySQL configs.
+	// Database configs.
+	// Driver is one of 'mysql'(default), 'postgres' or 'sqlite'.
+	// For sqlite, Database is the path of the database file and other fields are ignored.
+	Driver   string
 	User     string
-	Password string
+	Password string `secret:"true"`
 	Hostname string
 	Port     string
 	Database string
+	// SSLMode is the sslmode parameter of postgres connections, default 'disable'.
+	SSLMode string `mapstructure:"sslmode"`
 
 	// Label sets log output prefix.
 	Label string
 
-	RPCs []string `mapstructure:"rpc_url"`
+	// Log sets level, format and destination of log entries.
+	Log LogConfig `mapstructure:"log"`
+
+	// Network is the protocol of the indexed chain, one of 'legacy'(default, Neo 2.x) or 'n3'.
+	// Both use the same database, N3 data is stored in tables prefixed with 'n3_'.
+	Network string
+
+	// Profile selects constants of the indexed network, such as asset ids and magic number.
+	Profile ProfileConfig `mapstructure:"profile"`
+
+	// RPCs are either plain urls or objects with url, priority and weight.
+	RPCs []RPCConfig `mapstructure:"rpc_url"`
+
+	// RPC sets timeouts and retries of rpc requests.
+	RPC RPCCallConfig `mapstructure:"rpc"`
 
 	// Workers sets the number of goroutines that will be created for data processing.
 	// Recommend value: 3.
 	Workers int
 
-	// AliyunMail is an optional config which will be used in mail alert package.
+	// Tasks enables tasks by name with their worker counts and channel sizes.
+	// Block, tx, nep5 and addr_tx are enabled if missing.
+	Tasks map[string]TaskConfig `mapstructure:"tasks"`
+
+	// AliyunMail is an optional config of the aliyun mail notifier, which is enabled by the '-mail' flag.
 	AliyunMail AliyunMailConfig `mapstructure:"aliyun_mail"`
+
+	// Notify enables alert notifiers, any of them can be enabled together.
+	Notify NotifyConfig `mapstructure:"notify"`
+
+	// API is an optional http server which serves indexed data.
+	API APIConfig `mapstructure:"api"`
+
+	// Metrics is an optional http server which serves prometheus metrics.
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	// Health sets thresholds of /healthz and /readyz served by the metrics server.
+	Health HealthConfig `mapstructure:"health"`
+
+	// Watchlist notifies transfers of watched addresses to webhooks.
+	Watchlist WatchlistConfig `mapstructure:"watchlist"`
+}
+
+// RPCConfig is the struct for rpc server configs.
+// Requests go to available servers of the lowest Priority,
+// and are spread among them in proportion to Weight and their health.
+type RPCConfig struct {
+	URL string
+	// Priority defaults to 0, servers of higher values are only used as fallbacks.
+	Priority int
+	// Weight defaults to 1, servers of weight 0 are only used if no server of the same priority has a positive weight.
+	Weight int
+}
+
+// RPCCallConfig is the struct for rpc request configs.
+type RPCCallConfig struct {
+	RPCMethodConfig `mapstructure:",squash"`
+	// Methods overrides the defaults for rpc methods, e.g. 'getblock'.
+	Methods map[string]RPCMethodConfig
+}
+
+// RPCMethodConfig is the struct for timeout and retries of rpc requests.
+type RPCMethodConfig struct {
+	// Timeout is the number of seconds to wait for each attempt, default 10.
+	Timeout int
+	// MaxRetries is the number of attempts after the first failed one, default 5.
+	MaxRetries int `mapstructure:"max_retries"`
+}
+
+// LogConfig is the struct for log configs, changes take effect without restart.
+type LogConfig struct {
+	// Level is one of 'debug', 'info'(default), 'warn' or 'error'.
+	Level string
+	// Format is 'text'(default) or 'json'.
+	Format string
+	// Output is 'stdout'(default), 'stderr' or a file path.
+	Output string
+	// ErrorFile is a file error entries are also appended to, default 'error.log'.
+	ErrorFile string `mapstructure:"error_file"`
+}
+
+// TaskConfig is the struct for task configs, changes take effect without restart.
+type TaskConfig struct {
+	Enabled bool
+	// Workers is the number of goroutines fetching blocks or application logs for block, nep5 and nft,
+	// default 'workers' for block and 4 for the others.
+	Workers int
+	// ChannelSize is the capacity of queues between goroutines of the task,
+	// default 5000 or 10 batches of blocks for Neo N3.
+	ChannelSize int `mapstructure:"channel_size"`
+}
+
+// APIConfig is the struct for query api configs.
+type APIConfig struct {
+	Enabled bool
+	// Listen is the tcp address of the server, e.g. '127.0.0.1:8080'.
+	Listen string
+}
+
+// MetricsConfig is the struct for prometheus metrics configs.
+type MetricsConfig struct {
+	Enabled bool
+	// Listen is the tcp address of the server, e.g. '127.0.0.1:9100'.
+	Listen string
+}
+
+// HealthConfig is the struct for health check configs.
+type HealthConfig struct {
+	// MaxLag is the number of blocks a task may fall behind the rpc best height while ready, default 20.
+	MaxLag int `mapstructure:"max_lag"`
+	// StallTimeout is the number of seconds a task with pending work may make no progress, default 600.
+	StallTimeout int `mapstructure:"stall_timeout"`
 }
 
 // AliyunMailConfig is the struct for aliyun mail configs.
@@ -38,72 +182,249 @@ type AliyunMailConfig struct {
 	AccountName     string
 	Region          string
 	AccessKeyID     string
-	AccessKeySecret string
+	AccessKeySecret string `secret:"true"`
 	Receiver        []string
+	RateLimit       RateLimitConfig `mapstructure:"rate_limit"`
 }
 
-var cfg config
+// NotifyConfig is the struct for alert notifier configs.
+type NotifyConfig struct {
+	// StateFile keeps rate limiting state across restarts, default 'notify_state.json'.
+	StateFile string `mapstructure:"state_file"`
+	Stdout    StdoutConfig
+	SMTP      SMTPConfig `mapstructure:"smtp"`
+	Webhook   WebhookConfig
+}
+
+// RateLimitConfig allows at most Count alerts per Period seconds, default 10 per hour.
+type RateLimitConfig struct {
+	Count  int
+	Period int
+}
+
+// StdoutConfig is the struct for stdout notifier configs.
+type StdoutConfig struct {
+	Enabled   bool
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// SMTPConfig is the struct for smtp notifier configs.
+type SMTPConfig struct {
+	Enabled bool
+	Host    string
+	// Port defaults to 587.
+	Port      int
+	Username  string
+	Password  string `secret:"true"`
+	From      string
+	To        []string
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// WebhookConfig is the struct for webhook notifier configs.
+// Alerts are posted to URL as json.
+type WebhookConfig struct {
+	Enabled   bool
+	URL       string
+	Headers   map[string]string `secret:"true"`
+	RateLimit RateLimitConfig   `mapstructure:"rate_limit"`
+}
+
+var (
+	// cfg is replaced as a whole by valid configs, and never modified once set.
+	cfg   = &config{}
+	cfgMu sync.RWMutex
+
+	changeMu       sync.Mutex
+	changeHandlers []func()
+)
+
+// current returns the loaded configs.
+func current() *config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return cfg
+}
+
+// set replaces the loaded configs with c.
+func set(c *config) {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	cfg = c
+}
 
 // Load creates a single.
 func Load(display bool) {
-	viper.SetConfigName("config")
-	viper.AddConfigPath("./config")
-	// Incase test cases require loading configs.
-	viper.AddConfigPath("../config")
+	searchConfig()
 
-	if err := load(display); err != nil {
+	c, err := load(display)
+	if err != nil {
 		panic(err)
 	}
 
-	if err := check(); err != nil {
+	if err := c.check(); err != nil {
 		panic(err)
 	}
 
-	update()
+	c.update()
+	set(c)
 
-	log.UpdatePrefix(GetLabel())
+	if err := applyLog(); err != nil {
+		panic(err)
+	}
 
 	viper.WatchConfig()
 	viper.OnConfigChange(onConfigChange)
 }
 
-func load(display bool) error {
-	err := viper.ReadInConfig()
+// Check loads configs and checks them without starting to watch changes,
+// and returns the path of the config file.
+func Check() (string, error) {
+	searchConfig()
+
+	c, err := load(false)
 	if err != nil {
-		return err
+		return viper.ConfigFileUsed(), err
+	}
+
+	return viper.ConfigFileUsed(), c.check()
+}
+
+func searchConfig() {
+	bindEnv()
+
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+		return
 	}
 
-	err = viper.Unmarshal(&cfg)
+	viper.SetConfigName("config")
+	viper.AddConfigPath("./config")
+	// Incase test cases require loading configs.
+	viper.AddConfigPath("../config")
+}
+
+// LoadFile loads configs from the given file, changes of the file are not watched.
+func LoadFile(path string) error {
+	bindEnv()
+	viper.SetConfigFile(path)
+
+	c, err := load(false)
 	if err != nil {
 		return err
 	}
 
+	if err := c.check(); err != nil {
+		return err
+	}
+
+	c.update()
+	set(c)
+
+	return applyLog()
+}
+
+// load reads configs from the config file and the environment, they are not checked nor in effect yet.
+func load(display bool) (*config, error) {
+	err := viper.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &config{}
+	err = viper.Unmarshal(c, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		toRPCConfig,
+		stringToWatchConfig,
+	)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readSecretFiles(c); err != nil {
+		return nil, err
+	}
+
 	if display {
-		configContent, _ := json.MarshalIndent(cfg, "", "    ")
+		configContent, _ := json.MarshalIndent(redacted(reflect.ValueOf(*c)).Interface(), "", "    ")
 		log.Println(string(configContent))
 	}
 
-	return nil
+	return c, nil
+}
+
+// toRPCConfig allows plain urls in 'rpc_url', and sets weight to 1 if omitted,
+// since weight 0 is a valid setting.
+func toRPCConfig(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(RPCConfig{}) {
+		return data, nil
+	}
+
+	switch v := data.(type) {
+	case string:
+		return RPCConfig{URL: v, Weight: 1}, nil
+	case map[string]interface{}:
+		for k := range v {
+			if strings.EqualFold(k, "weight") {
+				return data, nil
+			}
+		}
+
+		m := map[string]interface{}{"weight": 1}
+		for k, value := range v {
+			m[k] = value
+		}
+		return m, nil
+	default:
+		return data, nil
+	}
+}
+
+// applyLog configures log output with the label as prefix.
+func applyLog() error {
+	log.UpdatePrefix(GetLabel())
+
+	c := GetLogConfig()
+	return log.Configure(log.Options{
+		Level:     c.Level,
+		Format:    c.Format,
+		Output:    c.Output,
+		ErrorFile: c.ErrorFile,
+	})
 }
 
-func update() {
-	for i := 0; i < len(cfg.RPCs); i++ {
-		rpc := cfg.RPCs[i]
-		if !strings.HasPrefix(rpc, "http") {
-			cfg.RPCs[i] = "http://" + rpc
+func (c *config) update() {
+	for i := 0; i < len(c.RPCs); i++ {
+		rpc := &c.RPCs[i]
+		if !strings.HasPrefix(rpc.URL, "http") {
+			rpc.URL = "http://" + rpc.URL
 		}
 	}
 }
 
-// GetDbConnStr returns mysql connection string.
+// GetDbDriver returns the configured database driver.
+func GetDbDriver() string {
+	return current().Driver
+}
+
+// GetDbConnStr returns connection string of the configured database.
 func GetDbConnStr() string {
+	c := current()
+	switch c.Driver {
+	case "postgres":
+		return c.postgresConnStr()
+	case "sqlite":
+		return c.sqliteConnStr()
+	}
+
 	str := fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s",
-		cfg.User,
-		cfg.Password,
-		cfg.Hostname,
-		cfg.Port,
-		cfg.Database,
+		c.User,
+		c.Password,
+		c.Hostname,
+		c.Port,
+		c.Database,
 	)
 
 	params := []string{
@@ -121,69 +442,366 @@ func GetDbConnStr() string {
 	return str
 }
 
+func (c *config) postgresConnStr() string {
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%s", c.Hostname, c.Port),
+		Path:     c.Database,
+		RawQuery: "sslmode=" + url.QueryEscape(sslMode),
+	}
+
+	return u.String()
+}
+
+// sqliteConnStr makes writers wait for each other instead of failing with 'database is locked'.
+func (c *config) sqliteConnStr() string {
+	params := []string{
+		"_busy_timeout=30000",
+		"_journal_mode=WAL",
+		"_txlock=immediate",
+	}
+
+	return fmt.Sprintf("file:%s?%s", c.Database, strings.Join(params, "&"))
+}
+
+// GetNetwork returns the protocol of the indexed chain.
+func GetNetwork() string {
+	return current().network()
+}
+
+func (c *config) network() string {
+	if c.Network == "" {
+		return NetworkLegacy
+	}
+	return c.Network
+}
+
 // GetLabel returns custome label as console output prefix.
 func GetLabel() string {
-	return cfg.Label
+	return current().Label
+}
+
+// GetLogConfig returns log configs with defaults applied.
+func GetLogConfig() LogConfig {
+	c := current().Log
+	if c.ErrorFile == "" {
+		c.ErrorFile = "error.log"
+	}
+	return c
 }
 
-// GetRPCs returns all rpc urls from config.
-func GetRPCs() []string {
-	return cfg.RPCs
+// GetRPCs returns all rpc server configs.
+func GetRPCs() []RPCConfig {
+	return current().RPCs
+}
+
+// GetRPCMethodConfig returns timeout and retries of the rpc method,
+// with defaults applied to values not configured for it.
+func GetRPCMethodConfig(method string) RPCMethodConfig {
+	m := RPCMethodConfig{
+		Timeout:    10,
+		MaxRetries: 5,
+	}
+
+	rpc := current().RPC
+	for _, c := range []RPCMethodConfig{rpc.RPCMethodConfig, rpc.Methods[method]} {
+		if c.Timeout != 0 {
+			m.Timeout = c.Timeout
+		}
+		if c.MaxRetries != 0 {
+			m.MaxRetries = c.MaxRetries
+		}
+	}
+
+	return m
 }
 
 // GetGoroutines returns the number of working goroutines.
 func GetGoroutines() int {
-	return cfg.Workers
+	return current().Workers
+}
+
+// GetTasks returns configs of enabled tasks.
+func GetTasks() map[string]TaskConfig {
+	tasks := map[string]TaskConfig{}
+	conf := current()
+
+	if conf.Tasks == nil {
+		for _, name := range defaultTasks {
+			if name == TaskBlock || conf.network() != NetworkN3 {
+				tasks[name] = TaskConfig{Enabled: true}
+			}
+		}
+		return tasks
+	}
+
+	for name, c := range conf.Tasks {
+		if c.Enabled {
+			tasks[name] = c
+		}
+	}
+	return tasks
+}
+
+// GetAPIConfig returns query api configs.
+func GetAPIConfig() APIConfig {
+	return current().API
+}
+
+// GetMetricsConfig returns prometheus metrics configs.
+func GetMetricsConfig() MetricsConfig {
+	return current().Metrics
+}
+
+// GetHealthConfig returns health check configs with defaults applied.
+func GetHealthConfig() HealthConfig {
+	h := current().Health
+	if h.MaxLag == 0 {
+		h.MaxLag = 20
+	}
+	if h.StallTimeout == 0 {
+		h.StallTimeout = 600
+	}
+	return h
 }
 
 // LoadAliyunMailConfig performs a basic check on aliyun mail config.
 func LoadAliyunMailConfig() error {
-	return checkAliyunMail()
+	return current().checkAliyunMail()
 }
 
 // GetAliyunMailConfig returns aliyun mail configs.
 func GetAliyunMailConfig() AliyunMailConfig {
-	return cfg.AliyunMail
+	return current().AliyunMail
+}
+
+// GetNotifyConfig returns alert notifier configs.
+func GetNotifyConfig() NotifyConfig {
+	return current().Notify
 }
 
-func check() error {
-	if err := checkWorker(); err != nil {
+func (c *config) check() error {
+	if err := c.checkDriver(); err != nil {
+		return err
+	}
+
+	if err := c.checkNetwork(); err != nil {
+		return err
+	}
+
+	if err := c.checkProfile(); err != nil {
+		return err
+	}
+
+	if err := c.checkWorker(); err != nil {
+		return err
+	}
+
+	if err := c.checkLog(); err != nil {
+		return err
+	}
+
+	if err := c.checkTasks(); err != nil {
+		return err
+	}
+
+	if err := c.checkRPCs(); err != nil {
+		return err
+	}
+
+	if err := c.checkRPCCall(); err != nil {
+		return err
+	}
+
+	if err := c.checkAPI(); err != nil {
+		return err
+	}
+
+	if err := c.checkMetrics(); err != nil {
+		return err
+	}
+
+	if err := c.checkHealth(); err != nil {
 		return err
 	}
 
-	if err := checkRPCs(); err != nil {
+	if err := c.checkNotify(); err != nil {
+		return err
+	}
+
+	if err := c.checkWatchlist(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func checkWorker() error {
-	if cfg.Workers < 1 {
+func (c *config) checkDriver() error {
+	switch c.Driver {
+	case "", "mysql", "postgres", "sqlite":
+		return nil
+	default:
+		return fmt.Errorf("unsupported database driver '%s'", c.Driver)
+	}
+}
+
+func (c *config) checkNetwork() error {
+	switch c.Network {
+	case "", NetworkLegacy, NetworkN3:
+		return nil
+	default:
+		return fmt.Errorf("unsupported network '%s'", c.Network)
+	}
+}
+
+func (c *config) checkWorker() error {
+	if c.Workers < 1 {
 		return errors.New("value of 'goroutine' must greater than or equal to 1")
 	}
 	return nil
 }
 
-func checkRPCs() error {
-	if len(cfg.RPCs) < 1 {
+func (c *config) checkTasks() error {
+	for name, t := range c.Tasks {
+		if !IsTask(name) {
+			return fmt.Errorf("unknown task '%s'", name)
+		}
+		if t.Enabled && name != TaskBlock && c.network() == NetworkN3 {
+			return fmt.Errorf("task '%s' is not supported by Neo N3", name)
+		}
+		if t.Workers < 0 || t.ChannelSize < 0 {
+			return fmt.Errorf("workers and channel_size of task '%s' cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+// IsTask tells if name is one of TaskNames.
+func IsTask(name string) bool {
+	for _, n := range TaskNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *config) checkLog() error {
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		return err
+	}
+
+	switch c.Log.Format {
+	case "", log.FormatText, log.FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported log format '%s'", c.Log.Format)
+	}
+}
+
+func (c *config) checkRPCs() error {
+	if len(c.RPCs) < 1 {
 		return errors.New("at least 1 rpc server url must be set")
 	}
 
-	for _, rpc := range cfg.RPCs {
-		if strings.HasPrefix(rpc, "http") {
-			_, err := url.Parse(rpc)
+	for _, rpc := range c.RPCs {
+		if rpc.URL == "" {
+			return errors.New("rpc server url cannot be empty")
+		}
+		if strings.HasPrefix(rpc.URL, "http") {
+			_, err := url.Parse(rpc.URL)
 			if err != nil {
 				return err
 			}
 		}
+		if rpc.Priority < 0 || rpc.Weight < 0 {
+			return fmt.Errorf("priority and weight of rpc server '%s' cannot be negative", rpc.URL)
+		}
+	}
+
+	return nil
+}
+
+func (c *config) checkRPCCall() error {
+	if c.RPC.Timeout < 0 || c.RPC.MaxRetries < 0 {
+		return errors.New("rpc timeout and max_retries cannot be negative")
 	}
 
+	for method, m := range c.RPC.Methods {
+		if m.Timeout < 0 || m.MaxRetries < 0 {
+			return fmt.Errorf("timeout and max_retries of rpc method '%s' cannot be negative", method)
+		}
+	}
+
+	return nil
+}
+
+func (c *config) checkAPI() error {
+	if c.API.Enabled && c.API.Listen == "" {
+		return errors.New("api listen address cannot be empty when api is enabled")
+	}
+	return nil
+}
+
+func (c *config) checkMetrics() error {
+	if c.Metrics.Enabled && c.Metrics.Listen == "" {
+		return errors.New("metrics listen address cannot be empty when metrics is enabled")
+	}
+	return nil
+}
+
+func (c *config) checkHealth() error {
+	if c.Health.MaxLag < 0 {
+		return errors.New("value of 'max_lag' cannot be negative")
+	}
+	if c.Health.StallTimeout < 0 {
+		return errors.New("value of 'stall_timeout' cannot be negative")
+	}
 	return nil
 }
 
-func checkAliyunMail() error {
-	m := cfg.AliyunMail
+func (c *config) checkNotify() error {
+	n := c.Notify
+
+	if n.SMTP.Enabled {
+		if n.SMTP.Host == "" {
+			return errors.New("smtp host cannot be empty")
+		}
+		if n.SMTP.From == "" {
+			return errors.New("smtp sender cannot be empty")
+		}
+		if len(n.SMTP.To) == 0 {
+			return errors.New("smtp receiver cannot be empty")
+		}
+	}
+
+	if n.Webhook.Enabled {
+		u, err := url.Parse(n.Webhook.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid webhook url '%s'", n.Webhook.URL)
+		}
+	}
+
+	for _, r := range []RateLimitConfig{n.Stdout.RateLimit, n.SMTP.RateLimit, n.Webhook.RateLimit, c.AliyunMail.RateLimit} {
+		if r.Count < 0 || r.Period < 0 {
+			return errors.New("values of 'rate_limit' cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+func (c *config) checkAliyunMail() error {
+	m := c.AliyunMail
 
 	if m.AccountName == "" {
 		return errors.New("aliyun mail account name cannot be empty")
@@ -213,20 +831,57 @@ func onConfigChange(e fsnotify.Event) {
 
 	const stdErr = "Failed to read new configuration, current configuration stay unchanged"
 
-	if err := viper.ReadInConfig(); err != nil {
+	c, err := load(true)
+	if err != nil {
 		log.Printf("%s: %s", stdErr, err)
 		return
 	}
 
-	if err := load(true); err != nil {
+	if err := c.check(); err != nil {
 		log.Printf("%s: %s", stdErr, err)
 		return
 	}
 
-	if err := check(); err != nil {
+	if err := current().checkReload(c); err != nil {
 		log.Printf("%s: %s", stdErr, err)
 		return
 	}
 
-	log.UpdatePrefix(GetLabel())
+	c.update()
+	set(c)
+
+	if err := applyLog(); err != nil {
+		log.Error.Printf("Failed to apply log config: %v\n", err)
+	}
+
+	changeMu.Lock()
+	handlers := changeHandlers
+	changeMu.Unlock()
+
+	for _, f := range handlers {
+		f()
+	}
+}
+
+// checkReload returns an error if next changes configs which only take effect on restart,
+// i.e. the indexed network and the database.
+func (c *config) checkReload(next *config) error {
+	if next.network() != c.network() {
+		return errors.New("network cannot be changed without restart")
+	}
+	if next.Driver != c.Driver {
+		return errors.New("driver cannot be changed without restart")
+	}
+	if !reflect.DeepEqual(next.Profile, c.Profile) {
+		return errors.New("profile cannot be changed without restart")
+	}
+	return nil
+}
+
+// OnChange registers f to be called after configs are reloaded from the changed file.
+func OnChange(f func()) {
+	changeMu.Lock()
+	defer changeMu.Unlock()
+
+	changeHandlers = append(changeHandlers, f)
 }
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestEnvAndSecretFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Remove("error.log")
+
+	cfgPath := filepath.Join(dir, "config.json")
+	content := `{
+		"driver": "sqlite",
+		"database": "squirrel.db",
+		"user": "file_user",
+		"password": "file_password",
+		"rpc_url": ["http://127.0.0.1:10332", {"url": "http://127.0.0.1:10333", "weight": 0}, {"url": "http://127.0.0.1:10334", "priority": 1}],
+		"workers": 1,
+		"notify": {"webhook": {"headers": {"Authorization": "Bearer token"}}}
+	}`
+	if err := ioutil.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	secretPath := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(secretPath, []byte("secret_password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"SQUIRREL_USER":                 "env_user",
+		"SQUIRREL_PASSWORD_FILE":        secretPath,
+		"SQUIRREL_NOTIFY_SMTP_PASSWORD": "smtp_password",
+		"SQUIRREL_WORKERS":              "3",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	if err := LoadFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.User != "env_user" {
+		t.Errorf("user = %q, want env_user", cfg.User)
+	}
+	if cfg.Password != "secret_password" {
+		t.Errorf("password = %q, want secret_password", cfg.Password)
+	}
+	if cfg.Notify.SMTP.Password != "smtp_password" {
+		t.Errorf("notify.smtp.password = %q, want smtp_password", cfg.Notify.SMTP.Password)
+	}
+	if cfg.Workers != 3 {
+		t.Errorf("workers = %d, want 3", cfg.Workers)
+	}
+	if len(cfg.RPCs) != 3 || cfg.RPCs[0].Weight != 1 || cfg.RPCs[1].Weight != 0 || cfg.RPCs[2].Weight != 1 {
+		t.Errorf("rpc_url = %+v, want weights 1, 0 and 1", cfg.RPCs)
+	}
+
+	out, err := json.Marshal(redacted(reflect.ValueOf(*cfg)).Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret_password", "smtp_password", "Bearer token"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("secret %q is not redacted: %s", secret, out)
+		}
+	}
+	if cfg.Password != "secret_password" || cfg.Notify.Webhook.Headers["authorization"] != "Bearer token" {
+		t.Error("redacting modified the loaded config")
+	}
+}
+
+func TestSecretFileMissing(t *testing.T) {
+	os.Setenv("SQUIRREL_PASSWORD_FILE", filepath.Join(os.TempDir(), "squirrel_missing_password"))
+	defer os.Unsetenv("SQUIRREL_PASSWORD_FILE")
+
+	bindEnv()
+	c := config{}
+	if err := readSecretFiles(&c); err == nil {
+		t.Fatal("want error for missing password_file")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	defer set(&config{})
+
+	set(&config{})
+	p := GetProfile()
+	if p.Name != ProfileMainnet || p.Magic != 7630401 || p.TotalSupplyHeight != 6200000 {
+		t.Errorf("default profile = %+v, want mainnet", p)
+	}
+	if !p.IsSkippedTx("0xb00a0d7b752ba935206e1db67079c186ba38a4696d3afe28814a4834b2254cbe") {
+		t.Error("known bad mainnet tx is not skipped")
+	}
+
+	set(&config{Profile: ProfileConfig{Name: ProfileTestnet}})
+	p = GetProfile()
+	if p.Magic != 1953787457 || p.TotalSupplyHeight != 6200000 || len(p.SkippedTxIDs) != 0 {
+		t.Errorf("testnet profile = %+v", p)
+	}
+
+	set(&config{Profile: ProfileConfig{Name: ProfilePrivate}})
+	if err := current().checkProfile(); err == nil {
+		t.Error("want error for private profile without magic")
+	}
+
+	gasID := "0x" + strings.Repeat("1", 64)
+	set(&config{Profile: ProfileConfig{Name: ProfilePrivate, Magic: 56753, GASAssetID: gasID, TotalSupplyHeight: 10}})
+	if err := current().checkProfile(); err != nil {
+		t.Fatal(err)
+	}
+	p = GetProfile()
+	if p.Magic != 56753 || p.GASAssetID != gasID || p.NEOAssetID == "" || p.TotalSupplyHeight != 10 {
+		t.Errorf("private profile = %+v", p)
+	}
+
+	set(&config{Network: NetworkN3, Profile: ProfileConfig{Name: ProfileTestnet}})
+	if p = GetProfile(); p.Magic != 894710606 {
+		t.Errorf("n3 testnet magic = %d, want 894710606", p.Magic)
+	}
+}
+
+func TestReloadRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Remove("error.log")
+	defer set(&config{})
+
+	write := func(driver string, workers int) {
+		content := `{"driver": "` + driver + `", "database": "squirrel.db", "rpc_url": ["http://127.0.0.1:10332"], "workers": ` + strconv.Itoa(workers) + `}`
+		if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("sqlite", 2)
+	if err := LoadFile(filepath.Join(dir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		driver  string
+		workers int
+	}{
+		{"sqlite", 0},
+		{"mysql", 3},
+	} {
+		write(c.driver, c.workers)
+		onConfigChange(fsnotify.Event{})
+		if GetGoroutines() != 2 || GetDbDriver() != "sqlite" {
+			t.Errorf("reload of driver=%s, workers=%d took effect", c.driver, c.workers)
+		}
+	}
+
+	write("sqlite", 3)
+	onConfigChange(fsnotify.Event{})
+	if GetGoroutines() != 3 {
+		t.Errorf("workers = %d after valid reload, want 3", GetGoroutines())
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix prefixes environment variables which override configs,
+// e.g. SQUIRREL_PASSWORD for 'password' and SQUIRREL_NOTIFY_SMTP_PASSWORD for 'notify.smtp.password'.
+const envPrefix = "squirrel"
+
+// fileSuffix marks configs whose value is read from the file at the given path,
+// e.g. 'password_file' or SQUIRREL_PASSWORD_FILE for 'password'.
+const fileSuffix = "_file"
+
+// redactedValue replaces values of secret configs in output.
+const redactedValue = "******"
+
+// configFile is the path of the config file, it is searched in './config' and '../config' if empty.
+var configFile string
+
+// configKey is the viper key of a field of config.
+type configKey struct {
+	key   string
+	index []int
+	kind  reflect.Kind
+}
+
+var configKeys = keysOf(reflect.TypeOf(config{}), "", nil)
+
+// SetFile sets the path of the config file loaded by Load and Check.
+func SetFile(path string) {
+	configFile = path
+}
+
+// keysOf returns viper keys of fields of struct t.
+// Fields of maps are not listed, since their keys are only known from the config file.
+func keysOf(t reflect.Type, prefix string, index []int) []configKey {
+	keys := []configKey{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		name := strings.ToLower(f.Name)
+		tag := strings.Split(f.Tag.Get("mapstructure"), ",")
+		if tag[0] != "" {
+			name = strings.ToLower(tag[0])
+		}
+
+		if f.Type.Kind() == reflect.Struct {
+			if len(tag) > 1 && tag[1] == "squash" {
+				keys = append(keys, keysOf(f.Type, prefix, fieldIndex)...)
+			} else {
+				keys = append(keys, keysOf(f.Type, prefix+name+".", fieldIndex)...)
+			}
+			continue
+		}
+
+		keys = append(keys, configKey{key: prefix + name, index: fieldIndex, kind: f.Type.Kind()})
+	}
+
+	return keys
+}
+
+// bindEnv makes environment variables override configs, including the ones missing in the config file.
+func bindEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	for _, k := range configKeys {
+		viper.BindEnv(k.key)
+		if k.kind == reflect.String {
+			viper.BindEnv(k.key + fileSuffix)
+		}
+	}
+}
+
+// readSecretFiles sets string configs with a '_file' config to the content of the file,
+// which takes precedence over the config itself. Trailing line breaks are trimmed.
+func readSecretFiles(c *config) error {
+	v := reflect.ValueOf(c).Elem()
+
+	for _, k := range configKeys {
+		if k.kind != reflect.String {
+			continue
+		}
+
+		path := viper.GetString(k.key + fileSuffix)
+		if path == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read '%s%s': %v", k.key, fileSuffix, err)
+		}
+
+		v.FieldByIndex(k.index).SetString(strings.TrimRight(string(data), "\r\n"))
+	}
+
+	return nil
+}
+
+// redacted returns a copy of v with values of fields tagged `secret:"true"` replaced,
+// empty values are kept to show they are not set.
+func redacted(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			f := c.Field(i)
+			if v.Type().Field(i).Tag.Get("secret") == "true" {
+				f.Set(redactSecret(f))
+				continue
+			}
+			f.Set(redacted(f))
+		}
+
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(redacted(v.Index(i)))
+		}
+
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, redacted(v.MapIndex(key)))
+		}
+
+		return c
+	}
+
+	return v
+}
+
+// redactSecret replaces a secret string, or all values of a map of secret strings.
+func redactSecret(v reflect.Value) reflect.Value {
+	switch {
+	case v.Kind() == reflect.String && v.Len() > 0:
+		return reflect.ValueOf(redactedValue).Convert(v.Type())
+	case v.Kind() == reflect.Map && !v.IsNil():
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, redactSecret(v.MapIndex(key)))
+		}
+		return c
+	}
+
+	return v
+}
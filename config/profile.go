@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"squirrel/asset"
+	"strings"
+)
+
+// Network profiles supported by config 'profile.name'.
+const (
+	ProfileMainnet = "mainnet"
+	ProfilePrivate = "private"
+	ProfileTestnet = "testnet"
+)
+
+// ProfileConfig selects the profile of the indexed network and overrides its constants.
+type ProfileConfig struct {
+	// Name is one of 'mainnet'(default), 'testnet' or 'private'.
+	Name string
+	// Magic is the network magic number, required for private networks.
+	Magic uint32
+	// NEOAssetID and GASAssetID default to the ids of the governing and utility tokens of Neo 2.x mainnet.
+	NEOAssetID string `mapstructure:"neo_asset_id"`
+	GASAssetID string `mapstructure:"gas_asset_id"`
+	// SkippedTxIDs are invocation transactions ignored by nep5, nft and sc tasks, added to the ones of the profile.
+	SkippedTxIDs []string `mapstructure:"skipped_txids"`
+	// TotalSupplyHeight overrides the height of the profile if positive.
+	TotalSupplyHeight uint `mapstructure:"total_supply_height"`
+}
+
+// Profile carries constants of the indexed network.
+type Profile struct {
+	Name       string
+	Magic      uint32
+	NEOAssetID string
+	GASAssetID string
+	// SkippedTxIDs are invocation transactions which cannot be handled as app calls.
+	SkippedTxIDs []string
+	// TotalSupplyHeight is the block height after which nep5 total supply is queried on every transfer,
+	// before it only transfers to the 'totalSupply' storage key are suspected of storage injection.
+	TotalSupplyHeight uint
+}
+
+// legacyTotalSupplyHeight is the nep5 total supply height of Neo 2.x networks,
+// which applied to every network before profiles were introduced.
+const legacyTotalSupplyHeight = 6200000
+
+// profiles are the built-in profiles of each network protocol.
+var profiles = map[string]map[string]Profile{
+	NetworkLegacy: {
+		ProfileMainnet: {
+			Name:       ProfileMainnet,
+			Magic:      7630401,
+			NEOAssetID: asset.NEOAssetID,
+			GASAssetID: asset.GASAssetID,
+			SkippedTxIDs: []string{
+				"0xb00a0d7b752ba935206e1db67079c186ba38a4696d3afe28814a4834b2254cbe",
+			},
+			TotalSupplyHeight: legacyTotalSupplyHeight,
+		},
+		ProfileTestnet: {
+			Name:              ProfileTestnet,
+			Magic:             1953787457,
+			NEOAssetID:        asset.NEOAssetID,
+			GASAssetID:        asset.GASAssetID,
+			TotalSupplyHeight: legacyTotalSupplyHeight,
+		},
+		ProfilePrivate: {
+			Name:              ProfilePrivate,
+			NEOAssetID:        asset.NEOAssetID,
+			GASAssetID:        asset.GASAssetID,
+			TotalSupplyHeight: legacyTotalSupplyHeight,
+		},
+	},
+	NetworkN3: {
+		ProfileMainnet: {Name: ProfileMainnet, Magic: 860833102},
+		ProfileTestnet: {Name: ProfileTestnet, Magic: 894710606},
+		ProfilePrivate: {Name: ProfilePrivate},
+	},
+}
+
+// GetProfile returns constants of the indexed network, the built-in profile with configured overrides.
+func GetProfile() Profile {
+	conf := current()
+	c := conf.Profile
+	name := c.Name
+	if name == "" {
+		name = ProfileMainnet
+	}
+
+	p := profiles[conf.network()][name]
+	if c.Magic != 0 {
+		p.Magic = c.Magic
+	}
+	if c.NEOAssetID != "" {
+		p.NEOAssetID = c.NEOAssetID
+	}
+	if c.GASAssetID != "" {
+		p.GASAssetID = c.GASAssetID
+	}
+	if len(c.SkippedTxIDs) > 0 {
+		p.SkippedTxIDs = append(append([]string{}, p.SkippedTxIDs...), c.SkippedTxIDs...)
+	}
+	if c.TotalSupplyHeight > 0 {
+		p.TotalSupplyHeight = c.TotalSupplyHeight
+	}
+
+	return p
+}
+
+// IsSkippedTx reports if the invocation transaction is ignored by nep5, nft and sc tasks.
+func (p Profile) IsSkippedTx(txID string) bool {
+	for _, id := range p.SkippedTxIDs {
+		if id == txID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *config) checkProfile() error {
+	p := c.Profile
+	switch p.Name {
+	case "", ProfileMainnet, ProfileTestnet:
+	case ProfilePrivate:
+		if p.Magic == 0 {
+			return fmt.Errorf("profile.magic is required for private networks")
+		}
+	default:
+		return fmt.Errorf("unsupported profile '%s', must be one of %s, %s or %s", p.Name, ProfileMainnet, ProfileTestnet, ProfilePrivate)
+	}
+
+	for _, id := range []string{p.NEOAssetID, p.GASAssetID} {
+		if id != "" && (len(id) != 66 || !strings.HasPrefix(id, "0x")) {
+			return fmt.Errorf("invalid asset id '%s' in profile, must be 0x followed by 64 hex characters", id)
+		}
+	}
+
+	for _, id := range p.SkippedTxIDs {
+		if len(id) != 66 || !strings.HasPrefix(id, "0x") {
+			return fmt.Errorf("invalid txid '%s' in profile.skipped_txids", id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"squirrel/util"
+	"strings"
+)
+
+// WatchlistConfig is the struct for address watchlist configs.
+// Transfers of watched addresses are notified to webhooks, addresses can also be watched through the query api.
+type WatchlistConfig struct {
+	Enabled bool
+	// Addresses are either plain addresses or objects with address, asset ids and webhook.
+	Addresses []WatchConfig
+	// Webhook receives events of addresses without their own webhook.
+	Webhook WatchWebhookConfig
+	// Token authorizes requests to /watchlist of the query api, which are rejected if empty.
+	Token string `secret:"true"`
+}
+
+// WatchConfig is the struct for a watched address.
+type WatchConfig struct {
+	Address string
+	// AssetIDs limits events to the given assets, events of all assets are sent if empty.
+	AssetIDs []string `mapstructure:"asset_ids"`
+	// Webhook overrides the url of 'watchlist.webhook'.
+	Webhook string
+}
+
+// WatchWebhookConfig is the struct for watchlist webhook configs.
+type WatchWebhookConfig struct {
+	URL     string
+	Headers map[string]string `secret:"true"`
+	// Timeout is the number of seconds to wait for a response, default 10.
+	Timeout int
+	// MaxBackoff is the max number of seconds between retries of an event, default 600.
+	MaxBackoff int `mapstructure:"max_backoff"`
+}
+
+// GetWatchlistConfig returns watchlist configs with defaults applied.
+func GetWatchlistConfig() WatchlistConfig {
+	w := current().Watchlist
+	if w.Webhook.Timeout == 0 {
+		w.Webhook.Timeout = 10
+	}
+	if w.Webhook.MaxBackoff == 0 {
+		w.Webhook.MaxBackoff = 600
+	}
+	return w
+}
+
+// stringToWatchConfig allows plain addresses in 'watchlist.addresses'.
+func stringToWatchConfig(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(WatchConfig{}) {
+		return data, nil
+	}
+	return WatchConfig{Address: data.(string)}, nil
+}
+
+// WatchAssetIDValid checks if assetID is a global asset id, or the script hash of a nep5 or nft contract.
+func WatchAssetIDValid(assetID string) bool {
+	id := assetID
+	if len(id) == 66 && strings.HasPrefix(id, "0x") {
+		id = id[2:]
+	} else if len(id) != 40 {
+		return false
+	}
+
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// WatchWebhookValid checks if u is an http(s) url.
+func WatchWebhookValid(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (c *config) checkWatchlist() error {
+	w := c.Watchlist
+	if !w.Enabled {
+		return nil
+	}
+
+	if c.network() == NetworkN3 {
+		return errors.New("watchlist does not support Neo N3")
+	}
+
+	if !WatchWebhookValid(w.Webhook.URL) {
+		return fmt.Errorf("invalid watchlist webhook url '%s'", w.Webhook.URL)
+	}
+
+	if w.Webhook.Timeout < 0 || w.Webhook.MaxBackoff < 0 {
+		return errors.New("watchlist webhook timeout and max_backoff cannot be negative")
+	}
+
+	for _, a := range w.Addresses {
+		if !util.AddressValid(a.Address) {
+			return fmt.Errorf("invalid watched address '%s'", a.Address)
+		}
+		for _, assetID := range a.AssetIDs {
+			if !WatchAssetIDValid(assetID) {
+				return fmt.Errorf("invalid asset id '%s' of watched address %s", assetID, a.Address)
+			}
+		}
+		if a.Webhook != "" && !WatchWebhookValid(a.Webhook) {
+			return fmt.Errorf("invalid webhook url '%s' of watched address %s", a.Webhook, a.Address)
+		}
+	}
+
+	return nil
+}
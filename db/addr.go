@@ -11,11 +11,11 @@ import (
 )
 
 // GetAddrAssetInfo returns all addresses with it's assets.
-func GetAddrAssetInfo() []*addr.AssetInfo {
+func (s *sqlStore) GetAddrAssetInfo() []*addr.AssetInfo {
 	const query = "SELECT `address`.`address`, `address`.`created_at`, `address`.`last_transaction_time`, `addr_asset`.`asset_id`, `addr_asset`.`balance` FROM `addr_asset` LEFT JOIN `address` ON `address`.`address`=`addr_asset`.`address`"
 
 	result := []*addr.AssetInfo{}
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		panic(err)
 	}
@@ -41,14 +41,14 @@ func GetAddrAssetInfo() []*addr.AssetInfo {
 	}
 
 	// Append addr-nft asset info.
-	result = append(result, getAddrAssetNFTInfo()...)
+	result = append(result, s.getAddrAssetNFTInfo()...)
 
 	return result
 }
 
-func getAddrAssetNFTInfo() []*addr.AssetInfo {
+func (s *sqlStore) getAddrAssetNFTInfo() []*addr.AssetInfo {
 	const query = "SELECT `address`, `asset_id`, SUM(`balance`) AS `balance` FROM `addr_asset_nft` GROUP BY `address`, `asset_id`"
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		panic(err)
 	}
@@ -10,7 +10,7 @@ import (
 )
 
 // InsertBlock inserts raw block data into database.
-func InsertBlock(maxIndex int, blocks []*block.Block, txBulk *tx.Bulk) error {
+func (s *sqlStore) InsertBlock(maxIndex int, blocks []*block.Block, txBulk *tx.Bulk) error {
 	insertBlocksCmd := generateInsertCmdForBlock(blocks)
 	insertTxsCmd := generateInsertCmdForTxs(txBulk.TXs)
 	insertTxAttrsCmd := generateInsertCmdForTxAttrs(txBulk.TXAttrs)
@@ -31,7 +31,7 @@ func InsertBlock(maxIndex int, blocks []*block.Block, txBulk *tx.Bulk) error {
 		insertClaims,
 	}
 
-	return transact(func(tx *sql.Tx) error {
+	return s.transact(func(tx *sql.Tx) error {
 		for _, cmd := range cmdList {
 			if cmd == "" {
 				continue
@@ -162,10 +162,10 @@ func generateInsertCmdForClaims(claims []*tx.TransactionClaims) string {
 	}
 
 	var strBuilder strings.Builder
-	strBuilder.WriteString("INSERT INTO `tx_claims` (`txid`, `vout`) VALUES ")
+	strBuilder.WriteString("INSERT INTO `tx_claims` (`block_index`, `txid`, `vout`) VALUES ")
 
 	for _, claim := range claims {
-		strBuilder.WriteString(fmt.Sprintf("('%s', %d),", claim.TxID, claim.Vout))
+		strBuilder.WriteString(fmt.Sprintf("(%d, '%s', %d),", claim.BlockIndex, claim.TxID, claim.Vout))
 	}
 	return strings.TrimSuffix(strBuilder.String(), ",")
 }
@@ -32,12 +32,12 @@ type Counter struct {
 }
 
 // GetLastHeight returns the highest block index stored in database.
-func GetLastHeight() int {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastHeight() int {
+	counter := s.getCounterInstance()
 	return counter.LastBlockIndex
 }
 
-func initCounterInstance() Counter {
+func (s *sqlStore) initCounterInstance() Counter {
 	c := Counter{
 		ID:                 1,
 		LastBlockIndex:     -1,
@@ -63,7 +63,7 @@ func initCounterInstance() Counter {
 	}
 	const query = "INSERT INTO `counter` (`id`, `last_block_index`, `last_tx_pk`, `last_asset_tx_pk`, `last_tx_pk_for_nep5`, `app_log_idx`, `last_tx_pk_for_nft`, `nft_app_log_idx`, `last_tx_pk_for_sc`, `nep5_tx_pk_for_addr_tx`, `nft_tx_pk_for_addr_tx`, `last_tx_pk_gas_balance`, `cnt_addr`, `cnt_tx_reg`, `cnt_tx_miner`, `cnt_tx_issue`, `cnt_tx_invocation`, `cnt_tx_contract`, `cnt_tx_claim`, `cnt_tx_publish`, `cnt_tx_enrollment`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-	_, err := db.Exec(query,
+	_, err := s.db.Exec(query,
 		c.ID,
 		c.LastBlockIndex,
 		c.LastTxPk,
@@ -93,11 +93,11 @@ func initCounterInstance() Counter {
 	return c
 }
 
-func getCounterInstance() Counter {
+func (s *sqlStore) getCounterInstance() Counter {
 	const query = "SELECT `id`, `last_block_index`, `last_tx_pk`, `last_asset_tx_pk`, `last_tx_pk_for_nep5`, `app_log_idx`, `last_tx_pk_for_nft`, `nft_app_log_idx`, `last_tx_pk_for_sc`, `nep5_tx_pk_for_addr_tx`, `nft_tx_pk_for_addr_tx`, `last_tx_pk_gas_balance` FROM `counter` WHERE `id` = 1 LIMIT 1"
 
 	var counter Counter
-	err := db.QueryRow(query).Scan(
+	err := s.db.QueryRow(query).Scan(
 		&counter.ID,
 		&counter.LastBlockIndex,
 		&counter.LastTxPk,
@@ -113,88 +113,88 @@ func getCounterInstance() Counter {
 	)
 	switch err {
 	case sql.ErrNoRows:
-		return initCounterInstance()
+		return s.initCounterInstance()
 	case nil:
 		return counter
 	default:
-		reconnect()
-		return getCounterInstance()
+		s.reconnect()
+		return s.getCounterInstance()
 	}
 }
 
 // GetLastTxPkCounter returns the last resolved pk of transaction in counter.
-func GetLastTxPkCounter() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastTxPkCounter() uint {
+	counter := s.getCounterInstance()
 	return counter.LastTxPk
 }
 
 // GetLastAssetTxPkCounter returns the last resolved pk of asset transaction in counter.
-func GetLastAssetTxPkCounter() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastAssetTxPkCounter() uint {
+	counter := s.getCounterInstance()
 	return counter.LastAssetTxPk
 }
 
 // GetLastTxPkForNep5 returns counter info of last processed nep5 transactions.
-func GetLastTxPkForNep5() (uint, int) {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastTxPkForNep5() (uint, int) {
+	counter := s.getCounterInstance()
 	return counter.LastTxPkForNep5, counter.AppLogIdx
 }
 
 // GetLastTxPkForNft returns counter info of last processed nft transactions.
-func GetLastTxPkForNft() (uint, int) {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastTxPkForNft() (uint, int) {
+	counter := s.getCounterInstance()
 	return counter.LastTxPkForNft, counter.NftAppLogIdx
 }
 
 // GetLastTxPkForSC returns counter info of last processed sc transactions.
-func GetLastTxPkForSC() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastTxPkForSC() uint {
+	counter := s.getCounterInstance()
 	return counter.LastTxPkForSC
 }
 
 // GetLastTxPkForGasBalance returns the last resolved pk of gas balance task.
-func GetLastTxPkForGasBalance() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetLastTxPkForGasBalance() uint {
+	counter := s.getCounterInstance()
 	return counter.LastTxPkGasBalacne
 }
 
 // GetNep5TxPkForAddrTx returns last pk of handled nep5 tx records.
-func GetNep5TxPkForAddrTx() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetNep5TxPkForAddrTx() uint {
+	counter := s.getCounterInstance()
 	return counter.Nep5TxPkForAddrTx
 }
 
 // GetNftTxPkForAddrTx returns last pk of handled nft tx records.
-func GetNftTxPkForAddrTx() uint {
-	counter := getCounterInstance()
+func (s *sqlStore) GetNftTxPkForAddrTx() uint {
+	counter := s.getCounterInstance()
 	return counter.NftTxPkForAddrTx
 }
 
 // UpdateLastTxPk updates last pk of processed transaction.
-func UpdateLastTxPk(txPk uint) error {
+func (s *sqlStore) UpdateLastTxPk(txPk uint) error {
 	const updateCounterSQL = "UPDATE `counter` SET `last_tx_pk` = ? WHERE `id` = 1 LIMIT 1"
-	_, err := db.Exec(updateCounterSQL, txPk)
+	_, err := s.db.Exec(updateCounterSQL, txPk)
 	return err
 }
 
 // UpdateLastTxPkForNep5 updates counter info of last processed nep5 transactions.
-func UpdateLastTxPkForNep5(currentTxPk uint, applogIdx int) error {
+func (s *sqlStore) UpdateLastTxPkForNep5(currentTxPk uint, applogIdx int) error {
 	const updateCounterSQL = "UPDATE `counter` SET `last_tx_pk_for_nep5` = ?, `app_log_idx` = ? WHERE `id` = 1 LIMIT 1"
-	_, err := db.Exec(updateCounterSQL, currentTxPk, applogIdx)
+	_, err := s.db.Exec(updateCounterSQL, currentTxPk, applogIdx)
 	return err
 }
 
 // UpdateLastTxPkForNft updates counter info of last processed nft transactions.
-func UpdateLastTxPkForNft(currentTxPk uint, applogIdx int) error {
+func (s *sqlStore) UpdateLastTxPkForNft(currentTxPk uint, applogIdx int) error {
 	const updateCounterSQL = "UPDATE `counter` SET `last_tx_pk_for_nft` = ?, `nft_app_log_idx` = ? WHERE `id` = 1 LIMIT 1"
-	_, err := db.Exec(updateCounterSQL, currentTxPk, applogIdx)
+	_, err := s.db.Exec(updateCounterSQL, currentTxPk, applogIdx)
 	return err
 }
 
 // UpdateLastTxPkForSC updates counter info of last processed sc transactions.
-func UpdateLastTxPkForSC(currentTxPk uint) error {
+func (s *sqlStore) UpdateLastTxPkForSC(currentTxPk uint) error {
 	const updateCounterSQL = "UPDATE `counter` SET `last_tx_pk_for_sc` = ? WHERE `id` = 1 LIMIT 1"
-	_, err := db.Exec(updateCounterSQL, currentTxPk)
+	_, err := s.db.Exec(updateCounterSQL, currentTxPk)
 	return err
 }
 
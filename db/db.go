@@ -2,48 +2,75 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"squirrel/config"
 	"squirrel/log"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/go-sql-driver/mysql"
 )
 
-var (
-	db     *sql.DB
-	locker uint32
-)
+// sqlStore implements Store on top of a database/sql connection pool.
+// Statements are written in MySQL syntax,
+// drivers of other backends translate them before execution.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+	locker  uint32
+
+	// rollbackLock is held exclusively while a chain reorganisation is rolled back,
+	// all other database transactions share it.
+	rollbackLock sync.RWMutex
+}
+
+// Init connects to the configured database and returns its store.
+func Init() Store {
+	d, err := getDialect(config.GetDbDriver())
+	if err != nil {
+		panic(err)
+	}
 
-// Init connects to the configured mysql database.
-func Init() {
-	var err error
-	db, err = sql.Open("mysql", config.GetDbConnStr())
+	s := &sqlStore{dialect: d}
+	s.db, err = sql.Open(d.driverName(), config.GetDbConnStr())
 	if err != nil {
 		panic(err)
 	}
+
+	return s
 }
 
-func reconnect() {
-	if !atomic.CompareAndSwapUint32(&locker, 0, 1) {
+func getDialect(driver string) (dialect, error) {
+	switch driver {
+	case "", "mysql":
+		return mysqlDialect{}, nil
+	case "postgres":
+		return postgresDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%s'", driver)
+	}
+}
+
+func (s *sqlStore) reconnect() {
+	if !atomic.CompareAndSwapUint32(&s.locker, 0, 1) {
 		for {
 			// Lock was held by others, wait till lock released.
 			time.Sleep(20 * time.Millisecond)
 			// Lock was released.
-			if atomic.LoadUint32(&locker) != 1 {
+			if atomic.LoadUint32(&s.locker) != 1 {
 				return
 			}
 		}
 	}
 
-	defer atomic.StoreUint32(&locker, 0)
+	defer atomic.StoreUint32(&s.locker, 0)
 
 	for {
 		log.Printf("Try Reconnecting to database...")
-		db, _ = sql.Open("mysql", config.GetDbConnStr())
+		s.db, _ = sql.Open(s.dialect.driverName(), config.GetDbConnStr())
 
-		if err := db.Ping(); err == nil {
+		if err := s.db.Ping(); err == nil {
 			return
 		}
 
@@ -52,32 +79,44 @@ func reconnect() {
 	}
 }
 
-func wrappedQuery(query string, args ...interface{}) (*sql.Rows, error) {
+func (s *sqlStore) wrappedQuery(query string, args ...interface{}) (*sql.Rows, error) {
 	for {
-		rows, err := db.Query(query, args...)
+		rows, err := s.db.Query(query, args...)
 
 		if err == nil {
 			return rows, err
 		}
 
-		if !connErr(err) {
-			rows.Close()
+		if !s.connErr(err) {
 			return nil, err
 		}
 
-		reconnect()
+		s.reconnect()
 	}
 }
 
-func transact(txFunc func(*sql.Tx) error) (err error) {
-	tx, err := db.Begin()
+func (s *sqlStore) transact(txFunc func(*sql.Tx) error) error {
+	s.rollbackLock.RLock()
+	defer s.rollbackLock.RUnlock()
+
+	start := time.Now()
+	defer func() {
+		transactionDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	return s.doTransact(txFunc)
+}
+
+func (s *sqlStore) doTransact(txFunc func(*sql.Tx) error) (err error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		if !connErr(err) {
+		if !s.connErr(err) {
 			return err
 		}
 
-		reconnect()
-		return transact(txFunc)
+		s.reconnect()
+		transactionRetries.Inc()
+		return s.doTransact(txFunc)
 	}
 
 	defer func() {
@@ -94,27 +133,21 @@ func transact(txFunc func(*sql.Tx) error) (err error) {
 	}()
 
 	err = txFunc(tx)
-	if err == nil || !connErr(err) {
+	if err == nil || !s.connErr(err) {
 		return err
 	}
 
-	reconnect()
-	return transact(txFunc)
+	s.reconnect()
+	transactionRetries.Inc()
+	return s.doTransact(txFunc)
 }
 
-func connErr(err error) bool {
+func (s *sqlStore) connErr(err error) bool {
 	if err == nil {
 		return false
 	}
 
 	log.Println(err)
 
-	if err == mysql.ErrInvalidConn ||
-		strings.HasSuffix(err.Error(), "operation timed out") ||
-		strings.HasSuffix(err.Error(), "Server shutdown in progress") ||
-		strings.HasPrefix(err.Error(), "Error 1290") {
-		return true
-	}
-
-	return false
+	return s.dialect.connErr(err)
 }
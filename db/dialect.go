@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// dialect covers differences between database backends
+// which cannot be handled by translating statements.
+type dialect interface {
+	// driverName returns the database/sql driver name of the backend.
+	driverName() string
+	// connErr tells if err was caused by a broken database connection.
+	connErr(err error) bool
+	// lastInsertID returns pk of the row inserted into table by res.
+	lastInsertID(trans *sql.Tx, res sql.Result, table string) (int64, error)
+	// ignoreDuplicates returns the clause which makes an INSERT statement skip duplicated rows.
+	ignoreDuplicates() string
+	// schemaFile returns name of the script in package sqls which creates all tables.
+	schemaFile() string
+	// n3SchemaFile returns name of the script in package sqls which creates all tables of Neo N3.
+	n3SchemaFile() string
+	// uintType returns the column type used for unsigned integers.
+	uintType() string
+	// serialType returns the column type of auto incremented primary keys.
+	serialType() string
+	// textType returns the column type of long texts.
+	textType() string
+	// tableExistsQuery returns the query which counts tables named by its argument.
+	tableExistsQuery() string
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) driverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) connErr(err error) bool {
+	return err == mysql.ErrInvalidConn ||
+		strings.HasSuffix(err.Error(), "operation timed out") ||
+		strings.HasSuffix(err.Error(), "Server shutdown in progress") ||
+		strings.HasPrefix(err.Error(), "Error 1290")
+}
+
+func (mysqlDialect) lastInsertID(trans *sql.Tx, res sql.Result, table string) (int64, error) {
+	return res.LastInsertId()
+}
+
+func (mysqlDialect) ignoreDuplicates() string {
+	return " ON DUPLICATE KEY UPDATE `id`=`id`"
+}
+
+func (mysqlDialect) schemaFile() string {
+	return "create_table.sql"
+}
+
+func (mysqlDialect) n3SchemaFile() string {
+	return "create_table_n3.sql"
+}
+
+func (mysqlDialect) uintType() string {
+	return "int unsigned"
+}
+
+func (mysqlDialect) serialType() string {
+	return "int unsigned auto_increment"
+}
+
+func (mysqlDialect) textType() string {
+	return "mediumtext"
+}
+
+func (mysqlDialect) tableExistsQuery() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+}
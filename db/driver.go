@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// rewriteDriver wraps a database driver and rewrites every statement
+// before it reaches the wrapped driver.
+type rewriteDriver struct {
+	base    driver.Driver
+	rewrite func(query string) string
+}
+
+func (d *rewriteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rewriteConn{Conn: conn, rewrite: d.rewrite}, nil
+}
+
+type rewriteConn struct {
+	driver.Conn
+	rewrite func(query string) string
+}
+
+func (c *rewriteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(c.rewrite(query))
+}
+
+func (c *rewriteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, c.rewrite(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *rewriteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rewriteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, c.rewrite(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rewriteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, c.rewrite(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rewriteConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// quotedBytes reports for each byte of query whether it belongs to a string literal.
+func quotedBytes(query string) []bool {
+	quoted := make([]bool, len(query))
+	inString := false
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if !inString {
+			if c == '\'' {
+				inString = true
+				quoted[i] = true
+			}
+			continue
+		}
+
+		quoted[i] = true
+		switch {
+		case c == '\\' && i+1 < len(query):
+			i++
+			quoted[i] = true
+		case c == '\'' && i+1 < len(query) && query[i+1] == '\'':
+			i++
+			quoted[i] = true
+		case c == '\'':
+			inString = false
+		}
+	}
+
+	return quoted
+}
+
+// splitStatements splits query on semicolons outside of string literals.
+// Joining the result with ';' gives back the original query.
+func splitStatements(query string) []string {
+	quoted := quotedBytes(query)
+	stmts := []string{}
+	start := 0
+
+	for i := 0; i < len(query); i++ {
+		if query[i] == ';' && !quoted[i] {
+			stmts = append(stmts, query[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(stmts, query[start:])
+}
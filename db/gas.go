@@ -18,9 +18,9 @@ type GasDateBalance struct {
 var gasDateCache = make(map[string]*GasDateBalance)
 
 // ApplyGASAssetChange persists daily gas balance changes into DB.
-func ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[string]*big.Float) error {
+func (s *sqlStore) ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[string]*big.Float) error {
 	for addr, gasChange := range gasChangeMap {
-		err := transact(func(trans *sql.Tx) error {
+		err := s.transact(func(trans *sql.Tx) error {
 			gasDateBalanceCache, ok := gasDateCache[addr]
 			if !ok {
 				dataCache := GasDateBalance{
@@ -30,7 +30,7 @@ func ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[strin
 
 				gasDateCache[addr] = &dataCache
 
-				lastDate, balance := queryAddrGasDateRecord(addr)
+				lastDate, balance := s.queryAddrGasDateRecord(addr)
 				if balance == nil || lastDate != date {
 					if balance != nil {
 						dataCache.Balance = new(big.Float).SetPrec(256).Add(balance, gasChange)
@@ -42,7 +42,7 @@ func ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[strin
 					}
 				} else {
 					dataCache.Balance = new(big.Float).SetPrec(256).Add(balance, gasChange)
-					err := updateGasDateBalanceRecord(trans, addr, date, dataCache.Balance)
+					err := s.updateGasDateBalanceRecord(trans, addr, date, dataCache.Balance)
 					if err != nil {
 						return err
 					}
@@ -56,7 +56,7 @@ func ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[strin
 			gasDateBalanceCache.Balance = newBalance
 
 			if gasDateBalanceCache.Date == date {
-				updateGasDateBalanceRecord(trans, addr, date, newBalance)
+				s.updateGasDateBalanceRecord(trans, addr, date, newBalance)
 			} else {
 				gasDateBalanceCache.Date = date
 				insertGasDateBalanceRecord(trans, addr, date, newBalance)
@@ -74,7 +74,7 @@ func ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[strin
 	return nil
 }
 
-func queryAddrGasDateRecord(addr string) (string, *big.Float) {
+func (s *sqlStore) queryAddrGasDateRecord(addr string) (string, *big.Float) {
 	tableName := getAddrDateGasTableName(addr)
 	query := fmt.Sprintf("SELECT `date`, `balance` FROM `%s` ", tableName)
 	query += fmt.Sprintf("WHERE `address` = '%s' ", addr)
@@ -82,18 +82,18 @@ func queryAddrGasDateRecord(addr string) (string, *big.Float) {
 
 	var date string
 	var balanceStr string
-	err := db.QueryRow(query).Scan(&date, &balanceStr)
+	err := s.db.QueryRow(query).Scan(&date, &balanceStr)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
 
-		if !connErr(err) {
+		if !s.connErr(err) {
 			panic(err)
 		}
 
-		reconnect()
-		return queryAddrGasDateRecord(addr)
+		s.reconnect()
+		return s.queryAddrGasDateRecord(addr)
 	}
 
 	return date, util.StrToBigFloat(balanceStr)
@@ -108,7 +108,7 @@ func insertGasDateBalanceRecord(trans *sql.Tx, addr, date string, balance *big.F
 	return err
 }
 
-func updateGasDateBalanceRecord(trans *sql.Tx, addr, date string, gasChange *big.Float) error {
+func (s *sqlStore) updateGasDateBalanceRecord(trans *sql.Tx, addr, date string, gasChange *big.Float) error {
 	tableName := getAddrDateGasTableName(addr)
 	query := fmt.Sprintf("UPDATE `%s` ", tableName)
 	query += fmt.Sprintf("SET `balance` = %.8f ", gasChange)
@@ -117,12 +117,12 @@ func updateGasDateBalanceRecord(trans *sql.Tx, addr, date string, gasChange *big
 
 	_, err := trans.Exec(query)
 	if err != nil {
-		if !connErr(err) {
+		if !s.connErr(err) {
 			panic(err)
 		}
 
-		reconnect()
-		return updateGasDateBalanceRecord(trans, addr, date, gasChange)
+		s.reconnect()
+		return s.updateGasDateBalanceRecord(trans, addr, date, gasChange)
 	}
 
 	return nil
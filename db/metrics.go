@@ -0,0 +1,19 @@
+package db
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	transactionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "squirrel_db_transaction_duration_seconds",
+		Help:    "Duration of database transactions including retries.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	transactionRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "squirrel_db_transaction_retries_total",
+		Help: "Number of database transactions retried after a connection error.",
+	})
+)
@@ -0,0 +1,258 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"squirrel/log"
+	"squirrel/sqls"
+	"time"
+)
+
+// migration upgrades the database schema to its version.
+type migration struct {
+	version     int
+	description string
+	up          func(trans *sql.Tx, d dialect) error
+}
+
+// migrations are ordered by version, an applied migration must never be changed.
+//
+// Schema scripts in package sqls always create the latest schema,
+// so migrations after the first one check if their changes exist before applying them.
+// Neo N3 tables are created by their own scripts, which are not part of the first migration.
+var migrations = []migration{
+	{1, "create tables", createTables},
+	{2, "add counter.last_tx_pk_gas_balance", addGasBalanceCounter},
+	{3, "add tx_claims.block_index", addClaimsBlockIndex},
+	{4, "create n3 tables", createN3Tables},
+	{5, "record nep5 transfers stored with 8 decimals", addNep5DecimalsRepair},
+	{6, "create watchlist tables", createWatchlist},
+	{7, "create tx_disassembly table", createTxDisassembly},
+	{8, "add watch_event.reverts", addWatchEventReverts},
+}
+
+// Migrate applies all migrations newer than the schema version of the database.
+func (s *sqlStore) Migrate() error {
+	return s.migrateTo(migrations[len(migrations)-1].version)
+}
+
+// migrateTo applies migrations newer than the schema version of the database up to target.
+func (s *sqlStore) migrateTo(target int) error {
+	const createVersionTable = "CREATE TABLE IF NOT EXISTS `schema_version` (`version` int NOT NULL PRIMARY KEY, `description` varchar(255) NOT NULL, `applied_at` bigint NOT NULL)"
+	if _, err := s.db.Exec(createVersionTable); err != nil {
+		return err
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if version > latest {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d", version, latest)
+	}
+
+	// Databases created before migrations were introduced already have all tables.
+	if version == 0 && s.tableExists("counter") {
+		log.Printf("Existing tables found, mark schema version 1 as applied\n")
+		if err := s.doTransact(func(trans *sql.Tx) error {
+			return recordMigration(trans, migrations[0])
+		}); err != nil {
+			return err
+		}
+		version = 1
+	}
+
+	for _, m := range migrations {
+		if m.version <= version || m.version > target {
+			continue
+		}
+
+		log.Printf("Applying schema migration %d: %s\n", m.version, m.description)
+
+		err := s.doTransact(func(trans *sql.Tx) error {
+			if err := m.up(trans, s.dialect); err != nil {
+				return err
+			}
+			return recordMigration(trans, m)
+		})
+		if err != nil {
+			return fmt.Errorf("schema migration %d failed: %v", m.version, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the last applied migration.
+func (s *sqlStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow("SELECT COALESCE(MAX(`version`), 0) FROM `schema_version`").Scan(&version)
+	return version, err
+}
+
+func (s *sqlStore) tableExists(table string) bool {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT * FROM `%s` LIMIT 0", table))
+	if err != nil {
+		return false
+	}
+	rows.Close()
+
+	return true
+}
+
+func recordMigration(trans *sql.Tx, m migration) error {
+	const query = "INSERT INTO `schema_version` (`version`, `description`, `applied_at`) VALUES (?, ?, ?)"
+	_, err := trans.Exec(query, m.version, m.description, time.Now().Unix())
+	return err
+}
+
+// columnExists tells if the table, which must exist, has the column.
+func columnExists(trans *sql.Tx, table string, column string) (bool, error) {
+	rows, err := trans.Query(fmt.Sprintf("SELECT * FROM `%s` LIMIT 0", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range columns {
+		if c == column {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func createTables(trans *sql.Tx, d dialect) error {
+	script, err := sqls.FS.ReadFile(d.schemaFile())
+	if err != nil {
+		return err
+	}
+
+	_, err = trans.Exec(string(script))
+	return err
+}
+
+func createN3Tables(trans *sql.Tx, d dialect) error {
+	var count int
+	if err := trans.QueryRow(d.tableExistsQuery(), "n3_block").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	script, err := sqls.FS.ReadFile(d.n3SchemaFile())
+	if err != nil {
+		return err
+	}
+
+	_, err = trans.Exec(string(script))
+	return err
+}
+
+func addGasBalanceCounter(trans *sql.Tx, d dialect) error {
+	exists, err := columnExists(trans, "counter", "last_tx_pk_gas_balance")
+	if err != nil || exists {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE `counter` ADD COLUMN `last_tx_pk_gas_balance` %s NOT NULL DEFAULT 0", d.uintType())
+	_, err = trans.Exec(query)
+	return err
+}
+
+// addClaimsBlockIndex adds the column required to roll back claims of orphaned blocks.
+// Block index of existing claims is unknown and set to 0, they are too deep to be rolled back anyway.
+func addClaimsBlockIndex(trans *sql.Tx, d dialect) error {
+	exists, err := columnExists(trans, "tx_claims", "block_index")
+	if err != nil || exists {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE `tx_claims` ADD COLUMN `block_index` %s NOT NULL DEFAULT 0", d.uintType())
+	if _, err := trans.Exec(query); err != nil {
+		return err
+	}
+
+	_, err = trans.Exec("CREATE INDEX `idx_tx_claims_block_index` ON `tx_claims`(`block_index`)")
+	return err
+}
+
+// addNep5DecimalsRepair records nep5 transfers whose values were divided by 10^8 regardless of decimals of their assets,
+// they are fixed by 'squirrel repair-nep5-decimals'.
+// Databases created after the fix already have the table and nothing to repair.
+func addNep5DecimalsRepair(trans *sql.Tx, d dialect) error {
+	var count int
+	if err := trans.QueryRow(d.tableExistsQuery(), "nep5_decimals_repair").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	query := fmt.Sprintf("CREATE TABLE `nep5_decimals_repair` (`asset_id` varchar(40) NOT NULL PRIMARY KEY, `decimals` smallint NOT NULL, `max_tx_pk` %s NOT NULL, `repaired_at` bigint NOT NULL)", d.uintType())
+	if _, err := trans.Exec(query); err != nil {
+		return err
+	}
+
+	const record = "INSERT INTO `nep5_decimals_repair` (`asset_id`, `decimals`, `max_tx_pk`, `repaired_at`) " +
+		"SELECT `nep5`.`asset_id`, `nep5`.`decimals`, MAX(`nep5_tx`.`id`), 0 FROM `nep5` " +
+		"JOIN `nep5_tx` ON `nep5_tx`.`asset_id` = `nep5`.`asset_id` " +
+		"WHERE `nep5`.`decimals` <> 8 GROUP BY `nep5`.`asset_id`, `nep5`.`decimals`"
+	_, err := trans.Exec(record)
+	return err
+}
+
+// createWatchlist creates tables of watched addresses and their events.
+func createWatchlist(trans *sql.Tx, d dialect) error {
+	var count int
+	if err := trans.QueryRow(d.tableExistsQuery(), "watch_event").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	stmts := []string{
+		"CREATE TABLE `watch_address` (`address` varchar(128) NOT NULL PRIMARY KEY, `asset_ids` varchar(1024) NOT NULL, `webhook` varchar(255) NOT NULL, `created_at` bigint NOT NULL)",
+		fmt.Sprintf("CREATE TABLE `watch_event` (`id` %s PRIMARY KEY, `address` varchar(128) NOT NULL, `asset_type` varchar(8) NOT NULL, `asset_id` varchar(66) NOT NULL, "+
+			"`token_id` varchar(255) NOT NULL, `txid` varchar(66) NOT NULL, `block_index` %s NOT NULL, `block_time` bigint NOT NULL, `from` varchar(128) NOT NULL, `to` varchar(128) NOT NULL, "+
+			"`value` varchar(100) NOT NULL, `webhook` varchar(255) NOT NULL, `attempts` int NOT NULL, `next_attempt_at` bigint NOT NULL, `delivered_at` bigint NOT NULL, `last_error` varchar(255) NOT NULL)",
+			d.serialType(), d.uintType()),
+		"CREATE INDEX `idx_watch_event_pending` ON `watch_event`(`delivered_at`, `next_attempt_at`)",
+		"CREATE INDEX `idx_watch_event_address` ON `watch_event`(`address`)",
+		"CREATE INDEX `idx_watch_event_block_index` ON `watch_event`(`block_index`)",
+	}
+
+	for _, stmt := range stmts {
+		if _, err := trans.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createTxDisassembly creates the table of disassembled invocation scripts,
+// which are filled in from the beginning by 'squirrel reset sc'.
+func createTxDisassembly(trans *sql.Tx, d dialect) error {
+	var count int
+	if err := trans.QueryRow(d.tableExistsQuery(), "tx_disassembly").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	query := fmt.Sprintf("CREATE TABLE `tx_disassembly` (`txid` varchar(66) NOT NULL PRIMARY KEY, `disassembly` %s NOT NULL)", d.textType())
+	_, err := trans.Exec(query)
+	return err
+}
+
+// addWatchEventReverts adds the column of events which revert delivered events of rolled back blocks.
+func addWatchEventReverts(trans *sql.Tx, d dialect) error {
+	exists, err := columnExists(trans, "watch_event", "reverts")
+	if err != nil || exists {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE `watch_event` ADD COLUMN `reverts` %s NOT NULL DEFAULT 0", d.uintType())
+	_, err = trans.Exec(query)
+	return err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"squirrel/log"
+	"testing"
+)
+
+func TestMigrateLegacySchema(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	// Replace tables created by openSqliteStore with the ones of an old schema.
+	for _, query := range []string{
+		"DROP TABLE `schema_version`",
+		"DROP TABLE `counter`",
+		"DROP TABLE `tx_claims`",
+		"CREATE TABLE `counter` (`id` integer primary key, `last_block_index` integer not null)",
+		"CREATE TABLE `tx_claims` (`id` integer primary key, `txid` varchar(66) not null, `vout` integer not null)",
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if version, err := s.SchemaVersion(); err != nil || version != migrations[len(migrations)-1].version {
+		t.Fatalf("Expected latest schema version, got %d(err=%v)", version, err)
+	}
+
+	if !s.tableExists("n3_block") {
+		t.Errorf("Table n3_block should be created")
+	}
+
+	err := s.doTransact(func(trans *sql.Tx) error {
+		for table, column := range map[string]string{
+			"counter":   "last_tx_pk_gas_balance",
+			"tx_claims": "block_index",
+		} {
+			if exists, err := columnExists(trans, table, column); err != nil || !exists {
+				t.Errorf("Column %s.%s should be added(err=%v)", table, column, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairNep5Decimals(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStoreAt(t, 4)
+	defer cleanup()
+
+	// Transfers stored before the repair table exists were divided by 10^8.
+	// The table is dropped as schema scripts always create the latest schema.
+	for _, query := range []string{
+		"DROP TABLE `nep5_decimals_repair`",
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES ('a6', '', 'Six', 'SIX', 6, 0, '', 0, 0, 0, 0, 1)",
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES ('a8', '', 'Eight', 'EIGHT', 8, 0, '', 0, 0, 0, 0, 1)",
+		"INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('t1', 'a6', '', 'A', 0.0123, 0, 0)",
+		"INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('t2', 'a8', '', 'A', 1.5, 0, 0)",
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transfers stored after the upgrade are already right.
+	if _, err := s.db.Exec("INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('t3', 'a6', '', 'A', 2, 0, 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	repairs, err := s.GetNep5DecimalsRepairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repairs) != 1 || repairs[0].AssetID != "a6" || repairs[0].Decimals != 6 {
+		t.Fatalf("Expected one repair of asset a6, got %v", repairs)
+	}
+
+	for i, expected := range []int64{1, 0} {
+		repaired, err := s.RepairNep5TxValues("a6")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repaired != expected {
+			t.Fatalf("Repair #%d should update %d transfers, got %d", i, expected, repaired)
+		}
+	}
+
+	for txID, expected := range map[string]float64{"t1": 1.23, "t2": 1.5, "t3": 2} {
+		var value float64
+		if err := s.db.QueryRow("SELECT `value` FROM `nep5_tx` WHERE `txid` = ?", txID).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("Value of %s should be %v, got %v", txID, expected, value)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"math/big"
+	"squirrel/n3"
+)
+
+// InsertN3Bulk inserts blocks of Neo N3 with all their records.
+// Blocks of a bulk are persisted together, so the highest block is the sync progress.
+func (s *sqlStore) InsertN3Bulk(bulk *n3.Bulk) error {
+	return s.transact(func(trans *sql.Tx) error {
+		inserts := []func(*sql.Tx, *n3.Bulk) error{
+			insertN3Blocks,
+			insertN3Txs,
+			insertN3Signers,
+			insertN3Witnesses,
+			insertN3Transfers,
+			insertN3Contracts,
+		}
+
+		for _, insert := range inserts {
+			if err := insert(trans, bulk); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetN3LastHeight returns the highest Neo N3 block index stored in database, -1 if there is none.
+func (s *sqlStore) GetN3LastHeight() int {
+	const query = "SELECT COALESCE(MAX(`index`), -1) FROM `n3_block`"
+
+	var height int
+	err := s.db.QueryRow(query).Scan(&height)
+	if err != nil {
+		if !s.connErr(err) {
+			panic(err)
+		}
+		s.reconnect()
+		return s.GetN3LastHeight()
+	}
+
+	return height
+}
+
+// GetN3Transfers returns a page of NEP-17 and NEP-11 transfers of the contract.
+func (s *sqlStore) GetN3Transfers(contract string, cursor uint, limit int) ([]*n3.Transfer, error) {
+	query := "SELECT `id`, `txid`, `notify_idx`, `block_index`, `block_time`, `contract`, `standard`, `from`, `to`, `amount`, `token_id` FROM `n3_transfer` WHERE `contract` = ?"
+	args := []interface{}{contract}
+	query, args = paginate(query, args, cursor, limit)
+
+	rows, err := s.wrappedQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*n3.Transfer{}
+	for rows.Next() {
+		t := new(n3.Transfer)
+		var amountStr string
+		if err := rows.Scan(&t.ID, &t.TxID, &t.NotifyIdx, &t.BlockIndex, &t.BlockTime, &t.Contract, &t.Standard, &t.From, &t.To, &amountStr, &t.TokenID); err != nil {
+			return nil, err
+		}
+
+		t.Amount, _ = new(big.Int).SetString(amountStr, 10)
+		transfers = append(transfers, t)
+	}
+
+	return transfers, rows.Err()
+}
+
+func insertN3Blocks(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_block` (`hash`, `size`, `version`, `previousblockhash`, `merkleroot`, `time`, `index`, `nonce`, `primary`, `nextconsensus`, `script_invocation`, `script_verification`, `tx_count`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Blocks), func(i int) []interface{} {
+		b := bulk.Blocks[i]
+		return []interface{}{b.Hash, b.Size, b.Version, b.PreviousBlockHash, b.MerkleRoot, b.Time, b.Index, b.Nonce, b.Primary, b.NextConsensus, b.ScriptInvocation, b.ScriptVerification, b.TxCount}
+	})
+}
+
+func insertN3Txs(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_tx` (`block_index`, `block_time`, `hash`, `size`, `version`, `nonce`, `sender`, `sys_fee`, `net_fee`, `valid_until_block`, `script`, `vm_state`, `exception`, `gas_consumed`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Txs), func(i int) []interface{} {
+		t := bulk.Txs[i]
+		return []interface{}{t.BlockIndex, t.BlockTime, t.Hash, t.Size, t.Version, t.Nonce, t.Sender, t.SysFee, t.NetFee, t.ValidUntilBlock, t.Script, t.VMState, t.Exception, t.GasConsumed}
+	})
+}
+
+func insertN3Signers(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_signer` (`txid`, `account`, `scopes`, `allowed_contracts`, `allowed_groups`) VALUES (?, ?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Signers), func(i int) []interface{} {
+		s := bulk.Signers[i]
+		return []interface{}{s.TxID, s.Account, s.Scopes, s.AllowedContracts, s.AllowedGroups}
+	})
+}
+
+func insertN3Witnesses(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_witness` (`txid`, `n`, `invocation`, `verification`) VALUES (?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Witnesses), func(i int) []interface{} {
+		w := bulk.Witnesses[i]
+		return []interface{}{w.TxID, w.N, w.Invocation, w.Verification}
+	})
+}
+
+func insertN3Transfers(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_transfer` (`txid`, `notify_idx`, `block_index`, `block_time`, `contract`, `standard`, `from`, `to`, `amount`, `token_id`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Transfers), func(i int) []interface{} {
+		t := bulk.Transfers[i]
+		return []interface{}{t.TxID, t.NotifyIdx, t.BlockIndex, t.BlockTime, t.Contract, t.Standard, t.From, t.To, t.Amount.String(), t.TokenID}
+	})
+}
+
+func insertN3Contracts(trans *sql.Tx, bulk *n3.Bulk) error {
+	const query = "INSERT INTO `n3_contract` (`hash`, `event`, `txid`, `block_index`, `block_time`, `name`, `standards`) VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	return execEach(trans, query, len(bulk.Contracts), func(i int) []interface{} {
+		c := bulk.Contracts[i]
+		return []interface{}{c.Hash, c.Event, c.TxID, c.BlockIndex, c.BlockTime, c.Name, c.Standards}
+	})
+}
+
+// execEach executes the prepared query with args of each of n rows.
+func execEach(trans *sql.Tx, query string, n int, args func(i int) []interface{}) error {
+	if n == 0 {
+		return nil
+	}
+
+	stmt, err := trans.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if _, err := stmt.Exec(args(i)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
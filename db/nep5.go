@@ -20,9 +20,9 @@ type addrInfo struct {
 }
 
 // GetInvocationTxs returns invocation transactions.
-func GetInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
+func (s *sqlStore) GetInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
 	const query = "SELECT `id`, `block_index`, `block_time`, `txid`, `size`, `type`, `version`, `sys_fee`, `net_fee`, `nonce`, `script`, `gas` FROM `tx` WHERE `id` >= ? AND `type` = ? ORDER BY ID ASC LIMIT ?"
-	rows, err := wrappedQuery(query, startPk, "InvocationTransaction", limit)
+	rows, err := s.wrappedQuery(query, startPk, "InvocationTransaction", limit)
 	if err != nil {
 		panic(err)
 	}
@@ -66,10 +66,10 @@ func GetInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
 }
 
 // GetNep5AssetDecimals returns all nep5 asset_id with decimal.
-func GetNep5AssetDecimals() map[string]uint8 {
+func (s *sqlStore) GetNep5AssetDecimals() map[string]uint8 {
 	nep5Decimals := make(map[string]uint8)
 	const query = "SELECT `asset_id`, `decimals` FROM `nep5`"
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		panic(err)
 	}
@@ -88,10 +88,10 @@ func GetNep5AssetDecimals() map[string]uint8 {
 }
 
 // GetTxScripts returns script string of transaction.
-func GetTxScripts(txID string) ([]*tx.TransactionScripts, error) {
+func (s *sqlStore) GetTxScripts(txID string) ([]*tx.TransactionScripts, error) {
 	var txScripts []*tx.TransactionScripts
 	const query = "SELECT `id`, `txid`, `invocation`, `verification` FROM `tx_scripts` WHERE `txid` = ?"
-	rows, err := wrappedQuery(query, txID)
+	rows, err := s.wrappedQuery(query, txID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,23 +112,17 @@ func GetTxScripts(txID string) ([]*tx.TransactionScripts, error) {
 }
 
 // InsertNep5Asset inserts new nep5 asset into db.
-func InsertNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegInfo, addrAsset *addr.Asset, atHeight uint) error {
-	return transact(func(tx *sql.Tx) error {
-		insertNep5Sql := fmt.Sprintf("INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES('%s', '%s', '%s', '%s', %d, %.64f, '%s', %d, %d, %d, %d, %d)", nep5.AssetID, nep5.AdminAddress, nep5.Name, nep5.Symbol, nep5.Decimals, nep5.TotalSupply, nep5.TxID, nep5.BlockIndex, nep5.BlockTime, nep5.Addresses, nep5.HoldingAddresses, nep5.Transfers)
-		res, err := tx.Exec(insertNep5Sql)
-		if err != nil {
+func (s *sqlStore) InsertNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegInfo, addrAsset *addr.Asset, atHeight uint) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if rolledBack, err := txRolledBack(tx, trans.ID); err != nil || rolledBack {
 			return err
 		}
 
-		newPK, err := res.LastInsertId()
-		if err != nil {
-			return err
-		}
-		const insertNep5RegInfo = "INSERT INTO `nep5_reg_info` (`nep5_id`, `name`, `version`, `author`, `email`, `description`, `need_storage`, `parameter_list`, `return_type`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
-		if _, err := tx.Exec(insertNep5RegInfo, newPK, regInfo.Name, regInfo.Version, regInfo.Author, regInfo.Email, regInfo.Description, regInfo.NeedStorage, regInfo.ParameterList, regInfo.ReturnType); err != nil {
+		if err := s.insertNep5(tx, nep5, regInfo); err != nil {
 			return err
 		}
 
+		var err error
 		addrCreated := false
 		if addrAsset != nil {
 			addrCreated, err = createAddrInfoIfNotExist(tx, trans.BlockTime, addrAsset.Address)
@@ -155,9 +149,43 @@ func InsertNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegIn
 	})
 }
 
+// InsertDiscoveredNep5Asset inserts a nep5 asset discovered from its first transfer in trans.
+// The nep5 counter is left to the transfer, and assets already stored are skipped.
+func (s *sqlStore) InsertDiscoveredNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegInfo) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if rolledBack, err := txRolledBack(tx, trans.ID); err != nil || rolledBack {
+			return err
+		}
+
+		var exists bool
+		const query = "SELECT EXISTS(SELECT `id` FROM `nep5` WHERE `asset_id` = ?)"
+		if err := tx.QueryRow(query, nep5.AssetID).Scan(&exists); err != nil || exists {
+			return err
+		}
+
+		return s.insertNep5(tx, nep5, regInfo)
+	})
+}
+
+func (s *sqlStore) insertNep5(tx *sql.Tx, nep5 *nep5.Nep5, regInfo *nep5.RegInfo) error {
+	const insertNep5Sql = "INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := tx.Exec(insertNep5Sql, nep5.AssetID, nep5.AdminAddress, nep5.Name, nep5.Symbol, nep5.Decimals, fmt.Sprintf("%.64f", nep5.TotalSupply), nep5.TxID, nep5.BlockIndex, nep5.BlockTime, nep5.Addresses, nep5.HoldingAddresses, nep5.Transfers)
+	if err != nil {
+		return err
+	}
+
+	newPK, err := s.dialect.lastInsertID(tx, res, "nep5")
+	if err != nil {
+		return err
+	}
+	const insertNep5RegInfo = "INSERT INTO `nep5_reg_info` (`nep5_id`, `name`, `version`, `author`, `email`, `description`, `need_storage`, `parameter_list`, `return_type`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.Exec(insertNep5RegInfo, newPK, regInfo.Name, regInfo.Version, regInfo.Author, regInfo.Email, regInfo.Description, regInfo.NeedStorage, regInfo.ParameterList, regInfo.ReturnType)
+	return err
+}
+
 // UpdateNep5TotalSupplyAndAddrAsset updates nep5 total supply and admin balance.
-func UpdateNep5TotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, addr string, balance *big.Float, assetID string, totalSupply *big.Float) error {
-	return transact(func(tx *sql.Tx) error {
+func (s *sqlStore) UpdateNep5TotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, addr string, balance *big.Float, assetID string, totalSupply *big.Float) error {
+	return s.transact(func(tx *sql.Tx) error {
 		addrCreated := false
 		var err error
 
@@ -197,6 +225,10 @@ func UpdateNep5TotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, addr s
 			// balance is zero.
 			if addrAssetCache, ok := cache.GetAddrAsset(addr, assetID); ok {
 				if addrAssetCache.UpdateBalance(balance, blockIndex) {
+					const clearBalanceQuery = "UPDATE `addr_asset` SET `balance` = 0 WHERE `address` = ? AND `asset_id` = ? LIMIT 1"
+					if _, err := tx.Exec(clearBalanceQuery, addr, assetID); err != nil {
+						return err
+					}
 					const updateBalanceQuery = "UPDATE `nep5` SET `holding_addresses` = `holding_addresses` - 1 WHERE `asset_id` = ? LIMIT 1"
 					if _, err := tx.Exec(updateBalanceQuery, assetID); err != nil {
 						return err
@@ -230,8 +262,11 @@ func UpdateNep5TotalSupply(tx *sql.Tx, assetID string, totalSupply *big.Float) e
 }
 
 // InsertNep5transaction inserts new nep5 transaction into db.
-func InsertNep5transaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, totalSupply *big.Float) error {
-	return transact(func(tx *sql.Tx) error {
+func (s *sqlStore) InsertNep5transaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, totalSupply *big.Float) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if rolledBack, err := txRolledBack(tx, trans.ID); err != nil || rolledBack {
+			return err
+		}
 
 		// Insert nep5 transaction record.
 		txSQL := fmt.Sprintf("INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('%s', '%s', '%s', '%s', %.64f, %d, %d);", trans.TxID, assetID, fromAddr, toAddr, transferValue, trans.BlockIndex, trans.BlockTime)
@@ -240,32 +275,36 @@ func InsertNep5transaction(trans *tx.Transaction, appLogIdx int, assetID string,
 			return err
 		}
 
+		if err := insertWatchEvents(tx, trans, transferWatchEvents(asset.NEP5, assetID, "", fromAddr, toAddr, transferValue)); err != nil {
+			return err
+		}
+
 		err := updateNep5Counter(tx, trans.ID, appLogIdx)
 		return err
 	})
 }
 
 // GetMaxNonEmptyScriptTxPk returns largest pk of invocation transaction.
-func GetMaxNonEmptyScriptTxPk() uint {
+func (s *sqlStore) GetMaxNonEmptyScriptTxPk() uint {
 	const query = "SELECT `id` from `tx` WHERE `type` = ? ORDER BY `id` DESC LIMIT 1"
 
 	var pk uint
-	err := db.QueryRow(query, "InvocationTransaction").Scan(&pk)
+	err := s.db.QueryRow(query, "InvocationTransaction").Scan(&pk)
 	if err != nil && err != sql.ErrNoRows {
-		if !connErr(err) {
+		if !s.connErr(err) {
 			panic(err)
 		}
-		reconnect()
-		return GetMaxNonEmptyScriptTxPk()
+		s.reconnect()
+		return s.GetMaxNonEmptyScriptTxPk()
 	}
 
 	return pk
 }
 
 // GetNep5TxRecords returns paged nep5 transactions from db.
-func GetNep5TxRecords(pk uint, limit int) ([]*nep5.Transaction, error) {
+func (s *sqlStore) GetNep5TxRecords(pk uint, limit int) ([]*nep5.Transaction, error) {
 	const query = "SELECT `id`, `txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time` FROM `nep5_tx` WHERE `id` > ? ORDER BY `id` ASC LIMIT ?"
-	rows, err := wrappedQuery(query, pk, limit)
+	rows, err := s.wrappedQuery(query, pk, limit)
 	if err != nil {
 		panic(err)
 	}
@@ -305,12 +344,12 @@ func GetNep5TxRecords(pk uint, limit int) ([]*nep5.Transaction, error) {
 }
 
 // InsertNep5AddrTxRec inserts addr_tx record of nep5 transactions.
-func InsertNep5AddrTxRec(nep5TxRecs []*nep5.Transaction, lastPk uint) error {
+func (s *sqlStore) InsertNep5AddrTxRec(nep5TxRecs []*nep5.Transaction, lastPk uint) error {
 	if len(nep5TxRecs) == 0 {
 		return nil
 	}
 
-	return transact(func(tx *sql.Tx) error {
+	return s.transact(func(tx *sql.Tx) error {
 		var strBuilder strings.Builder
 
 		strBuilder.WriteString("INSERT INTO `addr_tx` (`txid`, `address`, `block_time`, `asset_type`) VALUES ")
@@ -329,7 +368,7 @@ func InsertNep5AddrTxRec(nep5TxRecs []*nep5.Transaction, lastPk uint) error {
 		}
 
 		query = strings.TrimSuffix(query, ",")
-		query += "ON DUPLICATE KEY UPDATE `address`=`address`"
+		query += s.dialect.ignoreDuplicates()
 
 		if _, err := tx.Exec(query); err != nil {
 			return err
@@ -6,8 +6,8 @@ import (
 )
 
 // HandleNEP5Migrate handles nep5 contract migration.
-func HandleNEP5Migrate(newAssetAdmin, oldAssetID, newAssetID string, txPK uint, txID string) error {
-	return transact(func(tx *sql.Tx) error {
+func (s *sqlStore) HandleNEP5Migrate(newAssetAdmin, oldAssetID, newAssetID string, txPK uint, txID string) error {
+	return s.transact(func(tx *sql.Tx) error {
 		query := "UPDATE `nep5` SET `visible` = FALSE WHERE `asset_id` = ? LIMIT 1"
 		if _, err := tx.Exec(query, oldAssetID); err != nil {
 			return err
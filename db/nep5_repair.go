@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"squirrel/nep5"
+	"strings"
+	"time"
+)
+
+// GetNep5DecimalsRepairs returns all assets whose transfers were stored with 8 decimals,
+// including the ones already repaired.
+func (s *sqlStore) GetNep5DecimalsRepairs() ([]*nep5.DecimalsRepair, error) {
+	const query = "SELECT `asset_id`, `decimals`, `max_tx_pk`, `repaired_at` FROM `nep5_decimals_repair` ORDER BY `asset_id`"
+
+	rows, err := s.wrappedQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	repairs := []*nep5.DecimalsRepair{}
+	for rows.Next() {
+		r := new(nep5.DecimalsRepair)
+		if err := rows.Scan(&r.AssetID, &r.Decimals, &r.MaxTxPk, &r.RepairedAt); err != nil {
+			return nil, err
+		}
+		repairs = append(repairs, r)
+	}
+
+	return repairs, rows.Err()
+}
+
+// RepairNep5TxValues rescales values of transfers of the asset stored with 8 decimals to its own decimals,
+// and returns the number of transfers repaired.
+// An asset is only repaired once, later calls repair nothing.
+func (s *sqlStore) RepairNep5TxValues(assetID string) (int64, error) {
+	var repaired int64
+
+	err := s.transact(func(tx *sql.Tx) error {
+		repaired = 0
+
+		r := new(nep5.DecimalsRepair)
+		const query = "SELECT `decimals`, `max_tx_pk`, `repaired_at` FROM `nep5_decimals_repair` WHERE `asset_id` = ? FOR UPDATE"
+		err := tx.QueryRow(query, assetID).Scan(&r.Decimals, &r.MaxTxPk, &r.RepairedAt)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil || r.RepairedAt != 0 {
+			return err
+		}
+
+		update := fmt.Sprintf("UPDATE `nep5_tx` SET `value` = `value` * %s WHERE `asset_id` = ? AND `id` <= ?", decimalsFactor(r.Decimals))
+		res, err := tx.Exec(update, assetID, r.MaxTxPk)
+		if err != nil {
+			return err
+		}
+		if repaired, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		const done = "UPDATE `nep5_decimals_repair` SET `repaired_at` = ? WHERE `asset_id` = ?"
+		_, err = tx.Exec(done, time.Now().Unix(), assetID)
+		return err
+	})
+
+	return repaired, err
+}
+
+// GetNep5Holders returns addresses which have held the nep5 asset.
+func (s *sqlStore) GetNep5Holders(assetID string) ([]string, error) {
+	const query = "SELECT `address` FROM `addr_asset` WHERE `asset_id` = ? ORDER BY `id`"
+
+	rows, err := s.wrappedQuery(query, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addrs := []string{}
+	for rows.Next() {
+		var addr string
+		if err := rows.Scan(&addr); err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, rows.Err()
+}
+
+// decimalsFactor returns the exact decimal literal of 10^(8-decimals),
+// which turns a value divided by 10^8 into the one divided by 10^decimals.
+func decimalsFactor(decimals uint8) string {
+	if decimals <= 8 {
+		return "1" + strings.Repeat("0", 8-int(decimals))
+	}
+	return "0." + strings.Repeat("0", int(decimals)-9) + "1"
+}
@@ -15,9 +15,9 @@ import (
 )
 
 //GetNftInvocationTxs returns invocation transactions.
-func GetNftInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
+func (s *sqlStore) GetNftInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
 	const query = "SELECT `id`, `block_index`, `block_time`, `txid`, `size`, `type`, `version`, `sys_fee`, `net_fee`, `nonce`, `script`, `gas` FROM `tx` WHERE `id` >= ? AND `type` = ? ORDER BY ID ASC LIMIT ?"
-	rows, err := wrappedQuery(query, startPk, "InvocationTransaction", limit)
+	rows, err := s.wrappedQuery(query, startPk, "InvocationTransaction", limit)
 	if err != nil {
 		panic(err)
 	}
@@ -61,10 +61,10 @@ func GetNftInvocationTxs(startPk uint, limit uint) []*tx.Transaction {
 }
 
 // GetNftAssetDecimals returns all nft asset_id with decimal.
-func GetNftAssetDecimals() map[string]uint8 {
+func (s *sqlStore) GetNftAssetDecimals() map[string]uint8 {
 	nftDecimals := make(map[string]uint8)
 	const query = "SELECT `asset_id`, `decimals` FROM `nft`"
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		panic(err)
 	}
@@ -83,11 +83,11 @@ func GetNftAssetDecimals() map[string]uint8 {
 }
 
 // NftTokenExists tells if the given tokenID exists.
-func NftTokenExists(assetID, tokenID string) bool {
+func (s *sqlStore) NftTokenExists(assetID, tokenID string) bool {
 	exists := false
 
 	query := "SELECT EXISTS (SELECT `id` FROM `nft_token` WHERE `asset_id` = ? AND `token_id` = ? LIMIT 1)"
-	err := db.QueryRow(query, assetID, tokenID).Scan(&exists)
+	err := s.db.QueryRow(query, assetID, tokenID).Scan(&exists)
 	if err != nil {
 		log.Error.Println(err)
 		panic(err)
@@ -97,10 +97,10 @@ func NftTokenExists(assetID, tokenID string) bool {
 }
 
 //GetNftTxScripts returns script string of transaction.
-func GetNftTxScripts(txID string) ([]*tx.TransactionScripts, error) {
+func (s *sqlStore) GetNftTxScripts(txID string) ([]*tx.TransactionScripts, error) {
 	var txScripts []*tx.TransactionScripts
 	const query = "SELECT `id`, `txid`, `invocation`, `verification` FROM `tx_scripts` WHERE `txid` = ?"
-	rows, err := wrappedQuery(query, txID)
+	rows, err := s.wrappedQuery(query, txID)
 	if err != nil {
 		return nil, err
 	}
@@ -121,15 +121,19 @@ func GetNftTxScripts(txID string) ([]*tx.TransactionScripts, error) {
 }
 
 // InsertNftAsset inserts new nft asset into db.
-func InsertNftAsset(trans *tx.Transaction, nft *nft.Nft, regInfo *nft.NftRegInfo, atHeight uint) error {
-	return transact(func(tx *sql.Tx) error {
-		insertNftSQL := fmt.Sprintf("INSERT INTO `nft` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES('%s', '%s', '%s', '%s', %d, %.64f, '%s', %d, %d, %d, %d, %d)", nft.AssetID, nft.AdminAddress, nft.Name, nft.Symbol, nft.Decimals, nft.TotalSupply, nft.TxID, nft.BlockIndex, nft.BlockTime, nft.Addresses, nft.HoldingAddresses, nft.Transfers)
-		res, err := tx.Exec(insertNftSQL)
+func (s *sqlStore) InsertNftAsset(trans *tx.Transaction, nft *nft.Nft, regInfo *nft.NftRegInfo, atHeight uint) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if rolledBack, err := txRolledBack(tx, trans.ID); err != nil || rolledBack {
+			return err
+		}
+
+		const insertNftSQL = "INSERT INTO `nft` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		res, err := tx.Exec(insertNftSQL, nft.AssetID, nft.AdminAddress, nft.Name, nft.Symbol, nft.Decimals, fmt.Sprintf("%.64f", nft.TotalSupply), nft.TxID, nft.BlockIndex, nft.BlockTime, nft.Addresses, nft.HoldingAddresses, nft.Transfers)
 		if err != nil {
 			return err
 		}
 
-		newPK, err := res.LastInsertId()
+		newPK, err := s.dialect.lastInsertID(tx, res, "nft")
 		if err != nil {
 			return err
 		}
@@ -143,8 +147,8 @@ func InsertNftAsset(trans *tx.Transaction, nft *nft.Nft, regInfo *nft.NftRegInfo
 }
 
 // UpdateNftTotalSupplyAndAddrAsset updates nft total supply.
-func UpdateNftTotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, assetID string, totalSupply *big.Float) error {
-	return transact(func(tx *sql.Tx) error {
+func (s *sqlStore) UpdateNftTotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, assetID string, totalSupply *big.Float) error {
+	return s.transact(func(tx *sql.Tx) error {
 		return UpdateNftTotalSupply(tx, assetID, totalSupply)
 	})
 }
@@ -159,8 +163,12 @@ func UpdateNftTotalSupply(tx *sql.Tx, assetID string, totalSupply *big.Float) er
 }
 
 // InsertNftTransaction inserts new nft transaction into db.
-func InsertNftTransaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, tokenID string, totalSupply *big.Float, nftJSONInfo string) error {
-	return transact(func(tx *sql.Tx) error {
+func (s *sqlStore) InsertNftTransaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, tokenID string, totalSupply *big.Float, nftJSONInfo string) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if rolledBack, err := txRolledBack(tx, trans.ID); err != nil || rolledBack {
+			return err
+		}
+
 		addrsOffset := 0
 		holdingAddrsOffset := 0
 
@@ -268,6 +276,10 @@ func InsertNftTransaction(trans *tx.Transaction, appLogIdx int, assetID string,
 			}
 		}
 
+		if err := insertWatchEvents(tx, trans, transferWatchEvents(asset.NFT, assetID, tokenID, fromAddr, toAddr, transferValue)); err != nil {
+			return err
+		}
+
 		err := updateNftCounter(tx, trans.ID, appLogIdx)
 		return err
 	})
@@ -284,26 +296,26 @@ func persistNftToken(tx *sql.Tx, assetID, tokenID, nftJSONInfo string) error {
 }
 
 // GetMaxNonEmptyScriptTxPkForNft returns largest pk of invocation transaction.
-func GetMaxNonEmptyScriptTxPkForNft() uint {
+func (s *sqlStore) GetMaxNonEmptyScriptTxPkForNft() uint {
 	const query = "SELECT `id` from `tx` WHERE `type` = ? ORDER BY `id` DESC LIMIT 1"
 
 	var pk uint
-	err := db.QueryRow(query, "InvocationTransaction").Scan(&pk)
+	err := s.db.QueryRow(query, "InvocationTransaction").Scan(&pk)
 	if err != nil && err != sql.ErrNoRows {
-		if !connErr(err) {
+		if !s.connErr(err) {
 			panic(err)
 		}
-		reconnect()
-		return GetMaxNonEmptyScriptTxPkForNft()
+		s.reconnect()
+		return s.GetMaxNonEmptyScriptTxPkForNft()
 	}
 
 	return pk
 }
 
 // GetNftTxRecords returns paged nft transactions from db.
-func GetNftTxRecords(pk uint, limit int) ([]*nft.Transaction, error) {
+func (s *sqlStore) GetNftTxRecords(pk uint, limit int) ([]*nft.Transaction, error) {
 	const query = "SELECT `id`, `txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time` FROM `nft_tx` WHERE `id` > ? ORDER BY `id` ASC LIMIT ?"
-	rows, err := wrappedQuery(query, pk, limit)
+	rows, err := s.wrappedQuery(query, pk, limit)
 	if err != nil {
 		panic(err)
 	}
@@ -343,12 +355,12 @@ func GetNftTxRecords(pk uint, limit int) ([]*nft.Transaction, error) {
 }
 
 // InsertNftAddrTxRec inserts addr_tx record of nft transactions.
-func InsertNftAddrTxRec(nftTxRecs []*nft.Transaction, lastPk uint) error {
+func (s *sqlStore) InsertNftAddrTxRec(nftTxRecs []*nft.Transaction, lastPk uint) error {
 	if len(nftTxRecs) == 0 {
 		return nil
 	}
 
-	return transact(func(tx *sql.Tx) error {
+	return s.transact(func(tx *sql.Tx) error {
 		var strBuilder strings.Builder
 
 		strBuilder.WriteString("INSERT INTO `addr_tx` (`txid`, `address`, `block_time`, `asset_type`) VALUES ")
@@ -367,7 +379,7 @@ func InsertNftAddrTxRec(nftTxRecs []*nft.Transaction, lastPk uint) error {
 		}
 
 		query = strings.TrimSuffix(query, ",")
-		query += "ON DUPLICATE KEY UPDATE `address`=`address`"
+		query += s.dialect.ignoreDuplicates()
 
 		if _, err := tx.Exec(query); err != nil {
 			return err
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const postgresDriverName = "squirrel-postgres"
+
+func init() {
+	sql.Register(postgresDriverName, &rewriteDriver{
+		base:    &pq.Driver{},
+		rewrite: postgresSQL,
+	})
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) driverName() string {
+	return postgresDriverName
+}
+
+func (postgresDialect) connErr(err error) bool {
+	if err == driver.ErrBadConn {
+		return true
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	if e, ok := err.(*pq.Error); ok {
+		// Class 08 - Connection Exception, 57P0x - server is shutting down or starting up.
+		return e.Code.Class() == "08" ||
+			e.Code == "57P01" ||
+			e.Code == "57P02" ||
+			e.Code == "57P03"
+	}
+
+	return strings.HasSuffix(err.Error(), "operation timed out")
+}
+
+func (postgresDialect) lastInsertID(trans *sql.Tx, res sql.Result, table string) (int64, error) {
+	var id int64
+	err := trans.QueryRow("SELECT currval(pg_get_serial_sequence(?, 'id'))", table).Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) ignoreDuplicates() string {
+	return " ON CONFLICT DO NOTHING"
+}
+
+func (postgresDialect) schemaFile() string {
+	return "create_table_postgres.sql"
+}
+
+func (postgresDialect) n3SchemaFile() string {
+	return "create_table_n3_postgres.sql"
+}
+
+func (postgresDialect) uintType() string {
+	return "bigint"
+}
+
+func (postgresDialect) serialType() string {
+	return "bigserial"
+}
+
+func (postgresDialect) textType() string {
+	return "text"
+}
+
+func (postgresDialect) tableExistsQuery() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+}
+
+// limitedWrite matches UPDATE/DELETE statements ending with a LIMIT clause,
+// which is not supported by PostgreSQL.
+var limitedWrite = regexp.MustCompile(`(?is)^(\s*(?:UPDATE|DELETE)\s.*?)\s+LIMIT\s+\d+\s*$`)
+
+// postgresSQL translates the MySQL flavoured statements of this package into PostgreSQL:
+// backtick quoted identifiers become double quoted, '?' placeholders become '$n',
+// and LIMIT clauses of UPDATE/DELETE statements are dropped.
+func postgresSQL(query string) string {
+	var result strings.Builder
+	placeholders := 0
+
+	for i, stmt := range splitStatements(query) {
+		if i > 0 {
+			result.WriteByte(';')
+		}
+
+		stmt = limitedWrite.ReplaceAllString(stmt, "$1")
+		quoted := quotedBytes(stmt)
+
+		for j := 0; j < len(stmt); j++ {
+			switch {
+			case quoted[j]:
+				result.WriteByte(stmt[j])
+			case stmt[j] == '`':
+				result.WriteByte('"')
+			case stmt[j] == '?':
+				placeholders++
+				result.WriteString(fmt.Sprintf("$%d", placeholders))
+			default:
+				result.WriteByte(stmt[j])
+			}
+		}
+	}
+
+	return result.String()
+}
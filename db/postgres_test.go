@@ -0,0 +1,23 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestPostgresSQL(t *testing.T) {
+	cases := map[string]string{
+		"SELECT `id` FROM `tx` WHERE `id` > ? AND `type` = ? LIMIT ?": `SELECT "id" FROM "tx" WHERE "id" > $1 AND "type" = $2 LIMIT $3`,
+
+		"UPDATE `counter` SET `last_tx_pk` = ? WHERE `id` = 1 LIMIT 1": `UPDATE "counter" SET "last_tx_pk" = $1 WHERE "id" = 1`,
+
+		"UPDATE `nep5` SET `total_supply` = 1 WHERE `asset_id` = 'a' LIMIT 1;UPDATE `nft` SET `transfers` = 0 WHERE `asset_id` = 'b' LIMIT 1;": `UPDATE "nep5" SET "total_supply" = 1 WHERE "asset_id" = 'a';UPDATE "nft" SET "transfers" = 0 WHERE "asset_id" = 'b';`,
+
+		"INSERT INTO `nep5_reg_info` (`name`) VALUES ('what?`;''s LIMIT 1')": `INSERT INTO "nep5_reg_info" ("name") VALUES ('what?` + "`" + `;''s LIMIT 1')`,
+	}
+
+	for mysql, expected := range cases {
+		if got := postgresSQL(mysql); got != expected {
+			t.Errorf("postgresSQL(%q) = %q, expected %q", mysql, got, expected)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package db
+
+import (
+	"database/sql"
+	"squirrel/addr"
+	"squirrel/block"
+	"squirrel/nep5"
+	"squirrel/nft"
+	"squirrel/tx"
+	"squirrel/util"
+)
+
+// Queries below serve the query api, they return a nil model if the record does not exist.
+// Paginated queries return records with pk lower than cursor in descending order,
+// a zero cursor starts from the latest record.
+
+// GetCounter returns the counter record.
+func (s *sqlStore) GetCounter() Counter {
+	return s.getCounterInstance()
+}
+
+// GetBlock returns the block at the given index.
+func (s *sqlStore) GetBlock(index uint) (*block.Block, error) {
+	return s.queryBlock("`index` = ?", index)
+}
+
+// GetBlockByHash returns the block of the given hash.
+func (s *sqlStore) GetBlockByHash(hash string) (*block.Block, error) {
+	return s.queryBlock("`hash` = ?", hash)
+}
+
+func (s *sqlStore) queryBlock(where string, arg interface{}) (*block.Block, error) {
+	query := "SELECT `id`, `hash`, `size`, `version`, `previousblockhash`, `merkleroot`, `time`, `index`, `nonce`, `nextconsensus`, `script_invocation`, `script_verification`, `nextblockhash` FROM `block` WHERE " + where + " LIMIT 1"
+
+	b := new(block.Block)
+	err := s.db.QueryRow(query, arg).Scan(
+		&b.ID,
+		&b.Hash,
+		&b.Size,
+		&b.Version,
+		&b.PreviousBlockHash,
+		&b.MerkleRoot,
+		&b.Time,
+		&b.Index,
+		&b.Nonce,
+		&b.NextConsensus,
+		&b.ScriptInvocation,
+		&b.ScriptVerification,
+		&b.NextBlockhash,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetTx returns the transaction of the given txid.
+func (s *sqlStore) GetTx(txID string) (*tx.Transaction, error) {
+	const query = "SELECT `id`, `block_index`, `block_time`, `txid`, `size`, `type`, `version`, `sys_fee`, `net_fee`, `nonce`, `script`, `gas` FROM `tx` WHERE `txid` = ? LIMIT 1"
+
+	t := new(tx.Transaction)
+	var sysFeeStr, netFeeStr, gasStr string
+	err := s.db.QueryRow(query, txID).Scan(
+		&t.ID,
+		&t.BlockIndex,
+		&t.BlockTime,
+		&t.TxID,
+		&t.Size,
+		&t.Type,
+		&t.Version,
+		&sysFeeStr,
+		&netFeeStr,
+		&t.Nonce,
+		&t.Script,
+		&gasStr,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.SysFee = util.StrToBigFloat(sysFeeStr)
+	t.NetFee = util.StrToBigFloat(netFeeStr)
+	t.Gas = util.StrToBigFloat(gasStr)
+
+	return t, nil
+}
+
+// GetAddress returns the address record.
+func (s *sqlStore) GetAddress(address string) (*addr.Address, error) {
+	const query = "SELECT `id`, `address`, `created_at`, `last_transaction_time`, `trans_asset`, `trans_nep5`, `trans_nft` FROM `address` WHERE `address` = ? LIMIT 1"
+
+	a := new(addr.Address)
+	err := s.db.QueryRow(query, address).Scan(
+		&a.ID,
+		&a.Address,
+		&a.CreatedAt,
+		&a.LastTransactionTime,
+		&a.TransAsset,
+		&a.TransNep5,
+		&a.TransNft,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetAddrAssets returns all global asset and nep5 balances of the address.
+func (s *sqlStore) GetAddrAssets(address string) ([]*addr.Asset, error) {
+	const query = "SELECT `id`, `address`, `asset_id`, `balance`, `transactions`, `last_transaction_time` FROM `addr_asset` WHERE `address` = ? ORDER BY `id` ASC"
+
+	rows, err := s.wrappedQuery(query, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []*addr.Asset{}
+	for rows.Next() {
+		a := new(addr.Asset)
+		var balanceStr string
+		if err := rows.Scan(&a.ID, &a.Address, &a.AssetID, &balanceStr, &a.Transactions, &a.LastTransactionTime); err != nil {
+			return nil, err
+		}
+
+		a.Balance = util.StrToBigFloat(balanceStr)
+		assets = append(assets, a)
+	}
+
+	return assets, rows.Err()
+}
+
+// GetAddrTxs returns a page of transactions of the address.
+func (s *sqlStore) GetAddrTxs(address string, cursor uint, limit int) ([]*addr.Tx, error) {
+	query := "SELECT `id`, `txid`, `address`, `block_time`, `asset_type` FROM `addr_tx` WHERE `address` = ?"
+	args := []interface{}{address}
+	query, args = paginate(query, args, cursor, limit)
+
+	rows, err := s.wrappedQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []*addr.Tx{}
+	for rows.Next() {
+		t := new(addr.Tx)
+		if err := rows.Scan(&t.ID, &t.TxID, &t.Address, &t.BlockTime, &t.AssetType); err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, t)
+	}
+
+	return txs, rows.Err()
+}
+
+// GetNep5Asset returns the nep5 asset.
+func (s *sqlStore) GetNep5Asset(assetID string) (*nep5.Nep5, error) {
+	const query = "SELECT `id`, `asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers` FROM `nep5` WHERE `asset_id` = ? LIMIT 1"
+
+	n := new(nep5.Nep5)
+	var totalSupplyStr string
+	err := s.db.QueryRow(query, assetID).Scan(
+		&n.ID,
+		&n.AssetID,
+		&n.AdminAddress,
+		&n.Name,
+		&n.Symbol,
+		&n.Decimals,
+		&totalSupplyStr,
+		&n.TxID,
+		&n.BlockIndex,
+		&n.BlockTime,
+		&n.Addresses,
+		&n.HoldingAddresses,
+		&n.Transfers,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n.TotalSupply = util.StrToBigFloat(totalSupplyStr)
+
+	return n, nil
+}
+
+// GetNep5Transfers returns a page of transfers of the nep5 asset.
+func (s *sqlStore) GetNep5Transfers(assetID string, cursor uint, limit int) ([]*nep5.Transaction, error) {
+	query := "SELECT `id`, `txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time` FROM `nep5_tx` WHERE `asset_id` = ?"
+	args := []interface{}{assetID}
+	query, args = paginate(query, args, cursor, limit)
+
+	rows, err := s.wrappedQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*nep5.Transaction{}
+	for rows.Next() {
+		t := new(nep5.Transaction)
+		var valueStr string
+		if err := rows.Scan(&t.ID, &t.TxID, &t.AssetID, &t.From, &t.To, &valueStr, &t.BlockIndex, &t.BlockTime); err != nil {
+			return nil, err
+		}
+
+		t.Value = util.StrToBigFloat(valueStr)
+		transfers = append(transfers, t)
+	}
+
+	return transfers, rows.Err()
+}
+
+// GetNftAsset returns the nft asset.
+func (s *sqlStore) GetNftAsset(assetID string) (*nft.Nft, error) {
+	const query = "SELECT `id`, `asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers` FROM `nft` WHERE `asset_id` = ? LIMIT 1"
+
+	n := new(nft.Nft)
+	var totalSupplyStr string
+	err := s.db.QueryRow(query, assetID).Scan(
+		&n.ID,
+		&n.AssetID,
+		&n.AdminAddress,
+		&n.Name,
+		&n.Symbol,
+		&n.Decimals,
+		&totalSupplyStr,
+		&n.TxID,
+		&n.BlockIndex,
+		&n.BlockTime,
+		&n.Addresses,
+		&n.HoldingAddresses,
+		&n.Transfers,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n.TotalSupply = util.StrToBigFloat(totalSupplyStr)
+
+	return n, nil
+}
+
+// GetNftTransfers returns a page of transfers of the nft asset.
+func (s *sqlStore) GetNftTransfers(assetID string, cursor uint, limit int) ([]*nft.Transaction, error) {
+	query := "SELECT `id`, `txid`, `asset_id`, `from`, `to`, `token_id`, `value`, `block_index`, `block_time` FROM `nft_tx` WHERE `asset_id` = ?"
+	args := []interface{}{assetID}
+	query, args = paginate(query, args, cursor, limit)
+
+	rows, err := s.wrappedQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*nft.Transaction{}
+	for rows.Next() {
+		t := new(nft.Transaction)
+		var valueStr string
+		if err := rows.Scan(&t.ID, &t.TxID, &t.AssetID, &t.From, &t.To, &t.TokenID, &valueStr, &t.BlockIndex, &t.BlockTime); err != nil {
+			return nil, err
+		}
+
+		t.Value = util.StrToBigFloat(valueStr)
+		transfers = append(transfers, t)
+	}
+
+	return transfers, rows.Err()
+}
+
+// paginate appends the cursor condition and ordering to a query selecting `id`.
+func paginate(query string, args []interface{}, cursor uint, limit int) (string, []interface{}) {
+	if cursor > 0 {
+		query += " AND `id` < ?"
+		args = append(args, cursor)
+	}
+
+	query += " ORDER BY `id` DESC LIMIT ?"
+	return query, append(args, limit)
+}
@@ -0,0 +1,591 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"sort"
+	"squirrel/cache"
+	"squirrel/config"
+	"squirrel/log"
+	"squirrel/tx"
+	"squirrel/util"
+	"strings"
+)
+
+// GetBlockHash returns hash of the stored block at the given index,
+// or an empty string if the block does not exist.
+func (s *sqlStore) GetBlockHash(index int) string {
+	const query = "SELECT `hash` FROM `block` WHERE `index` = ? LIMIT 1"
+
+	var hash string
+	err := s.db.QueryRow(query, index).Scan(&hash)
+	if err != nil && err != sql.ErrNoRows {
+		if !s.connErr(err) {
+			panic(err)
+		}
+		s.reconnect()
+		return s.GetBlockHash(index)
+	}
+
+	return hash
+}
+
+// RollbackToHeight removes every block above the given height together with
+// all data derived from them(transactions, utxos, nep5/nft transfers, balances and counters),
+// so the orphaned blocks can be replaced by the ones from the main chain.
+//
+// Address records and daily gas balances created by orphaned transactions are kept.
+func (s *sqlStore) RollbackToHeight(height int) error {
+	s.rollbackLock.Lock()
+	defer s.rollbackLock.Unlock()
+
+	err := s.doTransact(func(trans *sql.Tx) error {
+		txs, err := getTxsAboveHeight(trans, height)
+		if err != nil {
+			return err
+		}
+
+		if len(txs) > 0 {
+			if err := s.rollbackTxs(trans, height, txs); err != nil {
+				return err
+			}
+		}
+
+		if _, err := trans.Exec("DELETE FROM `block` WHERE `index` > ?", height); err != nil {
+			return err
+		}
+
+		// Events already delivered cannot be taken back, they are reverted by new ones.
+		if err := revertWatchEvents(trans, height); err != nil {
+			return err
+		}
+
+		return updateCounter(trans, "last_block_index", int64(height))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Balances were changed behind the cache, reload it.
+	cache.LoadAddrAssetInfo(s.GetAddrAssetInfo())
+
+	return nil
+}
+
+// txRolledBack tells if the given transaction no longer exists because its block was rolled back.
+func txRolledBack(trans *sql.Tx, txPK uint) (bool, error) {
+	var exists bool
+	const query = "SELECT EXISTS(SELECT `id` FROM `tx` WHERE `id` = ?)"
+	if err := trans.QueryRow(query, txPK).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	if !exists {
+		log.Printf("Transaction pk=%d was rolled back, skip it\n", txPK)
+	}
+
+	return !exists, nil
+}
+
+func getTxsAboveHeight(trans *sql.Tx, height int) ([]*tx.Transaction, error) {
+	const query = "SELECT `id`, `block_index`, `block_time`, `txid`, `type` FROM `tx` WHERE `block_index` > ? ORDER BY `id` DESC"
+	rows, err := trans.Query(query, height)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []*tx.Transaction{}
+
+	for rows.Next() {
+		var t tx.Transaction
+		if err := rows.Scan(&t.ID, &t.BlockIndex, &t.BlockTime, &t.TxID, &t.Type); err != nil {
+			return nil, err
+		}
+		txs = append(txs, &t)
+	}
+
+	return txs, rows.Err()
+}
+
+// rollbackTxs reverts the given transactions, which must be sorted by pk in descending order.
+func (s *sqlStore) rollbackTxs(trans *sql.Tx, height int, txs []*tx.Transaction) error {
+	counter, err := getCounterForUpdate(trans)
+	if err != nil {
+		return err
+	}
+
+	// Tx pk grows with block index, so every pk below this one is kept.
+	firstRemovedPk := txs[len(txs)-1].ID
+
+	txIDs := []interface{}{}
+	for _, t := range txs {
+		txIDs = append(txIDs, t.TxID)
+	}
+
+	// Revert utxo and balance changes of transactions already applied by the tx task.
+	gasAssetID := config.GetProfile().GASAssetID
+	for _, t := range txs {
+		if t.ID > counter.LastTxPk {
+			continue
+		}
+
+		if err := s.revertVinsVouts(trans, t, gasAssetID); err != nil {
+			return err
+		}
+	}
+
+	if err := revertNep5Transfers(trans, height); err != nil {
+		return err
+	}
+	if err := revertNftTransfers(trans, height); err != nil {
+		return err
+	}
+
+	cmdList := []string{
+		"DELETE FROM `nep5_tx` WHERE `block_index` > ?",
+		"DELETE FROM `nft_tx` WHERE `block_index` > ?",
+		"DELETE FROM `addr_asset` WHERE `asset_id` IN (SELECT `asset_id` FROM `nep5` WHERE `block_index` > ?)",
+		"DELETE FROM `nep5_reg_info` WHERE `nep5_id` IN (SELECT `id` FROM `nep5` WHERE `block_index` > ?)",
+		"DELETE FROM `nep5` WHERE `block_index` > ?",
+		"DELETE FROM `addr_asset_nft` WHERE `asset_id` IN (SELECT `asset_id` FROM `nft` WHERE `block_index` > ?)",
+		"DELETE FROM `nft_reg_info` WHERE `nft_id` IN (SELECT `id` FROM `nft` WHERE `block_index` > ?)",
+		"DELETE FROM `nft` WHERE `block_index` > ?",
+		"DELETE FROM `asset` WHERE `block_index` > ?",
+		"DELETE FROM `tx_claims` WHERE `block_index` > ?",
+	}
+
+	for _, cmd := range cmdList {
+		if _, err := trans.Exec(cmd, height); err != nil {
+			return err
+		}
+	}
+
+	txIDCmdList := []string{
+		"DELETE FROM `addr_tx` WHERE `txid` IN ",
+		"DELETE FROM `asset_tx` WHERE `txid` IN ",
+		"DELETE FROM `nep5_migrate` WHERE `migrate_txid` IN ",
+		"DELETE FROM `smartcontract_info` WHERE `txid` IN ",
+		"DELETE FROM `tx_disassembly` WHERE `txid` IN ",
+		"DELETE FROM `tx_attr` WHERE `txid` IN ",
+		"DELETE FROM `tx_vin` WHERE `from` IN ",
+		"DELETE FROM `tx_vout` WHERE `txid` IN ",
+		"DELETE FROM `tx_scripts` WHERE `txid` IN ",
+	}
+
+	// Keep the number of placeholders of each statement within limits of drivers.
+	const chunkSize = 500
+	for start := 0; start < len(txIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(txIDs) {
+			end = len(txIDs)
+		}
+		chunk := txIDs[start:end]
+		in := "(?" + strings.Repeat(", ?", len(chunk)-1) + ")"
+
+		for _, cmd := range txIDCmdList {
+			if _, err := trans.Exec(cmd+in, chunk...); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := trans.Exec("DELETE FROM `tx` WHERE `block_index` > ?", height); err != nil {
+		return err
+	}
+
+	// Update tx type counter.
+	txTypeCounter := countTxTypes(txs)
+	for txType, cnt := range txTypeCounter {
+		if err := updateTxCounter(trans, txType, -cnt); err != nil {
+			return err
+		}
+	}
+
+	return rewindCounter(trans, counter, firstRemovedPk)
+}
+
+// revertVinsVouts is the reverse operation of ApplyVinsVouts.
+// Transactions must be reverted from the latest one, so addr_asset records are removed
+// by the transaction which created them, i.e. once their transactions count drops to 0.
+func (s *sqlStore) revertVinsVouts(trans *sql.Tx, t *tx.Transaction, gasAssetID string) error {
+	vins, vouts, err := getTxVinsVouts(trans, t.TxID)
+	if err != nil {
+		return err
+	}
+
+	cachedVinVouts := []*tx.TransactionVout{}
+
+	for _, vin := range vins {
+		const enableUTXOSQL = "UPDATE `utxo` SET `used_in_tx` = NULL WHERE `txid` = ? AND `n` = ? LIMIT 1"
+		if _, err := trans.Exec(enableUTXOSQL, vin.TxID, vin.Vout); err != nil {
+			return err
+		}
+
+		vinVout, err := getTxVout(trans, vin.TxID, vin.Vout)
+		if err != nil {
+			return err
+		}
+		if vinVout == nil {
+			continue
+		}
+		cachedVinVouts = append(cachedVinVouts, vinVout)
+
+		query := fmt.Sprintf("UPDATE `addr_asset` SET `balance` = `balance` + %.8f WHERE `address` = '%s' AND `asset_id` = '%s' LIMIT 1", vinVout.Value, vinVout.Address, vinVout.AssetID)
+		if _, err := trans.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	for _, vout := range vouts {
+		query := fmt.Sprintf("UPDATE `addr_asset` SET `balance` = `balance` - %.8f WHERE `address` = '%s' AND `asset_id` = '%s' LIMIT 1", vout.Value, vout.Address, vout.AssetID)
+		if _, err := trans.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if _, err := trans.Exec("DELETE FROM `utxo` WHERE `txid` = ?", t.TxID); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case "ClaimTransaction":
+		if err := revertAvailable(trans, vouts, gasAssetID, true); err != nil {
+			return err
+		}
+	case "IssueTransaction":
+		if err := revertAvailable(trans, vouts, gasAssetID, false); err != nil {
+			return err
+		}
+	}
+
+	assetIDs, addrAssetPair := countTxInfo(cachedVinVouts, vouts)
+
+	var addrs []string
+	for k := range addrAssetPair {
+		addrs = append(addrs, k)
+	}
+	// Sort address to avoid potential deadlock.
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		const decrAddrTx = "UPDATE `address` SET `trans_asset` = `trans_asset` - 1 WHERE `address` = ? AND `trans_asset` > 0 LIMIT 1"
+		if _, err := trans.Exec(decrAddrTx, addr); err != nil {
+			return err
+		}
+
+		for assetID := range addrAssetPair[addr] {
+			const query = "UPDATE `addr_asset` SET `transactions` = `transactions` - 1 WHERE `address` = ? AND `asset_id` = ? AND `transactions` > 0 LIMIT 1"
+			if _, err := trans.Exec(query, addr, assetID); err != nil {
+				return err
+			}
+
+			// The record was created by this transaction.
+			res, err := trans.Exec("DELETE FROM `addr_asset` WHERE `address` = ? AND `asset_id` = ? AND `transactions` = 0", addr, assetID)
+			if err != nil {
+				return err
+			}
+			if deleted, err := res.RowsAffected(); err != nil {
+				return err
+			} else if deleted > 0 {
+				const decrAssetAddrs = "UPDATE `asset` SET `addresses` = `addresses` - 1 WHERE `asset_id` = ? AND `addresses` > 0 LIMIT 1"
+				if _, err := trans.Exec(decrAssetAddrs, assetID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for assetID := range assetIDs {
+		const query = "UPDATE `asset` SET `transactions` = `transactions` - 1 WHERE `asset_id` = ? AND `transactions` > 0 LIMIT 1"
+		if _, err := trans.Exec(query, assetID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTxVinsVouts returns vins and vouts of the transaction, read in trans like the changes reverting them.
+func getTxVinsVouts(trans *sql.Tx, txID string) ([]*tx.TransactionVin, []*tx.TransactionVout, error) {
+	rows, err := trans.Query("SELECT `from`, `txid`, `vout` FROM `tx_vin` WHERE `from` = ?", txID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vins := []*tx.TransactionVin{}
+	for rows.Next() {
+		vin := new(tx.TransactionVin)
+		if err := rows.Scan(&vin.From, &vin.TxID, &vin.Vout); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		vins = append(vins, vin)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = trans.Query("SELECT `txid`, `n`, `asset_id`, `value`, `address` FROM `tx_vout` WHERE `txid` = ?", txID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	vouts := []*tx.TransactionVout{}
+	for rows.Next() {
+		vout := new(tx.TransactionVout)
+		var valueStr string
+		if err := rows.Scan(&vout.TxID, &vout.N, &vout.AssetID, &valueStr, &vout.Address); err != nil {
+			return nil, nil, err
+		}
+		vout.Value = util.StrToBigFloat(valueStr)
+		vouts = append(vouts, vout)
+	}
+
+	return vins, vouts, rows.Err()
+}
+
+// getTxVout returns the vout spent by a vin, or nil if it does not exist.
+func getTxVout(trans *sql.Tx, txID string, n uint16) (*tx.TransactionVout, error) {
+	vout := new(tx.TransactionVout)
+	var valueStr string
+	const query = "SELECT `txid`, `n`, `asset_id`, `value`, `address` FROM `tx_vout` WHERE `txid` = ? AND `n` = ?"
+	err := trans.QueryRow(query, txID, n).Scan(&vout.TxID, &vout.N, &vout.AssetID, &valueStr, &vout.Address)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vout.Value = util.StrToBigFloat(valueStr)
+	return vout, nil
+}
+
+// revertAvailable reverts 'available' changes made by handleClaimTx(gas) or handleIssueTx.
+func revertAvailable(trans *sql.Tx, vouts []*tx.TransactionVout, gasAssetID string, gas bool) error {
+	decreased := make(map[string]*big.Float)
+
+	for _, vout := range vouts {
+		if (vout.AssetID == gasAssetID) != gas {
+			continue
+		}
+
+		if v, ok := decreased[vout.AssetID]; ok {
+			decreased[vout.AssetID] = new(big.Float).SetPrec(256).Add(v, vout.Value)
+		} else {
+			decreased[vout.AssetID] = vout.Value
+		}
+	}
+
+	for assetID, decrement := range decreased {
+		query := fmt.Sprintf("UPDATE `asset` SET `available` = `available` - %.8f WHERE `asset_id` = '%s' LIMIT 1", decrement, assetID)
+		if _, err := trans.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revertNep5Transfers reverts balance changes of nep5 transfers above the given height.
+// Records of parties left without balance and kept transfers are removed,
+// then address counters of the assets are recounted from the remaining records.
+func revertNep5Transfers(trans *sql.Tx, height int) error {
+	const query = "SELECT `asset_id`, `from`, `to`, `value` FROM `nep5_tx` WHERE `block_index` > ? ORDER BY `id` DESC"
+	rows, err := trans.Query(query, height)
+	if err != nil {
+		return err
+	}
+
+	type party struct{ addr, assetID string }
+	parties := []party{}
+	assetIDs := []string{}
+	seen := map[party]bool{}
+	changes := map[party]*big.Float{}
+
+	change := func(p party, value *big.Float) {
+		if v, ok := changes[p]; ok {
+			changes[p] = new(big.Float).SetPrec(256).Add(v, value)
+			return
+		}
+		changes[p] = value
+		parties = append(parties, p)
+		if !seen[party{assetID: p.assetID}] {
+			seen[party{assetID: p.assetID}] = true
+			assetIDs = append(assetIDs, p.assetID)
+		}
+	}
+
+	for rows.Next() {
+		var assetID, from, to, valueStr string
+		if err := rows.Scan(&assetID, &from, &to, &valueStr); err != nil {
+			rows.Close()
+			return err
+		}
+		value := util.StrToBigFloat(valueStr)
+
+		if len(from) > 0 {
+			change(party{from, assetID}, value)
+		}
+		if len(to) > 0 {
+			change(party{to, assetID}, new(big.Float).SetPrec(256).Neg(value))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Sort parties to avoid potential deadlock.
+	sort.Slice(parties, func(i, j int) bool {
+		if parties[i].addr != parties[j].addr {
+			return parties[i].addr < parties[j].addr
+		}
+		return parties[i].assetID < parties[j].assetID
+	})
+
+	for _, p := range parties {
+		query := fmt.Sprintf("UPDATE `addr_asset` SET `balance` = `balance` + %.64f WHERE `address` = ? AND `asset_id` = ? LIMIT 1", changes[p])
+		if _, err := trans.Exec(query, p.addr, p.assetID); err != nil {
+			return err
+		}
+
+		const deleteCreated = "DELETE FROM `addr_asset` WHERE `address` = ? AND `asset_id` = ? AND `balance` = 0 " +
+			"AND `address` NOT IN (SELECT `admin_address` FROM `nep5` WHERE `asset_id` = ?) " +
+			"AND NOT EXISTS (SELECT `id` FROM `nep5_tx` WHERE `asset_id` = ? AND `block_index` <= ? AND (`from` = ? OR `to` = ?))"
+		if _, err := trans.Exec(deleteCreated, p.addr, p.assetID, p.assetID, p.assetID, height, p.addr, p.addr); err != nil {
+			return err
+		}
+	}
+
+	for _, assetID := range assetIDs {
+		const recount = "UPDATE `nep5` SET " +
+			"`addresses` = (SELECT COUNT(DISTINCT `address`) FROM `addr_asset` WHERE `asset_id` = ?), " +
+			"`holding_addresses` = (SELECT COUNT(DISTINCT `address`) FROM `addr_asset` WHERE `asset_id` = ? AND `balance` > 0) " +
+			"WHERE `asset_id` = ? LIMIT 1"
+		if _, err := trans.Exec(recount, assetID, assetID, assetID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revertNftTransfers reverts balance changes of nft transfers above the given height.
+func revertNftTransfers(trans *sql.Tx, height int) error {
+	const query = "SELECT `asset_id`, `from`, `to`, `token_id`, `value` FROM `nft_tx` WHERE `block_index` > ? ORDER BY `id` DESC"
+	rows, err := trans.Query(query, height)
+	if err != nil {
+		return err
+	}
+
+	type nftTransfer struct {
+		assetID string
+		from    string
+		to      string
+		tokenID string
+		value   *big.Float
+	}
+
+	transfers := []nftTransfer{}
+
+	for rows.Next() {
+		var t nftTransfer
+		var valueStr string
+		if err := rows.Scan(&t.assetID, &t.from, &t.to, &t.tokenID, &valueStr); err != nil {
+			rows.Close()
+			return err
+		}
+		t.value = util.StrToBigFloat(valueStr)
+		transfers = append(transfers, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range transfers {
+		if len(t.from) > 0 {
+			query := fmt.Sprintf("UPDATE `addr_asset_nft` SET `balance` = `balance` + %.8f WHERE `address` = ? AND `asset_id` = ? AND `token_id` = ? LIMIT 1", t.value)
+			if _, err := trans.Exec(query, t.from, t.assetID, t.tokenID); err != nil {
+				return err
+			}
+		}
+		if len(t.to) > 0 {
+			query := fmt.Sprintf("UPDATE `addr_asset_nft` SET `balance` = `balance` - %.8f WHERE `address` = ? AND `asset_id` = ? AND `token_id` = ? LIMIT 1", t.value)
+			if _, err := trans.Exec(query, t.to, t.assetID, t.tokenID); err != nil {
+				return err
+			}
+		}
+
+		const decrTransfers = "UPDATE `nft` SET `transfers` = `transfers` - 1 WHERE `asset_id` = ? AND `transfers` > 0 LIMIT 1"
+		if _, err := trans.Exec(decrTransfers, t.assetID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getCounterForUpdate(trans *sql.Tx) (Counter, error) {
+	const query = "SELECT `last_tx_pk`, `last_asset_tx_pk`, `last_tx_pk_for_nep5`, `last_tx_pk_for_nft`, `last_tx_pk_for_sc`, `last_tx_pk_gas_balance` FROM `counter` WHERE `id` = 1 LIMIT 1 FOR UPDATE"
+
+	var counter Counter
+	err := trans.QueryRow(query).Scan(
+		&counter.LastTxPk,
+		&counter.LastAssetTxPk,
+		&counter.LastTxPkForNep5,
+		&counter.LastTxPkForNft,
+		&counter.LastTxPkForSC,
+		&counter.LastTxPkGasBalacne,
+	)
+
+	return counter, err
+}
+
+// rewindCounter moves every task pointer back to the last transaction before firstRemovedPk.
+func rewindCounter(trans *sql.Tx, counter Counter, firstRemovedPk uint) error {
+	lastKeptPk := int64(firstRemovedPk) - 1
+
+	pointers := map[string]uint{
+		"last_tx_pk":             counter.LastTxPk,
+		"last_asset_tx_pk":       counter.LastAssetTxPk,
+		"last_tx_pk_for_sc":      counter.LastTxPkForSC,
+		"last_tx_pk_gas_balance": counter.LastTxPkGasBalacne,
+	}
+	for key, pk := range pointers {
+		if pk < firstRemovedPk {
+			continue
+		}
+		if err := updateCounter(trans, key, lastKeptPk); err != nil {
+			return err
+		}
+	}
+
+	if counter.LastTxPkForNep5 >= firstRemovedPk {
+		if err := updateNep5Counter(trans, uint(lastKeptPk), -1); err != nil {
+			return err
+		}
+	}
+	if counter.LastTxPkForNft >= firstRemovedPk {
+		if err := updateNftCounter(trans, uint(lastKeptPk), -1); err != nil {
+			return err
+		}
+	}
+
+	cmdList := []string{
+		"UPDATE `counter` SET `nep5_tx_pk_for_addr_tx` = LEAST(`nep5_tx_pk_for_addr_tx`, (SELECT COALESCE(MAX(`id`), 0) FROM `nep5_tx`)) WHERE `id` = 1",
+		"UPDATE `counter` SET `nft_tx_pk_for_addr_tx` = LEAST(`nft_tx_pk_for_addr_tx`, (SELECT COALESCE(MAX(`id`), 0) FROM `nft_tx`)) WHERE `id` = 1",
+	}
+	for _, cmd := range cmdList {
+		if _, err := trans.Exec(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// resets are the statements which delete all data of a task and rewind its counters,
+// so the task starts from beginning on next run.
+// Tables are emptied by DELETE instead of TRUNCATE, which is not transactional in MySQL.
+var resets = map[string][]string{
+	"nep5": {
+		"DELETE FROM `addr_asset` WHERE LENGTH(`asset_id`) = 40",
+		"DELETE FROM `addr_tx` WHERE `asset_type` = 'nep5'",
+		"UPDATE `address` SET `trans_nep5` = 0 WHERE 1=1",
+		"UPDATE `counter` SET `last_tx_pk_for_nep5` = 0, `app_log_idx` = -1, `nep5_tx_pk_for_addr_tx` = 0 WHERE `id` = 1",
+		"DELETE FROM `nep5`",
+		"DELETE FROM `nep5_reg_info`",
+		"DELETE FROM `nep5_tx`",
+		"DELETE FROM `nep5_migrate`",
+		// Transfers stored again have the right decimals.
+		"DELETE FROM `nep5_decimals_repair`",
+	},
+	"nft": {
+		"DELETE FROM `addr_asset_nft`",
+		"DELETE FROM `addr_tx` WHERE `asset_type` = 'nft'",
+		"UPDATE `address` SET `trans_nft` = 0 WHERE 1=1",
+		"UPDATE `counter` SET `last_tx_pk_for_nft` = 0, `nft_app_log_idx` = -1, `nft_tx_pk_for_addr_tx` = 0 WHERE `id` = 1",
+		"DELETE FROM `nft`",
+		"DELETE FROM `nft_reg_info`",
+		"DELETE FROM `nft_tx`",
+		"DELETE FROM `nft_token`",
+	},
+	"sc": {
+		"UPDATE `counter` SET `last_tx_pk_for_sc` = 0 WHERE `id` = 1",
+		"DELETE FROM `smartcontract_info`",
+		"DELETE FROM `tx_disassembly`",
+	},
+	"tx": {
+		"DELETE FROM `utxo`",
+		"DELETE FROM `addr_asset` WHERE LENGTH(`asset_id`) = 66",
+		"DELETE FROM `addr_tx` WHERE `asset_type` = 'asset'",
+		"UPDATE `counter` SET `last_tx_pk` = 0, `cnt_tx_reg` = 0, `cnt_tx_miner` = 0, `cnt_tx_issue` = 0, `cnt_tx_invocation` = 0, `cnt_tx_contract` = 0, `cnt_tx_claim` = 0, `cnt_tx_publish` = 0, `cnt_tx_enrollment` = 0 WHERE `id` = 1",
+		"UPDATE `asset` SET `addresses` = 0, `available` = 0, `transactions` = 0 WHERE 1=1",
+		"UPDATE `address` SET `trans_asset` = 0 WHERE 1=1",
+		"DELETE FROM `asset_tx`",
+		"UPDATE `counter` SET `last_asset_tx_pk` = 0 WHERE `id` = 1",
+	},
+}
+
+// ResetTasks returns names of the tasks which can be reset.
+func ResetTasks() []string {
+	tasks := make([]string, 0, len(resets))
+	for task := range resets {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+
+	return tasks
+}
+
+// ResetStatements returns the statements executed to reset the task.
+func ResetStatements(task string) ([]string, bool) {
+	stmts, ok := resets[task]
+	return stmts, ok
+}
+
+// ResetTask deletes all data of the task and rewinds its counters in one transaction,
+// so the task starts from beginning on next run. Tasks must be stopped beforehand.
+func (s *sqlStore) ResetTask(task string) error {
+	stmts, ok := resets[task]
+	if !ok {
+		return fmt.Errorf("task '%s' cannot be reset", task)
+	}
+
+	return s.transact(func(trans *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := trans.Exec(stmt); err != nil {
+				return fmt.Errorf("%v: %s", err, stmt)
+			}
+		}
+		return nil
+	})
+}
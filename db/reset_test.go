@@ -0,0 +1,65 @@
+package db
+
+import (
+	"squirrel/log"
+	"testing"
+)
+
+func TestResetTask(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	s.GetCounter()
+	if err := s.UpdateLastTxPkForNep5(5, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	const nep5ID = "0123456789012345678901234567890123456789"
+	const assetID = "0x0123456789012345678901234567890123456789012345678901234567890123"
+
+	for _, query := range []string{
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES ('" + nep5ID + "', '', 'Token', 'TKN', 8, 1, '', 0, 0, 1, 1, 1)",
+		"INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('t1', '" + nep5ID + "', '', 'A', 1, 0, 0)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('A', '" + nep5ID + "', 1, 1, 0)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('A', '" + assetID + "', 1, 1, 0)",
+		"INSERT INTO `addr_tx` (`txid`, `address`, `block_time`, `asset_type`) VALUES ('t1', 'A', 0, 'nep5')",
+		"INSERT INTO `addr_tx` (`txid`, `address`, `block_time`, `asset_type`) VALUES ('t2', 'A', 0, 'asset')",
+		"INSERT INTO `address` (`address`, `created_at`, `last_transaction_time`, `trans_asset`, `trans_nep5`) VALUES ('A', 0, 0, 1, 1)",
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.ResetTask("unknown"); err == nil {
+		t.Errorf("Reset of unknown task should fail")
+	}
+
+	if err := s.ResetTask("nep5"); err != nil {
+		t.Fatal(err)
+	}
+
+	for query, expected := range map[string]int{
+		"SELECT COUNT(*) FROM `nep5`":                                            0,
+		"SELECT COUNT(*) FROM `nep5_tx`":                                         0,
+		"SELECT COUNT(*) FROM `addr_asset` WHERE `asset_id` = '" + nep5ID + "'":  0,
+		"SELECT COUNT(*) FROM `addr_asset` WHERE `asset_id` = '" + assetID + "'": 1,
+		"SELECT COUNT(*) FROM `addr_tx` WHERE `asset_type` = 'nep5'":             0,
+		"SELECT COUNT(*) FROM `addr_tx` WHERE `asset_type` = 'asset'":            1,
+		"SELECT `trans_nep5` FROM `address` WHERE `address` = 'A'":               0,
+		"SELECT `trans_asset` FROM `address` WHERE `address` = 'A'":              1,
+	} {
+		var count int
+		if err := s.db.QueryRow(query).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != expected {
+			t.Errorf("%s: expected %d, got %d", query, expected, count)
+		}
+	}
+
+	if pk, idx := s.GetLastTxPkForNep5(); pk != 0 || idx != -1 {
+		t.Errorf("Nep5 counter should be rewound, got pk=%d, idx=%d", pk, idx)
+	}
+}
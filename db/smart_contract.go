@@ -6,25 +6,41 @@ import (
 	"squirrel/util"
 )
 
-// InsertSCInfos persists new smart contracts info into db.
-func InsertSCInfos(scRegInfos []*nep5.RegInfo, txPK uint) error {
-	if len(scRegInfos) == 0 {
-		return UpdateLastTxPkForSC(txPK)
+// TxDisassembly is the disassembled script of an invocation transaction.
+type TxDisassembly struct {
+	TxID        string
+	Disassembly string
+}
+
+// InsertSCInfos persists new smart contracts info and disassembled scripts into db.
+func (s *sqlStore) InsertSCInfos(scRegInfos []*nep5.RegInfo, disassemblies []TxDisassembly, txPK uint) error {
+	if len(scRegInfos) == 0 && len(disassemblies) == 0 {
+		return s.UpdateLastTxPkForSC(txPK)
 	}
 
-	return transact(func(trans *sql.Tx) error {
-		query := "INSERT INTO `smartcontract_info`(`txid`, `script_hash`, `name`, `version`, `author`, `email`, `description`, `need_storage`, `parameter_list`, `return_type`) VALUES "
-		args := []interface{}{}
+	return s.transact(func(trans *sql.Tx) error {
+		if len(scRegInfos) > 0 {
+			query := "INSERT INTO `smartcontract_info`(`txid`, `script_hash`, `name`, `version`, `author`, `email`, `description`, `need_storage`, `parameter_list`, `return_type`) VALUES "
+			args := []interface{}{}
+
+			for _, regInfo := range scRegInfos {
+				scriptHashHex := util.GetAssetIDFromScriptHash(regInfo.ScriptHash)
+				query += "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?), "
+				args = append(args, regInfo.TxID, scriptHashHex, regInfo.Name, regInfo.Version, regInfo.Author, regInfo.Email, regInfo.Description, regInfo.NeedStorage, regInfo.ParameterList, regInfo.ReturnType)
+			}
 
-		for _, regInfo := range scRegInfos {
-			scriptHashHex := util.GetAssetIDFromScriptHash(regInfo.ScriptHash)
-			query += "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?), "
-			args = append(args, regInfo.TxID, scriptHashHex, regInfo.Name, regInfo.Version, regInfo.Author, regInfo.Email, regInfo.Description, regInfo.NeedStorage, regInfo.ParameterList, regInfo.ReturnType)
+			_, err := trans.Exec(query[:len(query)-2], args...)
+			if err != nil {
+				panic(err)
+			}
 		}
 
-		_, err := trans.Exec(query[:len(query)-2], args...)
-		if err != nil {
-			panic(err)
+		// Scripts of contract deployments are large, insert them one by one.
+		for _, d := range disassemblies {
+			const query = "INSERT INTO `tx_disassembly`(`txid`, `disassembly`) VALUES (?, ?)"
+			if _, err := trans.Exec(query, d.TxID, d.Disassembly); err != nil {
+				return err
+			}
 		}
 
 		return updateCounter(trans, "last_tx_pk_for_sc", int64(txPK))
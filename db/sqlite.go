@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const sqliteDriverName = "squirrel-sqlite"
+
+func init() {
+	sql.Register(sqliteDriverName, &rewriteDriver{
+		base:    &sqlite3.SQLiteDriver{},
+		rewrite: sqliteSQL,
+	})
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) driverName() string {
+	return sqliteDriverName
+}
+
+// connErr always returns false, there is no connection to lose.
+func (sqliteDialect) connErr(err error) bool {
+	return false
+}
+
+func (sqliteDialect) lastInsertID(trans *sql.Tx, res sql.Result, table string) (int64, error) {
+	return res.LastInsertId()
+}
+
+func (sqliteDialect) ignoreDuplicates() string {
+	return " ON CONFLICT DO NOTHING"
+}
+
+func (sqliteDialect) schemaFile() string {
+	return "create_table_sqlite.sql"
+}
+
+func (sqliteDialect) n3SchemaFile() string {
+	return "create_table_n3_sqlite.sql"
+}
+
+func (sqliteDialect) uintType() string {
+	return "bigint"
+}
+
+func (sqliteDialect) serialType() string {
+	return "integer"
+}
+
+func (sqliteDialect) textType() string {
+	return "text"
+}
+
+func (sqliteDialect) tableExistsQuery() string {
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+var (
+	forUpdate = regexp.MustCompile(`(?i)\s+FOR\s+UPDATE\s*$`)
+	least     = regexp.MustCompile(`(?i)\bLEAST\(`)
+)
+
+// sqliteSQL translates the MySQL flavoured statements of this package into SQLite:
+// LIMIT clauses of UPDATE/DELETE statements and locking clauses are dropped,
+// and LEAST is replaced by its SQLite equivalent.
+// Backtick quoted identifiers and '?' placeholders are supported by SQLite as is.
+func sqliteSQL(query string) string {
+	var result strings.Builder
+
+	for i, stmt := range splitStatements(query) {
+		if i > 0 {
+			result.WriteByte(';')
+		}
+
+		stmt = limitedWrite.ReplaceAllString(stmt, "$1")
+		stmt = forUpdate.ReplaceAllString(stmt, "")
+		stmt = least.ReplaceAllString(stmt, "MIN(")
+		result.WriteString(stmt)
+	}
+
+	return result.String()
+}
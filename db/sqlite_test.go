@@ -0,0 +1,280 @@
+package db
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"squirrel/block"
+	"squirrel/cache"
+	"squirrel/log"
+	"squirrel/nep5"
+	"squirrel/tx"
+	"testing"
+)
+
+func openSqliteStore(t *testing.T) (*sqlStore, func()) {
+	return openSqliteStoreAt(t, migrations[len(migrations)-1].version)
+}
+
+// openSqliteStoreAt opens a store whose schema is migrated up to version.
+func openSqliteStoreAt(t *testing.T, version int) (*sqlStore, func()) {
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &sqlStore{dialect: sqliteDialect{}}
+	s.db, err = sql.Open(sqliteDriverName, "file:"+filepath.Join(dir, "squirrel.db")+"?_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.migrateTo(version); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, func() {
+		s.db.Close()
+		os.RemoveAll(dir)
+		os.Remove("error.log")
+	}
+}
+
+func TestSqliteStore(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	cache.LoadAddrAssetInfo(s.GetAddrAssetInfo())
+
+	if height := s.GetLastHeight(); height != -1 {
+		t.Fatalf("Initial height should be -1, got %d", height)
+	}
+
+	zero := big.NewFloat(0)
+	blocks := []*block.Block{}
+	bulk := &tx.Bulk{}
+	for i := 0; i < 3; i++ {
+		blocks = append(blocks, &block.Block{Hash: string(rune('a' + i)), Index: uint(i)})
+		txID := string(rune('a' + i))
+		bulk.TXs = append(bulk.TXs, &tx.Transaction{BlockIndex: uint(i), TxID: txID, Type: "MinerTransaction", SysFee: zero, NetFee: zero, Gas: zero})
+		bulk.TXVouts = append(bulk.TXVouts, &tx.TransactionVout{TxID: txID, Value: zero})
+	}
+
+	if err := s.InsertBlock(2, blocks, bulk); err != nil {
+		t.Fatal(err)
+	}
+	if height := s.GetLastHeight(); height != 2 {
+		t.Fatalf("Height should be 2 after insertion, got %d", height)
+	}
+	if txs := s.GetTxs(0, 10, ""); len(txs) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(txs))
+	}
+
+	txs := s.GetTxs(0, 10, "")
+	vouts, err := s.GetVouts([]string{txs[0].TxID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vouts[txs[0].TxID][0].Address = "AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc"
+	vouts[txs[0].TxID][0].AssetID = "602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7"
+	vouts[txs[0].TxID][0].Value = big.NewFloat(1.5)
+	if err := s.ApplyVinsVouts(txs[0], nil, vouts[txs[0].TxID]); err != nil {
+		t.Fatal(err)
+	}
+	if info := s.GetAddrAssetInfo(); len(info) != 1 || info[0].Balance.String() != "1.5" {
+		t.Fatalf("Expected one address with balance 1.5, got %v", info)
+	}
+
+	if b, err := s.GetBlockByHash("b"); err != nil || b == nil || b.Index != 1 {
+		t.Fatalf("Expected block 1 by hash, got %v(err=%v)", b, err)
+	}
+	if a, err := s.GetAddress("AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc"); err != nil || a == nil || a.TransAsset != 1 {
+		t.Fatalf("Expected address with 1 transaction, got %v(err=%v)", a, err)
+	}
+	if addrTxs, err := s.GetAddrTxs("AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc", 0, 10); err != nil || len(addrTxs) != 1 {
+		t.Fatalf("Expected 1 address transaction, got %d(err=%v)", len(addrTxs), err)
+	}
+
+	if err := s.UpdateLastTxPk(2); err != nil {
+		t.Fatal(err)
+	}
+	if pk := s.GetLastTxPkCounter(); pk != 2 {
+		t.Fatalf("Expected last tx pk 2, got %d", pk)
+	}
+
+	if err := s.RollbackToHeight(0); err != nil {
+		t.Fatal(err)
+	}
+	if height := s.GetLastHeight(); height != 0 {
+		t.Fatalf("Height should be 0 after rollback, got %d", height)
+	}
+	if hash := s.GetBlockHash(1); hash != "" {
+		t.Fatalf("Block 1 should be removed, got hash %s", hash)
+	}
+	if pk := s.GetLastTxPkCounter(); pk != 1 {
+		t.Fatalf("Expected last tx pk 1 after rollback, got %d", pk)
+	}
+}
+
+func TestInsertNep5Quoted(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	n := &nep5.Nep5{AssetID: "ecc6b20d3ccac1ee9ef109af5a7cdb85706b1df9", Name: "Bob's Token", Symbol: "B'T", Decimals: 8, TotalSupply: big.NewFloat(1.5)}
+	err := s.transact(func(trans *sql.Tx) error {
+		return s.insertNep5(trans, n, &nep5.RegInfo{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := s.GetNep5Asset(n.AssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.Name != n.Name || stored.Symbol != n.Symbol || stored.TotalSupply.String() != "1.5" {
+		t.Fatalf("Unexpected nep5 asset %+v", stored)
+	}
+}
+
+func TestRollbackBalances(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	s.GetCounter()
+	cache.LoadAddrAssetInfo(s.GetAddrAssetInfo())
+
+	const (
+		addrA   = "AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc"
+		addrB   = "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"
+		assetID = "602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7"
+		nep5ID  = "ecc6b20d3ccac1ee9ef109af5a7cdb85706b1df9"
+	)
+
+	zero := big.NewFloat(0)
+	blocks := []*block.Block{}
+	bulk := &tx.Bulk{}
+	for i := 0; i < 2; i++ {
+		blocks = append(blocks, &block.Block{Hash: string(rune('a' + i)), Index: uint(i)})
+		txID := string(rune('a' + i))
+		bulk.TXs = append(bulk.TXs, &tx.Transaction{BlockIndex: uint(i), TxID: txID, Type: "MinerTransaction", SysFee: zero, NetFee: zero, Gas: zero})
+		bulk.TXVouts = append(bulk.TXVouts, &tx.TransactionVout{TxID: txID, Address: addrA, AssetID: assetID, Value: big.NewFloat(1.5)})
+	}
+	if err := s.InsertBlock(1, blocks, bulk); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts := []string{
+		"INSERT INTO `asset` (`block_index`, `block_time`, `version`, `asset_id`, `type`, `name`, `amount`, `available`, `precision`, `owner`, `admin`, `issuer`, `expiration`, `frozen`, `addresses`, `transactions`) " +
+			"VALUES (0, 0, 0, '" + assetID + "', 'GoverningToken', 'NEO', 100, 100, 0, '', '', '', 0, false, 0, 0)",
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) " +
+			"VALUES ('" + nep5ID + "', '', 'T', 'T', 0, 10, 'a', 0, 0, 2, 2, 2)",
+		"INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('a', '" + nep5ID + "', '', '" + addrA + "', 10, 0, 0)",
+		"INSERT INTO `nep5_tx` (`txid`, `asset_id`, `from`, `to`, `value`, `block_index`, `block_time`) VALUES ('b', '" + nep5ID + "', '" + addrA + "', '" + addrB + "', 4, 1, 0)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('" + addrA + "', '" + nep5ID + "', 6, 0, 0)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('" + addrB + "', '" + nep5ID + "', 4, 0, 0)",
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txs := s.GetTxs(0, 10, "")
+	for _, trans := range txs {
+		vouts, err := s.GetVouts([]string{trans.TxID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ApplyVinsVouts(trans, nil, vouts[trans.TxID]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.RollbackToHeight(0); err != nil {
+		t.Fatal(err)
+	}
+
+	balances := map[string]string{}
+	for _, info := range s.GetAddrAssetInfo() {
+		balances[info.Address+"/"+info.AssetID] = info.Balance.String()
+	}
+	if len(balances) != 2 || balances[addrA+"/"+assetID] != "1.5" || balances[addrA+"/"+nep5ID] != "10" {
+		t.Fatalf("Unexpected balances after rollback %v", balances)
+	}
+
+	var assetAddrs, nep5Addrs, nep5Holding int
+	if err := s.db.QueryRow("SELECT `addresses` FROM `asset` WHERE `asset_id` = ?", assetID).Scan(&assetAddrs); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.QueryRow("SELECT `addresses`, `holding_addresses` FROM `nep5` WHERE `asset_id` = ?", nep5ID).Scan(&nep5Addrs, &nep5Holding); err != nil {
+		t.Fatal(err)
+	}
+	if assetAddrs != 1 || nep5Addrs != 1 || nep5Holding != 1 {
+		t.Fatalf("Expected 1 address of each asset, got asset=%d, nep5=%d(holding %d)", assetAddrs, nep5Addrs, nep5Holding)
+	}
+}
+
+func TestInsertTxDisassembly(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	s.GetCounter()
+
+	disassemblies := []TxDisassembly{{TxID: "a", Disassembly: "0000  PUSH0\n0001  RET\n"}}
+	if err := s.InsertSCInfos(nil, disassemblies, 2); err != nil {
+		t.Fatal(err)
+	}
+	if pk := s.GetLastTxPkForSC(); pk != 2 {
+		t.Fatalf("Expected last tx pk for sc 2, got %d", pk)
+	}
+
+	var text string
+	if err := s.db.QueryRow("SELECT `disassembly` FROM `tx_disassembly` WHERE `txid` = 'a'").Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != disassemblies[0].Disassembly {
+		t.Fatalf("Unexpected disassembly %q", text)
+	}
+}
+
+func TestClearNep5Balance(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	const assetID = "ecc6b20d3ccac1ee9ef109af5a7cdb85706b1df9"
+	const address = "AKQjaQ7Hor11BfRnXUBvYYiY1CwUkLywyc"
+	for _, query := range []string{
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES ('" + assetID + "', '', 'Token', 'TKN', 8, 0, '', 0, 0, 1, 1, 1)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('" + address + "', '" + assetID + "', 1.5, 1, 0)",
+		"INSERT INTO `address` (`address`, `created_at`, `last_transaction_time`, `trans_asset`) VALUES ('" + address + "', 0, 0, 0)",
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.LoadAddrAssetInfo(s.GetAddrAssetInfo())
+
+	if err := s.UpdateNep5TotalSupplyAndAddrAsset(10, 1, address, big.NewFloat(0), assetID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var balance float64
+	var holding int
+	if err := s.db.QueryRow("SELECT `balance` FROM `addr_asset` WHERE `address` = ? AND `asset_id` = ?", address, assetID).Scan(&balance); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.QueryRow("SELECT `holding_addresses` FROM `nep5` WHERE `asset_id` = ?", assetID).Scan(&holding); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 0 || holding != 0 {
+		t.Fatalf("Expected zero balance without holders, got balance %v with %d holders", balance, holding)
+	}
+}
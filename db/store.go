@@ -0,0 +1,120 @@
+package db
+
+import (
+	"math/big"
+	"squirrel/addr"
+	"squirrel/block"
+	"squirrel/n3"
+	"squirrel/nep5"
+	"squirrel/nft"
+	"squirrel/tx"
+	"squirrel/watch"
+)
+
+// Reader queries indexed data.
+type Reader interface {
+	GetCounter() Counter
+	GetBlock(index uint) (*block.Block, error)
+	GetBlockByHash(hash string) (*block.Block, error)
+	GetTx(txID string) (*tx.Transaction, error)
+	GetTxAttrs(txID string) []*tx.TransactionAttribute
+	GetVins(txIDs []string) (map[string][]*tx.TransactionVin, error)
+	GetVouts(txIDs []string) (map[string][]*tx.TransactionVout, error)
+	GetAddress(address string) (*addr.Address, error)
+	GetAddrAssets(address string) ([]*addr.Asset, error)
+	GetAddrTxs(address string, cursor uint, limit int) ([]*addr.Tx, error)
+	GetNep5Asset(assetID string) (*nep5.Nep5, error)
+	GetNep5Transfers(assetID string, cursor uint, limit int) ([]*nep5.Transaction, error)
+	GetNftAsset(assetID string) (*nft.Nft, error)
+	GetNftTransfers(assetID string, cursor uint, limit int) ([]*nft.Transaction, error)
+	GetN3Transfers(contract string, cursor uint, limit int) ([]*n3.Transfer, error)
+}
+
+// Watchlist keeps addresses watched through the api and events of watched addresses.
+type Watchlist interface {
+	GetWatchedAddresses() ([]*watch.Entry, error)
+	PutWatchedAddress(e *watch.Entry) error
+	DeleteWatchedAddress(address string) (bool, error)
+	GetWatchEvents(address string, cursor uint, limit int) ([]*watch.Event, error)
+	GetPendingWatchEvents(now int64, limit int) ([]*watch.Event, error)
+	MarkWatchEventDelivered(id uint, deliveredAt int64) (bool, error)
+	RetryWatchEvent(id uint, attempts int, nextAttemptAt int64, lastError string) error
+	RevertWatchEvent(e *watch.Event) error
+}
+
+// Store is the persistence layer used by all tasks.
+type Store interface {
+	Reader
+	Watchlist
+
+	// Schema.
+	Migrate() error
+	SchemaVersion() (int, error)
+
+	// Maintenance.
+	ResetTask(task string) error
+	Verify() ([]*Mismatch, error)
+
+	// Blocks.
+	InsertBlock(maxIndex int, blocks []*block.Block, txBulk *tx.Bulk) error
+	GetLastHeight() int
+	GetBlockHash(index int) string
+	RollbackToHeight(height int) error
+
+	// Counters.
+	GetLastTxPkCounter() uint
+	GetLastAssetTxPkCounter() uint
+	GetLastTxPkForNep5() (uint, int)
+	GetLastTxPkForNft() (uint, int)
+	GetLastTxPkForSC() uint
+	GetLastTxPkForGasBalance() uint
+	GetNep5TxPkForAddrTx() uint
+	GetNftTxPkForAddrTx() uint
+	UpdateLastTxPk(txPk uint) error
+	UpdateLastTxPkForNep5(currentTxPk uint, applogIdx int) error
+	UpdateLastTxPkForNft(currentTxPk uint, applogIdx int) error
+	UpdateLastTxPkForSC(currentTxPk uint) error
+
+	// Transactions and global assets.
+	GetAddrAssetInfo() []*addr.AssetInfo
+	GetTxs(txPk uint, limit int, txType string) []*tx.Transaction
+	GetVinVout(txIDs []string) (map[string][]*tx.TransactionVin, map[string][]*tx.TransactionVout, error)
+	GetVout(txID string, n uint16) (*tx.TransactionVout, error)
+	GetHighestTxPk() uint
+	RecordAddrAssetIDTx(records []tx.AddrAssetIDTx, txPK int64) error
+	ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.TransactionVout) error
+	ApplyGASAssetChange(tx *tx.Transaction, date string, gasChangeMap map[string]*big.Float) error
+	InsertSCInfos(scRegInfos []*nep5.RegInfo, disassemblies []TxDisassembly, txPK uint) error
+
+	// NEP5.
+	GetInvocationTxs(startPk uint, limit uint) []*tx.Transaction
+	GetNep5AssetDecimals() map[string]uint8
+	GetTxScripts(txID string) ([]*tx.TransactionScripts, error)
+	GetMaxNonEmptyScriptTxPk() uint
+	InsertNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegInfo, addrAsset *addr.Asset, atHeight uint) error
+	InsertDiscoveredNep5Asset(trans *tx.Transaction, nep5 *nep5.Nep5, regInfo *nep5.RegInfo) error
+	UpdateNep5TotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, addr string, balance *big.Float, assetID string, totalSupply *big.Float) error
+	InsertNep5transaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, totalSupply *big.Float) error
+	GetNep5TxRecords(pk uint, limit int) ([]*nep5.Transaction, error)
+	InsertNep5AddrTxRec(nep5TxRecs []*nep5.Transaction, lastPk uint) error
+	HandleNEP5Migrate(newAssetAdmin, oldAssetID, newAssetID string, txPK uint, txID string) error
+	GetNep5DecimalsRepairs() ([]*nep5.DecimalsRepair, error)
+	RepairNep5TxValues(assetID string) (int64, error)
+	GetNep5Holders(assetID string) ([]string, error)
+
+	// NFT.
+	GetNftInvocationTxs(startPk uint, limit uint) []*tx.Transaction
+	GetNftAssetDecimals() map[string]uint8
+	GetNftTxScripts(txID string) ([]*tx.TransactionScripts, error)
+	GetMaxNonEmptyScriptTxPkForNft() uint
+	NftTokenExists(assetID, tokenID string) bool
+	InsertNftAsset(trans *tx.Transaction, nft *nft.Nft, regInfo *nft.NftRegInfo, atHeight uint) error
+	UpdateNftTotalSupplyAndAddrAsset(blockTime uint64, blockIndex uint, assetID string, totalSupply *big.Float) error
+	InsertNftTransaction(trans *tx.Transaction, appLogIdx int, assetID string, fromAddr string, fromBalance *big.Float, toAddr string, toBalance *big.Float, transferValue *big.Float, tokenID string, totalSupply *big.Float, nftJSONInfo string) error
+	GetNftTxRecords(pk uint, limit int) ([]*nft.Transaction, error)
+	InsertNftAddrTxRec(nftTxRecs []*nft.Transaction, lastPk uint) error
+
+	// Neo N3.
+	InsertN3Bulk(bulk *n3.Bulk) error
+	GetN3LastHeight() int
+}
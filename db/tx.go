@@ -7,13 +7,14 @@ import (
 	"sort"
 	"squirrel/asset"
 	"squirrel/cache"
+	"squirrel/config"
 	"squirrel/tx"
 	"squirrel/util"
 	"strings"
 )
 
 // GetTxs returns transactions of given tx pk range.
-func GetTxs(txPk uint, limit int, txType string) []*tx.Transaction {
+func (s *sqlStore) GetTxs(txPk uint, limit int, txType string) []*tx.Transaction {
 	txSQL := "SELECT `id`, `block_index`, `block_time`, `txid`, `size`, `type`, `version`, `sys_fee`, `net_fee`, `nonce`, `script`, `gas` FROM `tx` WHERE `id` >= ?"
 
 	if txType != "" {
@@ -22,7 +23,7 @@ func GetTxs(txPk uint, limit int, txType string) []*tx.Transaction {
 
 	txSQL += " AND (EXISTS(SELECT `id` FROM `tx_vin` WHERE `from`=`tx`.`txid` LIMIT 1) OR EXISTS (SELECT `id` FROM `tx_vout` WHERE `txid`=`tx`.`txid` LIMIT 1)) ORDER BY ID ASC LIMIT ?"
 
-	rows, err := wrappedQuery(txSQL, txPk, limit)
+	rows, err := s.wrappedQuery(txSQL, txPk, limit)
 	if err != nil {
 		panic(err)
 	}
@@ -66,14 +67,14 @@ func GetTxs(txPk uint, limit int, txType string) []*tx.Transaction {
 }
 
 // GetTxAttrs returns attributes of a transaction.
-func GetTxAttrs(txID string) []*tx.TransactionAttribute {
+func (s *sqlStore) GetTxAttrs(txID string) []*tx.TransactionAttribute {
 	query := []string{
 		"SELECT `id`, `txid`, `usage`, `data`",
 		"FROM `tx_attr`",
 		fmt.Sprintf("WHERE `txid` = '%s'", txID),
 	}
 
-	rows, err := wrappedQuery(strings.Join(query, " "))
+	rows, err := s.wrappedQuery(strings.Join(query, " "))
 	if err != nil {
 		panic(err)
 	}
@@ -101,13 +102,13 @@ func GetTxAttrs(txID string) []*tx.TransactionAttribute {
 }
 
 // GetVinVout returns correspond vouts of vins.
-func GetVinVout(txIDs []string) (map[string][]*tx.TransactionVin, map[string][]*tx.TransactionVout, error) {
-	vinMap, err := GetVins(txIDs)
+func (s *sqlStore) GetVinVout(txIDs []string) (map[string][]*tx.TransactionVin, map[string][]*tx.TransactionVout, error) {
+	vinMap, err := s.GetVins(txIDs)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	voutMap, err := GetVouts(txIDs)
+	voutMap, err := s.GetVouts(txIDs)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -116,14 +117,14 @@ func GetVinVout(txIDs []string) (map[string][]*tx.TransactionVin, map[string][]*
 }
 
 // GetVins returns all vins of the given txID.
-func GetVins(txIDs []string) (map[string][]*tx.TransactionVin, error) {
+func (s *sqlStore) GetVins(txIDs []string) (map[string][]*tx.TransactionVin, error) {
 	query := "SELECT `from`, `txid`, `vout` FROM `tx_vin` WHERE `from` IN ('"
 	query += strings.Join(txIDs, "', '")
 	query += "')"
 
 	vinMap := make(map[string][]*tx.TransactionVin)
 
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		return nil, err
 	}
@@ -148,14 +149,14 @@ func GetVins(txIDs []string) (map[string][]*tx.TransactionVin, error) {
 }
 
 // GetVouts returns all vins of the given txID.
-func GetVouts(txIDs []string) (map[string][]*tx.TransactionVout, error) {
+func (s *sqlStore) GetVouts(txIDs []string) (map[string][]*tx.TransactionVout, error) {
 	query := "SELECT `txid`, `n`, `asset_id`, `value`, `address` FROM `tx_vout` WHERE `txid` IN ('"
 	query += strings.Join(txIDs, "', '")
 	query += "')"
 
 	voutMap := make(map[string][]*tx.TransactionVout)
 
-	rows, err := wrappedQuery(query)
+	rows, err := s.wrappedQuery(query)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +184,7 @@ func GetVouts(txIDs []string) (map[string][]*tx.TransactionVout, error) {
 	return voutMap, nil
 }
 
-func handleVins(blockIndex uint, tx *sql.Tx, vins []*tx.TransactionVin, cachedVinVouts *[]*tx.TransactionVout) error {
+func (s *sqlStore) handleVins(blockIndex uint, tx *sql.Tx, vins []*tx.TransactionVin, cachedVinVouts *[]*tx.TransactionVout) error {
 	for _, vin := range vins {
 		const disableUTXOSQL = "UPDATE `utxo` SET `used_in_tx` = ? WHERE `txid` = ? AND `n` = ? LIMIT 1"
 		_, err := tx.Exec(disableUTXOSQL, vin.From, vin.TxID, vin.Vout)
@@ -191,7 +192,7 @@ func handleVins(blockIndex uint, tx *sql.Tx, vins []*tx.TransactionVin, cachedVi
 			return err
 		}
 
-		vinVout, err := GetVout(vin.TxID, vin.Vout)
+		vinVout, err := s.GetVout(vin.TxID, vin.Vout)
 		if err != nil {
 			return err
 		}
@@ -247,12 +248,12 @@ func handleVouts(blockIndex uint, blockTime uint64, tx *sql.Tx, vouts []*tx.Tran
 }
 
 // RecordAddrAssetIDTx records {address, asset_id, txid}.
-func RecordAddrAssetIDTx(records []tx.AddrAssetIDTx, txPK int64) error {
+func (s *sqlStore) RecordAddrAssetIDTx(records []tx.AddrAssetIDTx, txPK int64) error {
 	if len(records) == 0 {
 		return nil
 	}
 
-	return transact(func(trans *sql.Tx) error {
+	return s.transact(func(trans *sql.Tx) error {
 		piece := 100
 
 		for start := 0; start < len(records); start += piece {
@@ -285,11 +286,15 @@ func RecordAddrAssetIDTx(records []tx.AddrAssetIDTx, txPK int64) error {
 }
 
 // ApplyVinsVouts process transaction and update related db table info.
-func ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.TransactionVout) error {
-	return transact(func(trans *sql.Tx) error {
+func (s *sqlStore) ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.TransactionVout) error {
+	return s.transact(func(trans *sql.Tx) error {
+		if rolledBack, err := txRolledBack(trans, t.ID); err != nil || rolledBack {
+			return err
+		}
+
 		cachedVinVouts := []*tx.TransactionVout{}
 
-		if err := handleVins(t.BlockIndex, trans, vins, &cachedVinVouts); err != nil {
+		if err := s.handleVins(t.BlockIndex, trans, vins, &cachedVinVouts); err != nil {
 			return err
 		}
 
@@ -321,13 +326,14 @@ func ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.Tr
 			return err
 		}
 
+		gasAssetID := config.GetProfile().GASAssetID
 		if t.Type == "ClaimTransaction" {
-			if err := handleClaimTx(trans, vouts); err != nil {
+			if err := handleClaimTx(trans, vouts, gasAssetID); err != nil {
 				return err
 			}
 		}
 		if t.Type == "IssueTransaction" {
-			if err := handleIssueTx(trans, vouts); err != nil {
+			if err := handleIssueTx(trans, vouts, gasAssetID); err != nil {
 				return err
 			}
 		}
@@ -336,6 +342,10 @@ func ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.Tr
 			return err
 		}
 
+		if err := insertWatchEvents(trans, t, assetWatchEvents(cachedVinVouts, vouts)); err != nil {
+			return err
+		}
+
 		if createdAddrCnt > 0 {
 			if err := incrAddrCounter(trans, createdAddrCnt); err != nil {
 				return err
@@ -350,16 +360,16 @@ func ApplyVinsVouts(t *tx.Transaction, vins []*tx.TransactionVin, vouts []*tx.Tr
 	})
 }
 
-func handleClaimTx(tx *sql.Tx, vouts []*tx.TransactionVout) error {
+func handleClaimTx(tx *sql.Tx, vouts []*tx.TransactionVout, gasAssetID string) error {
 	gas := big.NewFloat(0)
 
 	for _, vout := range vouts {
-		if vout.AssetID == asset.GASAssetID {
+		if vout.AssetID == gasAssetID {
 			gas = new(big.Float).SetPrec(256).Add(gas, vout.Value)
 		}
 	}
 
-	query := fmt.Sprintf("UPDATE `asset` SET `available` = `available` + %.8f WHERE `asset_id` = '%s' LIMIT 1", gas, asset.GASAssetID)
+	query := fmt.Sprintf("UPDATE `asset` SET `available` = `available` + %.8f WHERE `asset_id` = '%s' LIMIT 1", gas, gasAssetID)
 	if _, err := tx.Exec(query); err != nil {
 		return err
 	}
@@ -367,11 +377,11 @@ func handleClaimTx(tx *sql.Tx, vouts []*tx.TransactionVout) error {
 	return nil
 }
 
-func handleIssueTx(tx *sql.Tx, vouts []*tx.TransactionVout) error {
+func handleIssueTx(tx *sql.Tx, vouts []*tx.TransactionVout, gasAssetID string) error {
 	issued := make(map[string]*big.Float)
 
 	for _, vout := range vouts {
-		if vout.AssetID != asset.GASAssetID {
+		if vout.AssetID != gasAssetID {
 			if _, ok := issued[vout.AssetID]; !ok {
 				issued[vout.AssetID] = vout.Value
 			} else {
@@ -440,11 +450,11 @@ func updateTxInfo(tx *sql.Tx, blockTime uint64, txID string, addrs []string, ass
 }
 
 // GetVout returns vouts of a transaction.
-func GetVout(txID string, n uint16) (*tx.TransactionVout, error) {
+func (s *sqlStore) GetVout(txID string, n uint16) (*tx.TransactionVout, error) {
 	vout := new(tx.TransactionVout)
 	valueStr := ""
 	const query = "SELECT `txid`, `n`, `asset_id`, `value`, `address` FROM `tx_vout` WHERE `txid` = ? AND `n` = ?"
-	err := db.QueryRow(query, txID, n).Scan(
+	err := s.db.QueryRow(query, txID, n).Scan(
 		// &vout.ID,
 		&vout.TxID,
 		&vout.N,
@@ -465,16 +475,16 @@ func GetVout(txID string, n uint16) (*tx.TransactionVout, error) {
 }
 
 // GetHighestTxPk returns maximum pk of tx.
-func GetHighestTxPk() uint {
+func (s *sqlStore) GetHighestTxPk() uint {
 	var pk uint
 	const query = "SELECT `id` FROM `tx` WHERE EXISTS (SELECT `id` FROM `tx_vin` WHERE `from`=`tx`.`txid` LIMIT 1) OR EXISTS (SELECT `id` FROM `tx_vout` WHERE `txid`=`tx`.`txid` LIMIT 1) ORDER BY `id` DESC LIMIT 1"
-	err := db.QueryRow(query).Scan(&pk)
+	err := s.db.QueryRow(query).Scan(&pk)
 	if err != nil && err != sql.ErrNoRows {
-		if !connErr(err) {
+		if !s.connErr(err) {
 			panic(err)
 		}
-		reconnect()
-		return GetHighestTxPk()
+		s.reconnect()
+		return s.GetHighestTxPk()
 	}
 
 	return pk
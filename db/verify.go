@@ -0,0 +1,136 @@
+package db
+
+import "fmt"
+
+// maxMismatches is the number of mismatches reported by each verification.
+const maxMismatches = 100
+
+// Mismatch is a value kept by tasks which differs from the one computed from stored records.
+type Mismatch struct {
+	// Check names the verification.
+	Check string
+	// Subject is the verified record, e.g. an asset id.
+	Subject  string
+	Stored   string
+	Computed string
+}
+
+// verification is a query returning subject, stored and computed values of mismatching records.
+type verification struct {
+	check string
+	query string
+}
+
+// counter columns of transaction types.
+var txTypeCounters = []struct {
+	txType string
+	column string
+}{
+	{"ClaimTransaction", "cnt_tx_claim"},
+	{"ContractTransaction", "cnt_tx_contract"},
+	{"InvocationTransaction", "cnt_tx_invocation"},
+	{"IssueTransaction", "cnt_tx_issue"},
+	{"MinerTransaction", "cnt_tx_miner"},
+	{"RegisterTransaction", "cnt_tx_reg"},
+	{"PublishTransaction", "cnt_tx_publish"},
+	{"EnrollmentTransaction", "cnt_tx_enrollment"},
+}
+
+// verifications returns the checks of sqls/verification.sql.
+func verifications() []verification {
+	v := []verification{
+		{
+			check: "nep5 addresses",
+			query: "SELECT `nep5`.`asset_id`, `nep5`.`addresses`, COUNT(DISTINCT `addr_asset`.`address`) " +
+				"FROM `nep5` JOIN `addr_asset` ON `nep5`.`asset_id` = `addr_asset`.`asset_id` " +
+				"GROUP BY `nep5`.`asset_id`, `nep5`.`addresses` " +
+				"HAVING COUNT(DISTINCT `addr_asset`.`address`) != `nep5`.`addresses`",
+		},
+		{
+			check: "nep5 holding addresses",
+			query: "SELECT `nep5`.`asset_id`, `nep5`.`holding_addresses`, COUNT(DISTINCT `addr_asset`.`address`) " +
+				"FROM `nep5` JOIN `addr_asset` ON `nep5`.`asset_id` = `addr_asset`.`asset_id` " +
+				"WHERE `addr_asset`.`balance` > 0 " +
+				"GROUP BY `nep5`.`asset_id`, `nep5`.`holding_addresses` " +
+				"HAVING COUNT(DISTINCT `addr_asset`.`address`) != `nep5`.`holding_addresses`",
+		},
+		{
+			check: "asset addresses",
+			query: "SELECT `asset`.`asset_id`, `asset`.`addresses`, COUNT(DISTINCT `addr_asset`.`address`) " +
+				"FROM `asset` JOIN `addr_asset` ON `asset`.`asset_id` = `addr_asset`.`asset_id` " +
+				"GROUP BY `asset`.`asset_id`, `asset`.`addresses` " +
+				"HAVING COUNT(DISTINCT `addr_asset`.`address`) != `asset`.`addresses`",
+		},
+		{
+			check: "total addresses",
+			query: "SELECT 'address', (SELECT COUNT(*) FROM `address`), (SELECT COUNT(DISTINCT `address`) FROM `addr_asset`) " +
+				"FROM `counter` WHERE `id` = 1 " +
+				"AND (SELECT COUNT(*) FROM `address`) != (SELECT COUNT(DISTINCT `address`) FROM `addr_asset`)",
+		},
+		{
+			check: "nep5 total supply",
+			query: "SELECT `nep5`.`asset_id`, `nep5`.`total_supply`, SUM(`addr_asset`.`balance`) " +
+				"FROM `nep5` JOIN `addr_asset` ON `nep5`.`asset_id` = `addr_asset`.`asset_id` " +
+				"GROUP BY `nep5`.`asset_id`, `nep5`.`total_supply` " +
+				"HAVING SUM(`addr_asset`.`balance`) != `nep5`.`total_supply`",
+		},
+		{
+			check: "asset available",
+			query: "SELECT `asset`.`asset_id`, `asset`.`available`, SUM(`addr_asset`.`balance`) " +
+				"FROM `asset` JOIN `addr_asset` ON `asset`.`asset_id` = `addr_asset`.`asset_id` " +
+				"GROUP BY `asset`.`asset_id`, `asset`.`available` " +
+				"HAVING SUM(`addr_asset`.`balance`) != `asset`.`available`",
+		},
+	}
+
+	for _, c := range txTypeCounters {
+		count := fmt.Sprintf("(SELECT COUNT(`id`) FROM `tx` WHERE `type` = '%s')", c.txType)
+		v = append(v, verification{
+			check: "transaction counter",
+			query: fmt.Sprintf("SELECT '%s', `%s`, %s FROM `counter` WHERE `id` = 1 AND `%s` != %s",
+				c.column, c.column, count, c.column, count),
+		})
+	}
+
+	// Stored is the number of addr_asset records of an address missing from table address.
+	v = append(v, verification{
+		check: "addr_asset address",
+		query: "SELECT `addr_asset`.`address`, COUNT(*), 0 " +
+			"FROM `addr_asset` LEFT JOIN `address` ON `address`.`address` = `addr_asset`.`address` " +
+			"WHERE `address`.`address` IS NULL " +
+			"GROUP BY `addr_asset`.`address` ORDER BY MIN(`addr_asset`.`id`)",
+	})
+
+	return v
+}
+
+// Verify compares counts and sums kept by tasks with the ones computed from stored records,
+// and returns the mismatches, at most maxMismatches of each check.
+// Values kept by running tasks may be ahead or behind for a moment.
+func (s *sqlStore) Verify() ([]*Mismatch, error) {
+	mismatches := []*Mismatch{}
+
+	for _, v := range verifications() {
+		rows, err := s.wrappedQuery(fmt.Sprintf("%s LIMIT %d", v.query, maxMismatches))
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %v", v.check, err)
+		}
+
+		for rows.Next() {
+			m := &Mismatch{Check: v.check}
+			if err := rows.Scan(&m.Subject, &m.Stored, &m.Computed); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			mismatches = append(mismatches, m)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mismatches, nil
+}
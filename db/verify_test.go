@@ -0,0 +1,51 @@
+package db
+
+import (
+	"squirrel/log"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	s.GetCounter()
+
+	for _, query := range []string{
+		"INSERT INTO `nep5` (`asset_id`, `admin_address`, `name`, `symbol`, `decimals`, `total_supply`, `txid`, `block_index`, `block_time`, `addresses`, `holding_addresses`, `transfers`) VALUES ('a', '', 'Token', 'TKN', 8, 10, '', 0, 0, 2, 1, 2)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('A', 'a', 4, 1, 0)",
+		"INSERT INTO `addr_asset` (`address`, `asset_id`, `balance`, `transactions`, `last_transaction_time`) VALUES ('B', 'a', 0, 1, 0)",
+		"INSERT INTO `address` (`address`, `created_at`, `last_transaction_time`, `trans_asset`, `trans_nep5`) VALUES ('A', 0, 0, 0, 1)",
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mismatches, err := s.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]Mismatch{
+		"nep5 total supply":  {Subject: "a", Stored: "10", Computed: "4"},
+		"total addresses":    {Subject: "address", Stored: "1", Computed: "2"},
+		"addr_asset address": {Subject: "B", Stored: "1", Computed: "0"},
+	}
+
+	if len(mismatches) != len(expected) {
+		t.Fatalf("Expected %d mismatches, got %d", len(expected), len(mismatches))
+	}
+
+	for _, m := range mismatches {
+		e, ok := expected[m.Check]
+		if !ok {
+			t.Errorf("Unexpected mismatch %+v", m)
+			continue
+		}
+		if m.Subject != e.Subject || m.Stored != e.Stored || m.Computed != e.Computed {
+			t.Errorf("Mismatch of %s should be %+v, got %+v", m.Check, e, m)
+		}
+	}
+}
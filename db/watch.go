@@ -0,0 +1,263 @@
+package db
+
+import (
+	"database/sql"
+	"math/big"
+	"squirrel/asset"
+	"squirrel/tx"
+	"squirrel/watch"
+	"strings"
+	"time"
+)
+
+// GetWatchedAddresses returns addresses watched through the api.
+func (s *sqlStore) GetWatchedAddresses() ([]*watch.Entry, error) {
+	const query = "SELECT `address`, `asset_ids`, `webhook` FROM `watch_address` ORDER BY `created_at` ASC, `address` ASC"
+	rows, err := s.wrappedQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*watch.Entry{}
+	for rows.Next() {
+		var e watch.Entry
+		var assetIDs string
+		if err := rows.Scan(&e.Address, &assetIDs, &e.Webhook); err != nil {
+			return nil, err
+		}
+		e.AssetIDs = []string{}
+		if assetIDs != "" {
+			e.AssetIDs = strings.Split(assetIDs, ",")
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// PutWatchedAddress watches the address of e, or replaces its asset ids and webhook if already watched.
+func (s *sqlStore) PutWatchedAddress(e *watch.Entry) error {
+	return s.transact(func(trans *sql.Tx) error {
+		createdAt := time.Now().Unix()
+		if err := trans.QueryRow("SELECT `created_at` FROM `watch_address` WHERE `address` = ?", e.Address).Scan(&createdAt); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if _, err := trans.Exec("DELETE FROM `watch_address` WHERE `address` = ?", e.Address); err != nil {
+			return err
+		}
+
+		const query = "INSERT INTO `watch_address` (`address`, `asset_ids`, `webhook`, `created_at`) VALUES (?, ?, ?, ?)"
+		_, err := trans.Exec(query, e.Address, strings.Join(e.AssetIDs, ","), e.Webhook, createdAt)
+		return err
+	})
+}
+
+// DeleteWatchedAddress stops watching the address, and reports if it was watched.
+// Pending events of the address are still delivered.
+func (s *sqlStore) DeleteWatchedAddress(address string) (bool, error) {
+	var deleted int64
+	err := s.transact(func(trans *sql.Tx) error {
+		res, err := trans.Exec("DELETE FROM `watch_address` WHERE `address` = ?", address)
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+
+	return deleted > 0, err
+}
+
+const watchEventColumns = "`id`, `address`, `asset_type`, `asset_id`, `token_id`, `txid`, `block_index`, `block_time`, `from`, `to`, `value`, `reverts`, " +
+	"`webhook`, `attempts`, `next_attempt_at`, `delivered_at`, `last_error`"
+
+func scanWatchEvents(rows *sql.Rows) ([]*watch.Event, error) {
+	events := []*watch.Event{}
+	for rows.Next() {
+		var e watch.Event
+		err := rows.Scan(&e.ID, &e.Address, &e.AssetType, &e.AssetID, &e.TokenID, &e.TxID, &e.BlockIndex, &e.BlockTime, &e.From, &e.To, &e.Value, &e.Reverts,
+			&e.Webhook, &e.Attempts, &e.NextAttemptAt, &e.DeliveredAt, &e.LastError)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetWatchEvents returns events of the address with pk below cursor in descending order, from the latest if cursor is 0.
+func (s *sqlStore) GetWatchEvents(address string, cursor uint, limit int) ([]*watch.Event, error) {
+	query := "SELECT " + watchEventColumns + " FROM `watch_event` WHERE `address` = ?"
+	args := []interface{}{address}
+	query, args = paginate(query, args, cursor, limit)
+
+	rows, err := s.wrappedQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWatchEvents(rows)
+}
+
+// GetPendingWatchEvents returns undelivered events due at now, in the order they were recorded.
+func (s *sqlStore) GetPendingWatchEvents(now int64, limit int) ([]*watch.Event, error) {
+	query := "SELECT " + watchEventColumns + " FROM `watch_event` WHERE `delivered_at` = 0 AND `next_attempt_at` <= ? ORDER BY `id` ASC LIMIT ?"
+	rows, err := s.wrappedQuery(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWatchEvents(rows)
+}
+
+// MarkWatchEventDelivered records the delivery of the event,
+// and reports false if the event was removed by a rollback meanwhile.
+func (s *sqlStore) MarkWatchEventDelivered(id uint, deliveredAt int64) (bool, error) {
+	var marked int64
+	err := s.transact(func(trans *sql.Tx) error {
+		res, err := trans.Exec("UPDATE `watch_event` SET `attempts` = `attempts` + 1, `delivered_at` = ?, `last_error` = '' WHERE `id` = ?", deliveredAt, id)
+		if err != nil {
+			return err
+		}
+		marked, err = res.RowsAffected()
+		return err
+	})
+
+	return marked > 0, err
+}
+
+// RetryWatchEvent records a failed delivery of the event, which is attempted again at nextAttemptAt.
+func (s *sqlStore) RetryWatchEvent(id uint, attempts int, nextAttemptAt int64, lastError string) error {
+	return s.transact(func(trans *sql.Tx) error {
+		_, err := trans.Exec("UPDATE `watch_event` SET `attempts` = ?, `next_attempt_at` = ?, `last_error` = ? WHERE `id` = ?", attempts, nextAttemptAt, lastError, id)
+		return err
+	})
+}
+
+// RevertWatchEvent records the event which reverts e, delivered after its block was rolled back.
+func (s *sqlStore) RevertWatchEvent(e *watch.Event) error {
+	return s.transact(func(trans *sql.Tx) error {
+		return insertWatchEvent(trans, e.Reverting())
+	})
+}
+
+// revertWatchEvents removes pending events of blocks above height,
+// and records events which revert the delivered ones.
+func revertWatchEvents(trans *sql.Tx, height int) error {
+	// Events reverting others are kept, since the reverted ones may be delivered.
+	if _, err := trans.Exec("DELETE FROM `watch_event` WHERE `block_index` > ? AND `delivered_at` = 0 AND `reverts` = 0", height); err != nil {
+		return err
+	}
+
+	query := "SELECT " + watchEventColumns + " FROM `watch_event` WHERE `block_index` > ? AND `delivered_at` > 0 AND `reverts` = 0 " +
+		"AND `id` NOT IN (SELECT `reverts` FROM `watch_event` WHERE `reverts` > 0) ORDER BY `id` ASC"
+	rows, err := trans.Query(query, height)
+	if err != nil {
+		return err
+	}
+	delivered, err := scanWatchEvents(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range delivered {
+		if err := insertWatchEvent(trans, e.Reverting()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertWatchEvents records events of the transaction, they are delivered once the transaction is committed.
+func insertWatchEvents(trans *sql.Tx, t *tx.Transaction, events []*watch.Event) error {
+	for _, e := range events {
+		if e == nil {
+			continue
+		}
+
+		e.TxID = t.TxID
+		e.BlockIndex = t.BlockIndex
+		e.BlockTime = t.BlockTime
+		if err := insertWatchEvent(trans, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertWatchEvent(trans *sql.Tx, e *watch.Event) error {
+	const query = "INSERT INTO `watch_event` (`address`, `asset_type`, `asset_id`, `token_id`, `txid`, `block_index`, `block_time`, `from`, `to`, `value`, `reverts`, " +
+		"`webhook`, `attempts`, `next_attempt_at`, `delivered_at`, `last_error`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, '')"
+
+	_, err := trans.Exec(query, e.Address, e.AssetType, e.AssetID, e.TokenID, e.TxID, e.BlockIndex, e.BlockTime, e.From, e.To, e.Value, e.Reverts, e.Webhook)
+	return err
+}
+
+// assetWatchEvents returns events of watched addresses whose global asset balances are changed by spent and new vouts.
+func assetWatchEvents(spent []*tx.TransactionVout, vouts []*tx.TransactionVout) []*watch.Event {
+	type addrAsset struct{ addr, assetID string }
+	changes := map[addrAsset]*big.Float{}
+	keys := []addrAsset{}
+
+	add := func(vout *tx.TransactionVout, neg bool) {
+		if _, ok := watch.Match(vout.Address, vout.AssetID); !ok {
+			return
+		}
+
+		value := vout.Value
+		if neg {
+			value = new(big.Float).SetPrec(256).Neg(value)
+		}
+
+		k := addrAsset{vout.Address, vout.AssetID}
+		if v, ok := changes[k]; ok {
+			changes[k] = new(big.Float).SetPrec(256).Add(v, value)
+			return
+		}
+		changes[k] = value
+		keys = append(keys, k)
+	}
+
+	for _, vout := range spent {
+		add(vout, true)
+	}
+	for _, vout := range vouts {
+		add(vout, false)
+	}
+
+	events := []*watch.Event{}
+	for _, k := range keys {
+		events = append(events, watch.NewEvent(k.addr, asset.ASSET, k.assetID, changes[k]))
+	}
+
+	return events
+}
+
+// transferWatchEvents returns events of watched parties of a nep5 or nft transfer.
+func transferWatchEvents(assetType string, assetID string, tokenID string, from string, to string, value *big.Float) []*watch.Event {
+	if from == to {
+		return nil
+	}
+
+	events := []*watch.Event{
+		watch.NewEvent(from, assetType, assetID, new(big.Float).SetPrec(256).Neg(value)),
+		watch.NewEvent(to, assetType, assetID, value),
+	}
+	for _, e := range events {
+		if e != nil {
+			e.TokenID = tokenID
+			e.From = from
+			e.To = to
+		}
+	}
+
+	return events
+}
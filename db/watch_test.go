@@ -0,0 +1,117 @@
+package db
+
+import (
+	"math/big"
+	"squirrel/log"
+	"squirrel/tx"
+	"squirrel/watch"
+	"testing"
+)
+
+const (
+	testWatchedAddr = "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"
+	testWatchAsset  = "ecc6b20d3ccac1ee9ef109af5a7cdb85706b1df9"
+)
+
+func TestWatchedAddresses(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	e := &watch.Entry{Address: testWatchedAddr, AssetIDs: []string{testWatchAsset}}
+	for i := 0; i < 2; i++ {
+		if err := s.PutWatchedAddress(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := s.GetWatchedAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Address != testWatchedAddr || len(entries[0].AssetIDs) != 1 || entries[0].AssetIDs[0] != testWatchAsset {
+		t.Fatalf("Unexpected watched addresses %+v", entries)
+	}
+
+	for _, expected := range []bool{true, false} {
+		deleted, err := s.DeleteWatchedAddress(testWatchedAddr)
+		if err != nil || deleted != expected {
+			t.Fatalf("Expected deleted=%v, got %v(err=%v)", expected, deleted, err)
+		}
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	log.Init()
+	s, cleanup := openSqliteStore(t)
+	defer cleanup()
+
+	s.GetCounter()
+	watch.Set([]*watch.Entry{{Address: testWatchedAddr, AssetIDs: []string{testWatchAsset}}})
+	defer watch.Set(nil)
+
+	const insertTx = "INSERT INTO `tx` (`id`, `block_index`, `block_time`, `txid`, `size`, `type`, `version`, `sys_fee`, `net_fee`, `nonce`, `script`, `gas`) " +
+		"VALUES (1, 10, 1000, '0x01', 0, 'InvocationTransaction', 0, 0, 0, 0, '', 0)"
+	if _, err := s.db.Exec(insertTx); err != nil {
+		t.Fatal(err)
+	}
+
+	trans := &tx.Transaction{ID: 1, BlockIndex: 10, BlockTime: 1000, TxID: "0x01"}
+	value := big.NewFloat(2.5)
+	if err := s.InsertNep5transaction(trans, 0, testWatchAsset, testWatchedAddr, nil, "B", nil, value, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Other assets of the address are not watched.
+	if err := s.InsertNep5transaction(trans, 1, "a6", testWatchedAddr, nil, "B", nil, value, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.GetPendingWatchEvents(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	e := events[0]
+	if e.Address != testWatchedAddr || e.AssetType != "nep5" || e.TxID != "0x01" || e.BlockIndex != 10 || e.Value != "-2.5" || e.To != "B" {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+
+	if err := s.RetryWatchEvent(e.ID, 1, 100, "timeout"); err != nil {
+		t.Fatal(err)
+	}
+	if events, err := s.GetPendingWatchEvents(99, 10); err != nil || len(events) != 0 {
+		t.Fatalf("Expected the event to be retried later, got %d events(err=%v)", len(events), err)
+	}
+
+	if marked, err := s.MarkWatchEventDelivered(e.ID, 100); err != nil || !marked {
+		t.Fatalf("Expected the event to be marked delivered, got %v(err=%v)", marked, err)
+	}
+	if events, err := s.GetPendingWatchEvents(100, 10); err != nil || len(events) != 0 {
+		t.Fatalf("Expected no pending event, got %d(err=%v)", len(events), err)
+	}
+
+	events, err = s.GetWatchEvents(testWatchedAddr, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Attempts != 2 || events[0].DeliveredAt != 100 || events[0].LastError != "" {
+		t.Fatalf("Unexpected delivered events %+v", events)
+	}
+
+	// Delivered events of rolled back blocks are reverted once.
+	for i := 0; i < 2; i++ {
+		if err := s.RollbackToHeight(9); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err = s.GetPendingWatchEvents(100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Reverts != e.ID || events[0].Value != "2.5" || events[0].TxID != "0x01" {
+		t.Fatalf("Unexpected reverting events %+v", events)
+	}
+}
@@ -5,12 +5,13 @@ require (
 	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-errors/errors v1.0.1
 	github.com/go-sql-driver/mysql v1.5.0
-	github.com/json-iterator/go v1.1.9 // indirect
-	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/lib/pq v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/mitchellh/mapstructure v1.1.2
+	github.com/prometheus/client_golang v1.11.0
 	github.com/spf13/viper v1.6.2
 	github.com/valyala/fasthttp v1.9.0
-	golang.org/x/crypto v0.0.0-20200323165209-0ec3e9974c59
-	golang.org/x/text v0.3.2 // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )
 
-go 1.13
+go 1.16
@@ -0,0 +1,40 @@
+package log
+
+import "fmt"
+
+// Level is the severity of log entries.
+type Level int
+
+// Levels of log entries.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// ParseLevel returns the level of its name, info for empty name.
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+
+	for level, n := range levelNames {
+		if n == name {
+			return level, nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unsupported log level '%s'", name)
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
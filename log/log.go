@@ -1,58 +1,317 @@
+// Package log writes levelled log entries with fields, as text or json.
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
-	// Log is the logger for normal use
-	Log *log.Logger
-	// Error is the Logger for errors
-	Error *log.Logger
+	// Log is the logger for normal use, it writes info entries.
+	Log = log.New(levelWriter(LevelInfo), "", 0)
+	// Error is the Logger for errors, it writes error entries with the caller as field.
+	Error = log.New(levelWriter(LevelError), "", log.Lshortfile)
 )
 
 const errLogName = "error.log"
 
-// Init creates logger instance to loggers
-func Init() {
-	initLogger()
-	initErrorLogger()
+// Formats of log entries.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures output of log entries.
+type Options struct {
+	// Level is the lowest level written, one of 'debug', 'info'(default), 'warn' or 'error'.
+	Level string
+	// Format is 'text'(default) or 'json'.
+	Format string
+	// Output is 'stdout'(default), 'stderr' or the path of a file entries are appended to.
+	// For stdout, warn and error entries are written to stderr.
+	Output string
+	// ErrorFile is the path of a file error entries are also appended to, none if empty.
+	ErrorFile string
+}
+
+// Fields are key-value pairs attached to log entries, e.g. task, height, txid or rpc url.
+type Fields map[string]interface{}
+
+type logger struct {
+	mu        sync.Mutex
+	level     Level
+	format    string
+	prefix    string
+	out       io.Writer
+	errOut    io.Writer
+	errFile   io.Writer
+	openFiles []*os.File
 }
 
-func initLogger() {
-	flag := log.Ldate | log.Ltime
-	Log = log.New(os.Stdout, "", flag)
+var std = &logger{
+	level:  LevelInfo,
+	format: FormatText,
+	out:    os.Stdout,
+	errOut: os.Stderr,
 }
 
-func initErrorLogger() {
-	f, err := os.OpenFile(errLogName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0640)
+// Init creates logger instance to loggers,
+// entries are written as text to stdout and errors are also appended to 'error.log'.
+func Init() {
+	err := Configure(Options{ErrorFile: errLogName})
 	if err != nil {
 		panic(err)
 	}
+}
+
+// Configure applies opts to all later entries, files of previous options are closed.
+// Current options stay unchanged on error.
+func Configure(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+
+	format := opts.Format
+	switch format {
+	case "":
+		format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unsupported log format '%s'", opts.Format)
+	}
+
+	files := []*os.File{}
+	openFile := func(path string) (*os.File, error) {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0640)
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+		return f, nil
+	}
+
+	var out, errOut, errFile io.Writer
+	switch opts.Output {
+	case "", "stdout":
+		out, errOut = os.Stdout, os.Stderr
+	case "stderr":
+		out, errOut = os.Stderr, os.Stderr
+	default:
+		f, err := openFile(opts.Output)
+		if err != nil {
+			return err
+		}
+		out, errOut = f, f
+	}
+
+	if opts.ErrorFile != "" {
+		f, err := openFile(opts.ErrorFile)
+		if err != nil {
+			return err
+		}
+		errFile = f
+	}
+
+	std.mu.Lock()
+	defer std.mu.Unlock()
 
-	errHandler := io.MultiWriter(os.Stderr, f)
-	flag := log.Ldate | log.Ltime | log.Lshortfile
-	Error = log.New(errHandler, "", flag)
+	for _, f := range std.openFiles {
+		f.Close()
+	}
+
+	std.level = level
+	std.format = format
+	std.out = out
+	std.errOut = errOut
+	std.errFile = errFile
+	std.openFiles = files
+
+	return nil
 }
 
 // UpdatePrefix Sets new prefix
 func UpdatePrefix(prefix string) {
-	if prefix != "" {
-		prefix = fmt.Sprintf("[%s] ", prefix)
-	}
-	Log.SetPrefix(prefix)
-	Error.SetPrefix(prefix)
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.prefix = prefix
 }
 
-// Printf is the alias for Log.Printf
+// Enabled tells if entries of level are written.
+func Enabled(level Level) bool {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	return level >= std.level
+}
+
+// Printf is the alias for Infof.
 func Printf(format string, v ...interface{}) {
-	Log.Printf(format, v...)
+	std.write(LevelInfo, nil, fmt.Sprintf(format, v...))
 }
 
-// Println is the alias for Log.Printf
+// Println is the alias for Infof of its operands.
 func Println(v ...interface{}) {
-	Log.Println(v...)
+	std.write(LevelInfo, nil, fmt.Sprintln(v...))
+}
+
+// Debugf writes a debug entry.
+func Debugf(format string, v ...interface{}) {
+	std.write(LevelDebug, nil, fmt.Sprintf(format, v...))
+}
+
+// Infof writes an info entry.
+func Infof(format string, v ...interface{}) {
+	std.write(LevelInfo, nil, fmt.Sprintf(format, v...))
+}
+
+// Warnf writes a warn entry.
+func Warnf(format string, v ...interface{}) {
+	std.write(LevelWarn, nil, fmt.Sprintf(format, v...))
+}
+
+// Errorf writes an error entry.
+func Errorf(format string, v ...interface{}) {
+	std.write(LevelError, nil, fmt.Sprintf(format, v...))
+}
+
+// Entry writes log entries with fields.
+type Entry struct {
+	fields Fields
+}
+
+// With returns an entry writing fields.
+func With(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+// With returns an entry writing fields besides the ones of e.
+func (e *Entry) With(fields Fields) *Entry {
+	merged := make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{fields: merged}
+}
+
+// Debugf writes a debug entry with fields of e.
+func (e *Entry) Debugf(format string, v ...interface{}) {
+	std.write(LevelDebug, e.fields, fmt.Sprintf(format, v...))
+}
+
+// Infof writes an info entry with fields of e.
+func (e *Entry) Infof(format string, v ...interface{}) {
+	std.write(LevelInfo, e.fields, fmt.Sprintf(format, v...))
+}
+
+// Warnf writes a warn entry with fields of e.
+func (e *Entry) Warnf(format string, v ...interface{}) {
+	std.write(LevelWarn, e.fields, fmt.Sprintf(format, v...))
+}
+
+// Errorf writes an error entry with fields of e.
+func (e *Entry) Errorf(format string, v ...interface{}) {
+	std.write(LevelError, e.fields, fmt.Sprintf(format, v...))
+}
+
+// levelWriter writes output of a standard logger as entries of level.
+// The 'file:line: ' prefix of log.Lshortfile becomes the caller field.
+type levelWriter Level
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	var fields Fields
+
+	if Level(w) == LevelError {
+		if i := strings.Index(msg, ": "); i > 0 && strings.Contains(msg[:i], ".go:") {
+			fields = Fields{"caller": msg[:i]}
+			msg = msg[i+2:]
+		}
+	}
+
+	std.write(Level(w), fields, msg)
+	return len(p), nil
+}
+
+func (l *logger) write(level Level, fields Fields, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return
+	}
+
+	line := l.formatEntry(time.Now(), level, fields, strings.TrimRight(msg, "\n"))
+
+	out := l.out
+	if level >= LevelWarn {
+		out = l.errOut
+	}
+	out.Write(line)
+
+	if level == LevelError && l.errFile != nil {
+		l.errFile.Write(line)
+	}
+}
+
+func (l *logger) formatEntry(t time.Time, level Level, fields Fields, msg string) []byte {
+	if l.format == FormatJSON {
+		entry := make(map[string]interface{}, len(fields)+4)
+		for k, v := range fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			entry[k] = v
+		}
+		entry["time"] = t.Format(time.RFC3339Nano)
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		if l.prefix != "" {
+			entry["label"] = l.prefix
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			line, _ = json.Marshal(map[string]string{"time": entry["time"].(string), "level": level.String(), "msg": msg})
+		}
+		return append(line, '\n')
+	}
+
+	var b bytes.Buffer
+	b.WriteString(t.Format("2006/01/02 15:04:05 "))
+	if l.prefix != "" {
+		fmt.Fprintf(&b, "[%s] ", l.prefix)
+	}
+	fmt.Fprintf(&b, "%-5s %s", strings.ToUpper(level.String()), msg)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fmt.Sprint(fields[k])
+		if v == "" || strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes()
 }
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer Configure(Options{})
+
+	output := filepath.Join(dir, "squirrel.log")
+	errorFile := filepath.Join(dir, "error.log")
+	if err := Configure(Options{Level: "warn", Format: FormatJSON, Output: output, ErrorFile: errorFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	Infof("skipped")
+	With(Fields{"task": "block", "height": 10}).Warnf("warned\n")
+	Error.Printf("failed")
+
+	lines := readLines(t, output)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries of warn level and above, got %d", len(lines))
+	}
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "warn" || entry["msg"] != "warned" || entry["task"] != "block" || entry["height"] != float64(10) {
+		t.Errorf("Unexpected warn entry: %v", entry)
+	}
+
+	entry = map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "failed" || !strings.HasPrefix(entry["caller"].(string), "log_test.go:") {
+		t.Errorf("Unexpected error entry: %v", entry)
+	}
+
+	if errors := readLines(t, errorFile); len(errors) != 1 || errors[0] != lines[1] {
+		t.Errorf("Error file should only have the error entry, got %v", errors)
+	}
+
+	if err := Configure(Options{Level: "verbose"}); err == nil {
+		t.Errorf("Unsupported level should be rejected")
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
@@ -1,45 +1,234 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"squirrel/api"
 	"squirrel/config"
 	"squirrel/db"
 	"squirrel/log"
+	"squirrel/metrics"
+	"squirrel/notify"
 	"squirrel/rpc"
 	"squirrel/tasks"
+	"squirrel/watch"
+	"strconv"
+	"syscall"
 	"time"
-	// "squirrel/tasks"
 )
 
-var enableMail bool
+const (
+	// startResume continues from the last persisted block, backfilling blocks missed while down.
+	startResume = "resume"
+	// startTip skips all blocks between the last persisted block and the current chain height.
+	startTip = "tip"
+)
+
+var (
+	enableMail bool
+	startMode  string
+	recordPath string
+	taskList   string
+	configPath string
+)
 
 func init() {
-	flag.BoolVar(&enableMail, "mail", false, "If mail alert is enabled")
+	flag.BoolVar(&enableMail, "mail", false, "If aliyun mail alert is enabled, other notifiers are enabled in config")
+	flag.StringVar(&startMode, "start", startResume, "Where block sync starts from: 'resume', 'tip' or a block height")
+	flag.StringVar(&recordPath, "record", "", "Record rpc responses into the given fixture file on shutdown, which can be replayed in tests")
+	flag.StringVar(&taskList, "tasks", "", "Comma separated tasks to run instead of the ones enabled in config, e.g. 'block,nep5'")
+	flag.StringVar(&configPath, "config", os.Getenv("SQUIRREL_CONFIG"), "Path of the config file, searched in './config' and '../config' if empty, defaults to $SQUIRREL_CONFIG")
+	flag.Usage = usage
 }
 
 func main() {
 	flag.Parse()
 
 	log.Init()
+
+	command, args := flag.Arg(0), flag.Args()
+	if len(args) > 0 {
+		args = args[1:]
+	}
+
+	switch command {
+	case "", "run":
+		// Flags of run are also accepted after the command.
+		flag.CommandLine.Parse(args)
+		if flag.NArg() > 0 {
+			usageError("unexpected arguments %v", flag.Args())
+		}
+		run()
+	case "migrate":
+		parseCommandFlags(command, args)
+		store := openStore()
+		version, err := store.SchemaVersion()
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("Database schema is up to date(version=%d)\n", version)
+	case "repair-nep5-decimals":
+		parseCommandFlags(command, args)
+		if err := tasks.RepairNep5Decimals(context.Background(), openStore()); err != nil {
+			panic(err)
+		}
+	case "reset":
+		resetTask(args)
+	case "verify":
+		parseCommandFlags(command, args)
+		verify()
+	case "status":
+		parseCommandFlags(command, args)
+		status()
+	case "config":
+		checkConfig(args)
+	default:
+		usageError("unknown command '%s'", command)
+	}
+}
+
+// openStore loads configs and connects to database.
+// The schema is always upgraded before it is used.
+func openStore() db.Store {
+	config.SetFile(configPath)
 	config.Load(false)
-	db.Init()
+	notify.Init(enableMail)
+	store := db.Init()
+
+	if err := store.Migrate(); err != nil {
+		panic(err)
+	}
+
+	return store
+}
+
+// run indexes the chain with the selected tasks until SIGINT/SIGTERM.
+func run() {
+	selection, err := tasks.ParseSelection(taskList)
+	if err != nil {
+		panic(err)
+	}
+
+	store := openStore()
+	fixtures := startRecording(recordPath)
+
+	profile := config.GetProfile()
+	log.Printf("Indexing %s %s(magic=%d)\n", config.GetNetwork(), profile.Name, profile.Magic)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleSignals(cancel)
+
+	if err := rpc.CheckMagic(ctx, profile.Magic); err != nil {
+		panic(err)
+	}
+	go rpc.TraceBestHeight(ctx)
+
+	if apiConfig := config.GetAPIConfig(); apiConfig.Enabled {
+		go api.Serve(ctx, apiConfig.Listen, store)
+	}
+
+	if metricsConfig := config.GetMetricsConfig(); metricsConfig.Enabled {
+		go metrics.Serve(ctx, metricsConfig.Listen, tasks.Health)
+	}
+
+	if _, err := watch.Load(store); err != nil {
+		panic(err)
+	}
+	config.OnChange(func() {
+		if _, err := watch.Load(store); err != nil {
+			log.Error.Printf("Failed to reload watchlist: %v\n", err)
+		}
+	})
+	go watch.Dispatch(ctx, store)
 
-	go rpc.TraceBestHeight()
+	lastHeight := getStartHeight(store, startMode)
+	log.Printf("Block sync starts after height: %d(mode=%s)\n", lastHeight, startMode)
+	tasks.Run(ctx, store, lastHeight, selection)
+
+	if fixtures != nil {
+		if err := fixtures.Save(recordPath); err != nil {
+			panic(err)
+		}
+		log.Printf("Recorded %d rpc responses into %s\n", fixtures.Len(), recordPath)
+	}
+
+	log.Println("Shutdown completed")
+}
+
+// startRecording records rpc responses if path is not empty,
+// responses already recorded in the file are kept.
+func startRecording(path string) *rpc.Fixtures {
+	if path == "" {
+		return nil
+	}
+
+	fixtures, err := rpc.LoadFixtures(path)
+	if os.IsNotExist(err) {
+		fixtures, err = rpc.NewFixtures(), nil
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	rpc.Record(fixtures)
+	return fixtures
+}
+
+// handleSignals cancels all tasks on SIGINT/SIGTERM,
+// a second signal terminates the process immediately.
+func handleSignals(cancel context.CancelFunc) {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-c
+	log.Printf("Received signal %v, waiting for all tasks to flush their data. Send again to force exit\n", sig)
+	cancel()
+
+	sig = <-c
+	log.Printf("Received signal %v, force exit\n", sig)
+	os.Exit(1)
+}
+
+// getStartHeight returns the height of the last block considered persisted,
+// so block sync starts from the block right after it.
+func getStartHeight(store db.Store, mode string) int {
+	dbHeight := tasks.LastHeight(store)
+
+	switch mode {
+	case startResume:
+		return dbHeight
+	case startTip:
+		tip := waitForChainHeight()
+		if tip < dbHeight {
+			return dbHeight
+		}
+		return tip
+	}
+
+	height, err := strconv.Atoi(mode)
+	if err != nil || height < 0 {
+		panic(fmt.Errorf("invalid start mode '%s', must be '%s', '%s' or a block height", mode, startResume, startTip))
+	}
+
+	if height <= dbHeight {
+		panic(fmt.Errorf("cannot start at height %d, blocks up to %d are already persisted", height, dbHeight))
+	}
+
+	return height - 1
+}
+
+func waitForChainHeight() int {
 	log.Println("Waiting for chain last height..")
-	lastHeight := 0
+
 	for {
-		lastHeight = rpc.BestHeight.Get()
-		if lastHeight != 0 {
-			break
+		if height := rpc.BestHeight.Get(); height != 0 {
+			log.Printf("Chain lastHeight loaded: %d\n", height)
+			return height
 		}
 		time.Sleep(1 * time.Second)
 	}
-	// cause we wanna look up on the last 50 blocks in case we missed something
-	// lastHeight -= 50
-
-	log.Printf("Chain lastHeight loaded: %d\n", lastHeight)
-	tasks.Run(lastHeight)
-
-	select {}
 }
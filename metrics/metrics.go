@@ -0,0 +1,74 @@
+// Package metrics serves prometheus metrics of all pipeline stages
+// and health checks over http.
+// Metrics themselves are defined by the packages which own the measured values.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"squirrel/log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Checker reports health of the process.
+type Checker interface {
+	// Live returns an error if the process should be restarted.
+	Live() error
+	// Ready returns an error if the process should not serve traffic.
+	Ready() error
+}
+
+// Serve starts the metrics server and blocks until ctx is cancelled.
+func Serve(ctx context.Context, listen string, checker Checker) {
+	srv := &http.Server{
+		Addr:         listen,
+		Handler:      NewHandler(checker),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Metrics listening on %s\n", listen)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Error.Printf("Metrics server stopped: %v\n", err)
+	}
+}
+
+// NewHandler returns the http handler of /metrics, /healthz and /readyz.
+func NewHandler(checker Checker) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, checker.Live())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		err := checker.Live()
+		if err == nil {
+			err = checker.Ready()
+		}
+		respond(w, err)
+	})
+
+	return mux
+}
+
+func respond(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
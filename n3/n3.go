@@ -0,0 +1,367 @@
+// Package n3 parses Neo N3 blocks and application logs into db models.
+package n3
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"squirrel/rpc"
+	"squirrel/util"
+	"strings"
+)
+
+// ContractManagement is the hash of the native contract which deploys, updates and destroys contracts.
+const ContractManagement = "0xfffdc93764dbaddd97c48f252a53ea4643faa3fd"
+
+// Token standards of transfers.
+const (
+	NEP17 = "NEP-17"
+	NEP11 = "NEP-11"
+)
+
+// Block db model.
+type Block struct {
+	ID                 uint
+	Hash               string
+	Size               int
+	Version            uint
+	PreviousBlockHash  string
+	MerkleRoot         string
+	Time               uint64
+	Index              uint
+	Nonce              string
+	Primary            int
+	NextConsensus      string
+	ScriptInvocation   string
+	ScriptVerification string
+	TxCount            int
+}
+
+// Transaction db model.
+// Fees and gas consumed are integers in the smallest unit of GAS.
+type Transaction struct {
+	ID              uint
+	BlockIndex      uint
+	BlockTime       uint64
+	Hash            string
+	Size            uint
+	Version         uint
+	Nonce           uint32
+	Sender          string
+	SysFee          int64
+	NetFee          int64
+	ValidUntilBlock uint
+	Script          string
+	VMState         string
+	Exception       string
+	GasConsumed     int64
+}
+
+// Signer db model.
+// Allowed contracts and groups are joined by ','.
+type Signer struct {
+	TxID             string
+	Account          string
+	Scopes           string
+	AllowedContracts string
+	AllowedGroups    string
+}
+
+// Witness db model, N is the index of the witness in its transaction.
+type Witness struct {
+	TxID         string
+	N            int
+	Invocation   string
+	Verification string
+}
+
+// Transfer db model of NEP-17 and NEP-11 transfers.
+// TxID is the block hash for transfers of 'OnPersist' and 'PostPersist' executions.
+// From is empty for mints and To is empty for burns, TokenID is empty for NEP-17 transfers.
+type Transfer struct {
+	ID         uint
+	TxID       string
+	BlockIndex uint
+	BlockTime  uint64
+	// NotifyIdx is the index of the notification in its application log.
+	NotifyIdx int
+	Contract  string
+	Standard  string
+	From      string
+	To        string
+	Amount    *big.Int
+	TokenID   string
+}
+
+// Contract db model of ContractManagement events.
+// Event is one of 'Deploy', 'Update' or 'Destroy',
+// Name and Standards are read from the contract manifest once the event is indexed.
+type Contract struct {
+	ID         uint
+	Hash       string
+	Event      string
+	TxID       string
+	BlockIndex uint
+	BlockTime  uint64
+	Name       string
+	Standards  string
+}
+
+// Bulk is all records of blocks to be persisted together.
+type Bulk struct {
+	Blocks    []*Block
+	Txs       []*Transaction
+	Signers   []*Signer
+	Witnesses []*Witness
+	Transfers []*Transfer
+	Contracts []*Contract
+}
+
+// LogHashes returns hashes of all application logs of blocks,
+// which are hashes of blocks and their transactions.
+func LogHashes(rawBlocks []*rpc.RawN3Block) []string {
+	hashes := []string{}
+	for _, b := range rawBlocks {
+		hashes = append(hashes, b.Hash)
+		for _, t := range b.Tx {
+			hashes = append(hashes, t.Hash)
+		}
+	}
+	return hashes
+}
+
+// Parse parses blocks and application logs keyed by block or transaction hash.
+func Parse(rawBlocks []*rpc.RawN3Block, logs map[string]*rpc.RawN3ApplicationLog) (*Bulk, error) {
+	bulk := &Bulk{}
+
+	for _, rawBlock := range rawBlocks {
+		b := &Block{
+			Hash:              rawBlock.Hash,
+			Size:              rawBlock.Size,
+			Version:           rawBlock.Version,
+			PreviousBlockHash: rawBlock.PreviousBlockHash,
+			MerkleRoot:        rawBlock.MerkleRoot,
+			Time:              rawBlock.Time,
+			Index:             rawBlock.Index,
+			Nonce:             rawBlock.Nonce,
+			Primary:           rawBlock.Primary,
+			NextConsensus:     rawBlock.NextConsensus,
+			TxCount:           len(rawBlock.Tx),
+		}
+		if len(rawBlock.Witnesses) > 0 {
+			b.ScriptInvocation = rawBlock.Witnesses[0].Invocation
+			b.ScriptVerification = rawBlock.Witnesses[0].Verification
+		}
+		bulk.Blocks = append(bulk.Blocks, b)
+
+		// Block executions emit mints and burns of fees.
+		blockLog, ok := logs[rawBlock.Hash]
+		if !ok {
+			return nil, fmt.Errorf("application log of block %s is missing", rawBlock.Hash)
+		}
+		bulk.parseLog(b, rawBlock.Hash, blockLog)
+
+		for _, rawTx := range rawBlock.Tx {
+			appLog, ok := logs[rawTx.Hash]
+			if !ok {
+				return nil, fmt.Errorf("application log of transaction %s is missing", rawTx.Hash)
+			}
+
+			if err := bulk.parseTx(b, &rawTx, appLog); err != nil {
+				return nil, err
+			}
+			bulk.parseLog(b, rawTx.Hash, appLog)
+		}
+	}
+
+	return bulk, nil
+}
+
+func (bulk *Bulk) parseTx(b *Block, rawTx *rpc.RawN3Tx, appLog *rpc.RawN3ApplicationLog) error {
+	t := &Transaction{
+		BlockIndex:      b.Index,
+		BlockTime:       b.Time,
+		Hash:            rawTx.Hash,
+		Size:            rawTx.Size,
+		Version:         rawTx.Version,
+		Nonce:           rawTx.Nonce,
+		Sender:          rawTx.Sender,
+		ValidUntilBlock: rawTx.ValidUntilBlock,
+		Script:          rawTx.Script,
+	}
+
+	var err error
+	if t.SysFee, err = parseInt(rawTx.SysFee); err != nil {
+		return fmt.Errorf("invalid sysfee of transaction %s: %v", rawTx.Hash, err)
+	}
+	if t.NetFee, err = parseInt(rawTx.NetFee); err != nil {
+		return fmt.Errorf("invalid netfee of transaction %s: %v", rawTx.Hash, err)
+	}
+
+	if len(appLog.Executions) > 0 {
+		e := appLog.Executions[0]
+		t.VMState = e.VMState
+		t.Exception = e.Exception
+		if t.GasConsumed, err = parseInt(e.GasConsumed); err != nil {
+			return fmt.Errorf("invalid gasconsumed of transaction %s: %v", rawTx.Hash, err)
+		}
+	}
+
+	bulk.Txs = append(bulk.Txs, t)
+
+	for _, s := range rawTx.Signers {
+		bulk.Signers = append(bulk.Signers, &Signer{
+			TxID:             rawTx.Hash,
+			Account:          s.Account,
+			Scopes:           s.Scopes,
+			AllowedContracts: strings.Join(s.AllowedContracts, ","),
+			AllowedGroups:    strings.Join(s.AllowedGroups, ","),
+		})
+	}
+
+	for n, w := range rawTx.Witnesses {
+		bulk.Witnesses = append(bulk.Witnesses, &Witness{
+			TxID:         rawTx.Hash,
+			N:            n,
+			Invocation:   w.Invocation,
+			Verification: w.Verification,
+		})
+	}
+
+	return nil
+}
+
+// parseLog collects transfers and contract events of halted executions,
+// notifications of faulted executions were reverted.
+func (bulk *Bulk) parseLog(b *Block, hash string, appLog *rpc.RawN3ApplicationLog) {
+	notifyIdx := 0
+
+	for _, e := range appLog.Executions {
+		for _, n := range e.Notifications {
+			idx := notifyIdx
+			notifyIdx++
+
+			if e.VMState != "HALT" {
+				continue
+			}
+
+			if transfer, ok := ParseTransfer(n); ok {
+				transfer.TxID = hash
+				transfer.BlockIndex = b.Index
+				transfer.BlockTime = b.Time
+				transfer.NotifyIdx = idx
+				bulk.Transfers = append(bulk.Transfers, transfer)
+				continue
+			}
+
+			if contract, ok := ParseContractEvent(n); ok {
+				contract.TxID = hash
+				contract.BlockIndex = b.Index
+				contract.BlockTime = b.Time
+				bulk.Contracts = append(bulk.Contracts, contract)
+			}
+		}
+	}
+}
+
+// ParseTransfer parses a 'Transfer' notification,
+// NEP-17 transfers have 3 arguments (from, to, amount) and NEP-11 transfers have a 4th argument, the token id.
+// It returns false for other notifications, including malformed transfers.
+func ParseTransfer(n rpc.RawN3Notification) (*Transfer, bool) {
+	if n.EventName != "Transfer" {
+		return nil, false
+	}
+
+	args := n.State.Array()
+	if len(args) != 3 && len(args) != 4 {
+		return nil, false
+	}
+
+	from, ok := parseAddress(args[0])
+	if !ok {
+		return nil, false
+	}
+	to, ok := parseAddress(args[1])
+	if !ok {
+		return nil, false
+	}
+	amount, ok := args[2].Integer()
+	if !ok || amount.Sign() < 0 {
+		return nil, false
+	}
+
+	t := &Transfer{
+		Contract: n.Contract,
+		Standard: NEP17,
+		From:     from,
+		To:       to,
+		Amount:   amount,
+	}
+
+	if len(args) == 4 {
+		tokenID, ok := args[3].Bytes()
+		if !ok {
+			return nil, false
+		}
+		t.Standard = NEP11
+		t.TokenID = hex.EncodeToString(tokenID)
+	}
+
+	return t, true
+}
+
+// ParseContractEvent parses 'Deploy', 'Update' and 'Destroy' notifications of ContractManagement,
+// whose only argument is the contract hash.
+func ParseContractEvent(n rpc.RawN3Notification) (*Contract, bool) {
+	if n.Contract != ContractManagement {
+		return nil, false
+	}
+
+	switch n.EventName {
+	case "Deploy", "Update", "Destroy":
+	default:
+		return nil, false
+	}
+
+	args := n.State.Array()
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	hash, ok := args[0].Bytes()
+	if !ok || len(hash) != 20 {
+		return nil, false
+	}
+
+	return &Contract{
+		Hash:  "0x" + util.GetAssetIDFromScriptHash(hash),
+		Event: n.EventName,
+	}, true
+}
+
+// parseAddress returns the N3 address of a script hash item, or empty string for null.
+func parseAddress(item rpc.RawN3StackItem) (string, bool) {
+	if item.IsNull() {
+		return "", true
+	}
+
+	scriptHash, ok := item.Bytes()
+	if !ok || len(scriptHash) != 20 {
+		return "", false
+	}
+
+	return util.GetN3AddressFromScriptHash(scriptHash), true
+}
+
+func parseInt(str string) (int64, error) {
+	if str == "" {
+		return 0, nil
+	}
+
+	v, ok := new(big.Int).SetString(str, 10)
+	if !ok || !v.IsInt64() {
+		return 0, fmt.Errorf("'%s' is not an integer", str)
+	}
+
+	return v.Int64(), nil
+}
@@ -0,0 +1,60 @@
+package n3
+
+import (
+	"encoding/json"
+	"squirrel/rpc"
+	"testing"
+)
+
+func notification(t *testing.T, data string) rpc.RawN3Notification {
+	n := rpc.RawN3Notification{}
+	if err := json.Unmarshal([]byte(data), &n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestParseTransfer(t *testing.T) {
+	// Burn of 1 GAS, with the amount serialized as a ByteString.
+	n := notification(t, `{"contract":"0xd2a4cff31913016155e38e474a2c06d08be276cf","eventname":"Transfer","state":{"type":"Array","value":[
+		{"type":"ByteString","value":"9WPqQLwoPU0OBcSOowWz8qBzQO8="},{"type":"Any"},{"type":"ByteString","value":"AOH1BQ=="}]}}`)
+	transfer, ok := ParseTransfer(n)
+	if !ok || transfer.Standard != NEP17 || transfer.From != "NiHURyS83nX2mpxtA7xq84cGxVbHojj5Wc" || transfer.To != "" || transfer.Amount.String() != "100000000" {
+		t.Errorf("Expected NEP-17 burn, got %+v", transfer)
+	}
+
+	n = notification(t, `{"contract":"0xe887d34bbae57e47007826a8d87b80120b10cbb8","eventname":"Transfer","state":{"type":"Array","value":[
+		{"type":"Any"},{"type":"ByteString","value":"9WPqQLwoPU0OBcSOowWz8qBzQO8="},{"type":"Integer","value":"1"},{"type":"ByteString","value":"AQI="}]}}`)
+	transfer, ok = ParseTransfer(n)
+	if !ok || transfer.Standard != NEP11 || transfer.TokenID != "0102" {
+		t.Errorf("Expected NEP-11 mint, got %+v", transfer)
+	}
+
+	for _, data := range []string{
+		// Not a script hash.
+		`{"contract":"0x01","eventname":"Transfer","state":{"type":"Array","value":[{"type":"Any"},{"type":"ByteString","value":"AQI="},{"type":"Integer","value":"1"}]}}`,
+		// Negative amount.
+		`{"contract":"0x01","eventname":"Transfer","state":{"type":"Array","value":[{"type":"Any"},{"type":"Any"},{"type":"Integer","value":"-1"}]}}`,
+		// Wrong number of arguments.
+		`{"contract":"0x01","eventname":"Transfer","state":{"type":"Array","value":[{"type":"Any"},{"type":"Any"}]}}`,
+		`{"contract":"0x01","eventname":"Approval","state":{"type":"Array","value":[{"type":"Any"},{"type":"Any"},{"type":"Integer","value":"1"}]}}`,
+	} {
+		if transfer, ok := ParseTransfer(notification(t, data)); ok {
+			t.Errorf("Expected %s to be ignored, got %+v", data, transfer)
+		}
+	}
+}
+
+func TestParseContractEvent(t *testing.T) {
+	n := notification(t, `{"contract":"0xfffdc93764dbaddd97c48f252a53ea4643faa3fd","eventname":"Deploy","state":{"type":"Array","value":[
+		{"type":"ByteString","value":"9WPqQLwoPU0OBcSOowWz8qBzQO8="}]}}`)
+	contract, ok := ParseContractEvent(n)
+	if !ok || contract.Event != "Deploy" || contract.Hash != "0xef4073a0f2b305a38ec4050e4d3d28bc40ea63f5" {
+		t.Errorf("Expected deployment of NEO contract, got %+v", contract)
+	}
+
+	n.Contract = "0xef4073a0f2b305a38ec4050e4d3d28bc40ea63f5"
+	if contract, ok := ParseContractEvent(n); ok {
+		t.Errorf("Expected events of other contracts to be ignored, got %+v", contract)
+	}
+}
@@ -39,6 +39,25 @@ type RegInfo struct {
 	ReturnType    string
 }
 
+// UnknownRegInfo is the value of text fields of registration info of assets discovered from their transfers,
+// whose registration transactions were not recognized.
+const UnknownRegInfo = "unknown"
+
+// NewUnknownRegInfo returns registration info of an asset discovered from its transfers.
+func NewUnknownRegInfo(txID string, scriptHash []byte) *RegInfo {
+	return &RegInfo{
+		TxID:          txID,
+		ScriptHash:    scriptHash,
+		Name:          UnknownRegInfo,
+		Version:       UnknownRegInfo,
+		Author:        UnknownRegInfo,
+		Email:         UnknownRegInfo,
+		Description:   UnknownRegInfo,
+		ParameterList: UnknownRegInfo,
+		ReturnType:    UnknownRegInfo,
+	}
+}
+
 // Transaction db model.
 type Transaction struct {
 	ID         uint
@@ -51,6 +70,15 @@ type Transaction struct {
 	BlockTime  uint64
 }
 
+// DecimalsRepair db model of an asset whose transfers up to MaxTxPk
+// were stored with 8 decimals instead of its own.
+type DecimalsRepair struct {
+	AssetID    string
+	Decimals   uint8
+	MaxTxPk    uint
+	RepairedAt int64
+}
+
 // Tx represents nep5 transaction model.
 type Tx struct {
 	ID    uint
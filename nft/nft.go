@@ -45,6 +45,7 @@ type Transaction struct {
 	AssetID    string
 	From       string
 	To         string
+	TokenID    string
 	Value      *big.Float
 	BlockIndex uint
 	BlockTime  uint64
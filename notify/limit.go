@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"squirrel/config"
+	"squirrel/log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRateCount  = 10
+	defaultRatePeriod = time.Hour
+)
+
+// rateLimiter allows a limited number of alerts per period for each notifier.
+// Send times are persisted to stateFile, so restarts in a crash loop keep being limited.
+type rateLimiter struct {
+	mu        sync.Mutex
+	stateFile string
+	limits    map[string]config.RateLimitConfig
+	state     map[string]*limitState
+	now       func() time.Time
+}
+
+type limitState struct {
+	// Sent holds unix times of alerts sent within the period.
+	Sent []int64 `json:"sent"`
+	// Suppressed is the number of alerts dropped since the last sent one.
+	Suppressed int `json:"suppressed"`
+}
+
+func newRateLimiter(stateFile string, limits map[string]config.RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{
+		stateFile: stateFile,
+		limits:    limits,
+		state:     make(map[string]*limitState),
+		now:       time.Now,
+	}
+
+	if data, err := ioutil.ReadFile(stateFile); err == nil {
+		if err := json.Unmarshal(data, &l.state); err != nil {
+			log.Error.Printf("Ignored invalid alert rate limiting state %s: %v\n", stateFile, err)
+			l.state = make(map[string]*limitState)
+		}
+	}
+
+	return l
+}
+
+// allow records an alert to the notifier and returns if it can be sent,
+// with the number of alerts suppressed before it.
+func (l *rateLimiter) allow(name string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count, period := l.limit(name)
+	now := l.now()
+
+	s, ok := l.state[name]
+	if !ok {
+		s = &limitState{}
+		l.state[name] = s
+	}
+
+	sent := []int64{}
+	for _, t := range s.Sent {
+		if now.Sub(time.Unix(t, 0)) < period {
+			sent = append(sent, t)
+		}
+	}
+	s.Sent = sent
+
+	allowed := len(s.Sent) < count
+	suppressed := 0
+	if allowed {
+		s.Sent = append(s.Sent, now.Unix())
+		suppressed = s.Suppressed
+		s.Suppressed = 0
+	} else {
+		s.Suppressed++
+	}
+
+	l.save()
+	return allowed, suppressed
+}
+
+func (l *rateLimiter) limit(name string) (int, time.Duration) {
+	r := l.limits[name]
+
+	count := r.Count
+	if count == 0 {
+		count = defaultRateCount
+	}
+
+	period := time.Duration(r.Period) * time.Second
+	if period == 0 {
+		period = defaultRatePeriod
+	}
+
+	return count, period
+}
+
+func (l *rateLimiter) save() {
+	data, _ := json.Marshal(l.state)
+
+	tmp := l.stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+		log.Error.Printf("Failed to save alert rate limiting state: %v\n", err)
+		return
+	}
+
+	if err := os.Rename(tmp, l.stateFile); err != nil {
+		log.Error.Printf("Failed to save alert rate limiting state: %v\n", err)
+	}
+}
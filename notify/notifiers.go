@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"squirrel/config"
+	"squirrel/log"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/dm"
+)
+
+// stdoutNotifier prints alerts to the normal log.
+type stdoutNotifier struct{}
+
+func (stdoutNotifier) Name() string {
+	return "stdout"
+}
+
+func (stdoutNotifier) Notify(subject string, content string) error {
+	log.Printf("[ALERT] %s\n%s\n", subject, content)
+	return nil
+}
+
+// smtpNotifier sends alerts as plain text mails,
+// STARTTLS is used if the server supports it.
+type smtpNotifier struct {
+	cfg  config.SMTPConfig
+	addr string
+	auth smtp.Auth
+}
+
+func newSMTPNotifier(cfg config.SMTPConfig) *smtpNotifier {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	n := &smtpNotifier{
+		cfg:  cfg,
+		addr: fmt.Sprintf("%s:%d", cfg.Host, port),
+	}
+	if cfg.Username != "" {
+		n.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return n
+}
+
+func (n *smtpNotifier) Name() string {
+	return "smtp"
+}
+
+func (n *smtpNotifier) Notify(subject string, content string) error {
+	header := []string{
+		"From: " + n.cfg.From,
+		"To: " + strings.Join(n.cfg.To, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	msg := strings.Join(header, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(content, "\n", "\r\n")
+
+	return smtp.SendMail(n.addr, n.auth, n.cfg.From, n.cfg.To, []byte(msg))
+}
+
+// webhookNotifier posts alerts as json.
+type webhookNotifier struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+// webhookPayload is the request body of webhook alerts.
+type webhookPayload struct {
+	Label   string `json:"label"`
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+	Time    int64  `json:"time"`
+}
+
+func newWebhookNotifier(cfg config.WebhookConfig) *webhookNotifier {
+	return &webhookNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *webhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *webhookNotifier) Notify(subject string, content string) error {
+	body, err := json.Marshal(webhookPayload{
+		Label:   config.GetLabel(),
+		Subject: subject,
+		Content: content,
+		Time:    time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// aliyunNotifier sends alerts through aliyun DirectMail.
+type aliyunNotifier struct {
+	client *dm.Client
+}
+
+func newAliyunNotifier() (*aliyunNotifier, error) {
+	if err := config.LoadAliyunMailConfig(); err != nil {
+		return nil, err
+	}
+
+	mailCfg := config.GetAliyunMailConfig()
+	client, err := dm.NewClientWithAccessKey(
+		mailCfg.Region,
+		mailCfg.AccessKeyID,
+		mailCfg.AccessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aliyunNotifier{client: client}, nil
+}
+
+func (n *aliyunNotifier) Name() string {
+	return "aliyun_mail"
+}
+
+func (n *aliyunNotifier) Notify(subject string, content string) error {
+	mailCfg := config.GetAliyunMailConfig()
+
+	req := dm.CreateSingleSendMailRequest()
+	req.AccountName = mailCfg.AccountName
+	req.ReplyToAddress = requests.NewBoolean(false)
+	req.AddressType = requests.NewInteger(1)
+	if config.GetLabel() != "" {
+		req.FromAlias = fmt.Sprintf("[%s]-sq", config.GetLabel())
+	} else {
+		req.FromAlias = "squirrel"
+	}
+	req.Subject = subject
+	req.TextBody = content
+	req.ToAddress = strings.Join(mailCfg.Receiver, ",")
+
+	_, err := n.client.SingleSendMail(req)
+	return err
+}
@@ -0,0 +1,129 @@
+// Package notify sends alerts through all enabled notifiers.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"squirrel/config"
+	"squirrel/log"
+
+	eParser "github.com/go-errors/errors"
+)
+
+// Notifier delivers alerts to one destination.
+type Notifier interface {
+	// Name identifies the notifier in logs and rate limiting state.
+	Name() string
+	Notify(subject string, content string) error
+}
+
+var (
+	notifiers []Notifier
+	limiter   *rateLimiter
+)
+
+// Init enables notifiers from config,
+// enableMail additionally enables the aliyun mail notifier.
+func Init(enableMail bool) {
+	cfg := config.GetNotifyConfig()
+	notifiers = nil
+	limits := map[string]config.RateLimitConfig{}
+
+	if cfg.Stdout.Enabled {
+		notifiers = append(notifiers, stdoutNotifier{})
+		limits["stdout"] = cfg.Stdout.RateLimit
+	}
+
+	if cfg.SMTP.Enabled {
+		notifiers = append(notifiers, newSMTPNotifier(cfg.SMTP))
+		limits["smtp"] = cfg.SMTP.RateLimit
+	}
+
+	if cfg.Webhook.Enabled {
+		notifiers = append(notifiers, newWebhookNotifier(cfg.Webhook))
+		limits["webhook"] = cfg.Webhook.RateLimit
+	}
+
+	if enableMail {
+		n, err := newAliyunNotifier()
+		if err != nil {
+			panic(err)
+		}
+		notifiers = append(notifiers, n)
+		limits["aliyun_mail"] = config.GetAliyunMailConfig().RateLimit
+	}
+
+	stateFile := cfg.StateFile
+	if stateFile == "" {
+		stateFile = "notify_state.json"
+	}
+	limiter = newRateLimiter(stateFile, limits)
+
+	for _, n := range notifiers {
+		log.Printf("Alert notifier enabled: %s\n", n.Name())
+	}
+}
+
+// Enabled returns true if any notifier is enabled.
+func Enabled() bool {
+	return len(notifiers) > 0
+}
+
+// AlertIfErr captures paniced error and sends it to all notifiers.
+// Panics are only recovered when any notifier is enabled.
+func AlertIfErr() {
+	var err error
+	if !Enabled() {
+		return
+	}
+
+	if r := recover(); r != nil {
+		switch t := r.(type) {
+		case string:
+			err = errors.New(t)
+		case error:
+			err = t
+		default:
+			err = fmt.Errorf("unknown error: %v", t)
+		}
+
+		err = errors.New(eParser.Wrap(err, 0).ErrorStack())
+		log.Error.Println(err)
+		Send("Error Detected", err.Error())
+	}
+}
+
+// Send sends the alert to all notifiers which are not rate limited.
+func Send(subject string, content string) {
+	if !Enabled() {
+		return
+	}
+
+	if content == "" {
+		log.Printf("Alert content cannot be empty\n")
+		debug.PrintStack()
+		return
+	}
+
+	if label := config.GetLabel(); label != "" {
+		subject = fmt.Sprintf("[%s] %s", label, subject)
+	}
+
+	for _, n := range notifiers {
+		allowed, suppressed := limiter.allow(n.Name())
+		if !allowed {
+			log.Printf("Alert '%s' to %s suppressed by rate limit\n", subject, n.Name())
+			continue
+		}
+
+		body := content
+		if suppressed > 0 {
+			body = fmt.Sprintf("%s\n\n(%d earlier alerts were suppressed by rate limit)", content, suppressed)
+		}
+
+		if err := n.Notify(subject, body); err != nil {
+			log.Error.Printf("Failed to send alert to %s: %v\n", n.Name(), err)
+		}
+	}
+}
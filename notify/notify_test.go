@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"squirrel/config"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	limits := map[string]config.RateLimitConfig{
+		"webhook": {Count: 2, Period: 60},
+	}
+
+	now := time.Unix(1600000000, 0)
+	l := newRateLimiter(stateFile, limits)
+	l.now = func() time.Time { return now }
+
+	for i, expected := range []bool{true, true, false, false} {
+		if allowed, _ := l.allow("webhook"); allowed != expected {
+			t.Fatalf("Alert %d: expected allowed=%v", i, expected)
+		}
+	}
+
+	// State survives restarts.
+	l = newRateLimiter(stateFile, limits)
+	l.now = func() time.Time { return now.Add(30 * time.Second) }
+	if allowed, _ := l.allow("webhook"); allowed {
+		t.Fatal("Expected alert to be limited after restart")
+	}
+
+	l.now = func() time.Time { return now.Add(61 * time.Second) }
+	allowed, suppressed := l.allow("webhook")
+	if !allowed || suppressed != 3 {
+		t.Errorf("Expected alert allowed with 3 suppressed, got allowed=%v suppressed=%d", allowed, suppressed)
+	}
+
+	// Other notifiers use the default limit.
+	if allowed, _ := l.allow("stdout"); !allowed {
+		t.Error("Expected stdout alert to be allowed")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload webhookPayload
+	var auth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	n := newWebhookNotifier(config.WebhookConfig{
+		URL:     srv.URL,
+		Headers: map[string]string{"authorization": "Bearer token"},
+	})
+
+	if err := n.Notify("Error Detected", "panic"); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Subject != "Error Detected" || payload.Content != "panic" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("Expected authorization header, got '%s'", auth)
+	}
+
+	n.cfg.URL = srv.URL + "/missing"
+	srv.Config.Handler = http.NotFoundHandler()
+	if err := n.Notify("Error Detected", "panic"); err == nil {
+		t.Error("Expected error on non-2xx response")
+	}
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"squirrel/log"
+)
+
+// errMissingResponse is returned for batch items the server did not respond to.
+var errMissingResponse = errors.New("no response for the request in batch")
+
+// batchCall sends one json-rpc 2.0 batch request of method with each element of params,
+// and unmarshals result of params[i] into targets[i].
+// Ids of requests are their indexes, responses are matched by id since they can be in any order.
+// The returned errors hold per-item errors, all items fail with the same error
+// if the request is cancelled or the server rejects the whole batch.
+// Per-item errors are classified the same as errors of call,
+// results which cannot be decoded are *TransportError.
+func batchCall(ctx context.Context, minHeight int, method string, params [][]interface{}, targets []interface{}) []error {
+	errs := make([]error, len(params))
+	if len(params) == 0 {
+		return errs
+	}
+
+	requests := make([]request, len(params))
+	for i, p := range params {
+		requests[i] = newRequest(i, method, p)
+	}
+	requestBody, err := json.Marshal(requests)
+	if err != nil {
+		return fillErrors(errs, err)
+	}
+
+	url, bodyBytes, err := post(ctx, minHeight, method, true, requestBody)
+	if err != nil {
+		return fillErrors(errs, err)
+	}
+
+	responses := []response{}
+	if err := json.Unmarshal(bodyBytes, &responses); err != nil {
+		// Servers without batch support respond a single error object.
+		single := response{}
+		if json.Unmarshal(bodyBytes, &single) == nil && single.Error != nil {
+			return fillErrors(errs, single.Error)
+		}
+
+		serverUnavailable(url)
+
+		log.With(log.Fields{"rpc": url, "method": method}).Errorf("Invalid batch response: %v\n", err)
+		return fillErrors(errs, &TransportError{URL: url, Err: err})
+	}
+
+	fillErrors(errs, &TransportError{URL: url, Err: errMissingResponse})
+	invalid := false
+
+	for _, resp := range responses {
+		if resp.ID == nil || *resp.ID < 0 || *resp.ID >= len(targets) {
+			continue
+		}
+
+		record(method, params[*resp.ID], &resp)
+		err := resp.decode(method, targets[*resp.ID])
+
+		var rpcErr *Error
+		if err != nil && !errors.As(err, &rpcErr) && !errors.Is(err, ErrNotFound) {
+			log.With(log.Fields{"rpc": url, "method": method, "params": params[*resp.ID]}).Errorf("Invalid batch response item: %v\n", err)
+			err = &TransportError{URL: url, Err: err}
+			invalid = true
+		}
+		errs[*resp.ID] = err
+	}
+
+	if invalid {
+		serverUnavailable(url)
+	}
+
+	return errs
+}
+
+func fillErrors(errs []error, err error) []error {
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
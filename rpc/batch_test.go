@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"squirrel/log"
+	"testing"
+)
+
+type testRequest struct {
+	ID     int           `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// newTestServer serves getblock in reversed order for batches,
+// block 2 only succeeds when it is not batched and block 4 is invalid.
+func newTestServer(t *testing.T) *httptest.Server {
+	respond := func(req testRequest, batched bool) map[string]interface{} {
+		index := int(req.Params[0].(float64))
+		if batched && index == 2 {
+			return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -100, "message": "Unknown block"}}
+		}
+		if index == 4 {
+			return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": map[string]interface{}{"index": "four"}}
+		}
+		return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": map[string]interface{}{"index": index}}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			req := testRequest{}
+			json.Unmarshal(body, &req)
+			json.NewEncoder(w).Encode(respond(req, false))
+			return
+		}
+
+		batch := []testRequest{}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("Invalid batch request: %v", err)
+			return
+		}
+
+		resps := []interface{}{}
+		for i := len(batch) - 1; i >= 0; i-- {
+			resps = append(resps, respond(batch[i], true))
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+
+	setTestServer(srv.URL, 100)
+
+	return srv
+}
+
+// setTestServer makes url the only rpc server.
+func setTestServer(url string, height int) {
+	sLock.Lock()
+	servers = map[string]*server{url: {url: url, weight: 1, height: height}}
+	sLock.Unlock()
+}
+
+func TestBatchCall(t *testing.T) {
+	log.Init()
+	defer os.Remove("error.log")
+
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	blocks := make([]*RawBlock, 4)
+	params := [][]interface{}{{1, 1}, {2, 1}, {3, 1}, {4, 1}}
+	targets := []interface{}{&blocks[0], &blocks[1], &blocks[2], &blocks[3]}
+
+	errs := batchCall(context.Background(), 4, "getblock", params, targets)
+
+	if errs[0] != nil || blocks[0] == nil || blocks[0].Index != 1 {
+		t.Errorf("Expected block 1, got %v %v", blocks[0], errs[0])
+	}
+	if e, ok := errs[1].(*Error); !ok || e.Code != -100 || blocks[1] != nil {
+		t.Errorf("Expected rpc error of block 2, got %v", errs[1])
+	}
+	if errs[2] != nil || blocks[2] == nil || blocks[2].Index != 3 {
+		t.Errorf("Expected block 3, got %v %v", blocks[2], errs[2])
+	}
+	var transportErr *TransportError
+	if !errors.As(errs[3], &transportErr) {
+		t.Errorf("Expected transport error of invalid block 4, got %v", errs[3])
+	}
+}
+
+func TestDownloadBlocks(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	blocks, err := DownloadBlocks(context.Background(), []int{1, 2, 3})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for i, b := range blocks {
+		if b == nil || int(b.Index) != i+1 {
+			t.Errorf("Expected block %d, got %v", i+1, b)
+		}
+	}
+}
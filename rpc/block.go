@@ -1,16 +1,9 @@
 package rpc
 
-// BlockCountRespponse returns block height of chain.
-type BlockCountRespponse struct {
-	jsonRPCResponse
-	Result int `json:"result"`
-}
-
-// BlockResponse returns full block data of a specific index.
-type BlockResponse struct {
-	jsonRPCResponse
-	Result *RawBlock `json:"result"`
-}
+import (
+	"context"
+	"errors"
+)
 
 // RawBlock is the raw block structure used in rpc response.
 type RawBlock struct {
@@ -32,12 +25,50 @@ type RawBlock struct {
 }
 
 // DownloadBlock from rpc server.
-func DownloadBlock(index int) *RawBlock {
+// The returned error matches ErrNotFound if the block is beyond the highest block index.
+func DownloadBlock(ctx context.Context, index int) (*RawBlock, error) {
 	params := []interface{}{index, 1}
-	args := getRPCRequestBody("getblock", params)
 
-	respData := BlockResponse{}
-	rpcCall(index, args, &respData)
+	var block *RawBlock
+	if err := call(ctx, index, "getblock", params, &block); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// DownloadBlocks fetches blocks of all indexes in one batch request,
+// blocks failed in the batch are downloaded one by one.
+// Blocks which can not be downloaded are nil, with the first error other than ErrNotFound returned.
+func DownloadBlocks(ctx context.Context, indexes []int) ([]*RawBlock, error) {
+	minHeight := 0
+	params := make([][]interface{}, len(indexes))
+	targets := make([]interface{}, len(indexes))
+	blocks := make([]*RawBlock, len(indexes))
+
+	for i, index := range indexes {
+		if index > minHeight {
+			minHeight = index
+		}
+		params[i] = []interface{}{index, 1}
+		targets[i] = &blocks[i]
+	}
+
+	var firstErr error
+	errs := batchCall(ctx, minHeight, "getblock", params, targets)
+	for i, err := range errs {
+		if err == nil && blocks[i] != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return blocks, &TransportError{Err: ctx.Err()}
+		}
+
+		blocks[i], err = DownloadBlock(ctx, indexes[i])
+		if err != nil && !errors.Is(err, ErrNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
 
-	return respData.Result
+	return blocks, firstErr
 }
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fixtures holds recorded responses of rpc requests,
+// keyed by method and params so they can be replayed in any order.
+type Fixtures struct {
+	mu        sync.Mutex
+	responses map[string]*fixture
+}
+
+// fixture is a recorded response of a request.
+type fixture struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+var (
+	recorder     *Fixtures
+	recorderLock sync.Mutex
+)
+
+// NewFixtures returns empty fixtures.
+func NewFixtures() *Fixtures {
+	return &Fixtures{responses: make(map[string]*fixture)}
+}
+
+// LoadFixtures reads fixtures saved by Save.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFixtures()
+	if err := json.Unmarshal(data, &f.responses); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Save writes fixtures to path as a json object of 'method params' keys and responses.
+func (f *Fixtures) Save(path string) error {
+	f.mu.Lock()
+	data, err := json.MarshalIndent(f.responses, "", "  ")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Len returns the number of recorded responses.
+func (f *Fixtures) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.responses)
+}
+
+func (f *Fixtures) add(method string, params json.RawMessage, resp *response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[fixtureKey(method, params)] = &fixture{
+		Result: resp.Result,
+		Error:  resp.Error,
+	}
+}
+
+func (f *Fixtures) lookup(method string, params json.RawMessage) (*fixture, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r, ok := f.responses[fixtureKey(method, params)]
+	return r, ok
+}
+
+// keys returns recorded keys of method in order.
+func (f *Fixtures) keys(method string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := []string{}
+	for key := range f.responses {
+		if strings.HasPrefix(key, method+" ") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func fixtureKey(method string, params json.RawMessage) string {
+	buf := bytes.Buffer{}
+	if len(params) == 0 || json.Compact(&buf, params) != nil || buf.String() == "null" {
+		return method + " []"
+	}
+
+	return method + " " + buf.String()
+}
+
+// Record starts recording responses of all rpc requests into f,
+// recording stops if f is nil.
+func Record(f *Fixtures) {
+	recorderLock.Lock()
+	defer recorderLock.Unlock()
+
+	recorder = f
+}
+
+// record adds the response of a request to the recording fixtures if any.
+func record(method string, params []interface{}, resp *response) {
+	recorderLock.Lock()
+	f := recorder
+	recorderLock.Unlock()
+
+	if f == nil {
+		return
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+
+	f.add(method, data, resp)
+}
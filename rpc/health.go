@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"time"
+)
+
+const (
+	// latencyBase is the latency which halves the score of a server.
+	latencyBase = 100 * time.Millisecond
+	// ewmaAlpha is the weight of the latest request in latency and error rate.
+	ewmaAlpha = 0.2
+	// minScore keeps unhealthy servers receiving a little traffic, so their scores can recover.
+	minScore = 0.01
+
+	// breakerThreshold is the number of consecutive failures which opens the circuit of a server.
+	breakerThreshold = 3
+	minBackoff       = time.Second
+	maxBackoff       = time.Minute
+)
+
+// Circuit states of rpc servers.
+const (
+	// StateClosed servers receive requests normally.
+	StateClosed = "closed"
+	// StateOpen servers receive no requests until their back-off expires.
+	StateOpen = "open"
+	// StateHalfOpen servers receive one probe request,
+	// which closes the circuit if it succeeds or doubles the back-off if it fails.
+	StateHalfOpen = "half_open"
+)
+
+// server tracks height and health of a rpc server.
+type server struct {
+	url      string
+	priority int
+	weight   int
+	height   int
+
+	// latency is the moving average of successful requests.
+	latency time.Duration
+	// errorRate is the moving average of failed requests, between 0 and 1.
+	errorRate float64
+	requests  uint64
+	failures  uint64
+
+	consecutive int
+	backoff     time.Duration
+	openUntil   time.Time
+	probing     bool
+}
+
+func (s *server) state(now time.Time) string {
+	switch {
+	case s.consecutive < breakerThreshold:
+		return StateClosed
+	case now.Before(s.openUntil):
+		return StateOpen
+	default:
+		return StateHalfOpen
+	}
+}
+
+// available returns true if the server can receive a request now.
+func (s *server) available(now time.Time) bool {
+	switch s.state(now) {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		return !s.probing
+	default:
+		return false
+	}
+}
+
+// score rates the server by its error rate and latency, 1 is the best.
+func (s *server) score() float64 {
+	score := (1 - s.errorRate) * float64(latencyBase) / float64(latencyBase+s.latency)
+	if score < minScore {
+		return minScore
+	}
+	return score
+}
+
+func (s *server) success(latency time.Duration) {
+	s.requests++
+	if s.latency == 0 {
+		s.latency = latency
+	} else {
+		s.latency = time.Duration(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(s.latency))
+	}
+	s.errorRate *= 1 - ewmaAlpha
+
+	s.consecutive = 0
+	s.backoff = 0
+	s.probing = false
+}
+
+func (s *server) failure(now time.Time) {
+	s.requests++
+	s.failures++
+	s.errorRate = ewmaAlpha + (1-ewmaAlpha)*s.errorRate
+
+	s.consecutive++
+	s.probing = false
+	if s.consecutive < breakerThreshold {
+		return
+	}
+
+	if s.backoff == 0 {
+		s.backoff = minBackoff
+	} else if s.backoff *= 2; s.backoff > maxBackoff {
+		s.backoff = maxBackoff
+	}
+	s.openUntil = now.Add(s.backoff)
+}
+
+// pick selects one of candidates with the lowest priority,
+// randomly in proportion to weight times score, r is a random number in [0, 1).
+// Servers of weight 0 are picked evenly, only if all candidates of the priority have weight 0.
+func pick(candidates []*server, r float64) *server {
+	best := []*server{}
+	for _, s := range candidates {
+		if len(best) > 0 && s.priority > best[0].priority {
+			continue
+		}
+		if len(best) > 0 && s.priority < best[0].priority {
+			best = best[:0]
+		}
+		best = append(best, s)
+	}
+
+	if len(best) == 0 {
+		return nil
+	}
+
+	total := 0.0
+	for _, s := range best {
+		total += float64(s.weight) * s.score()
+	}
+
+	if total == 0 {
+		return best[int(r*float64(len(best)))]
+	}
+
+	r *= total
+	for _, s := range best {
+		r -= float64(s.weight) * s.score()
+		if r < 0 {
+			return s
+		}
+	}
+
+	// Rounding errors.
+	return best[len(best)-1]
+}
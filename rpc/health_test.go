@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPick(t *testing.T) {
+	primary := &server{url: "primary", weight: 3}
+	secondary := &server{url: "secondary", weight: 1}
+	fallback := &server{url: "fallback", priority: 1, weight: 100}
+	candidates := []*server{fallback, primary, secondary}
+
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		counts[pick(candidates, float64(i)/100).url]++
+	}
+
+	if counts["primary"] != 75 || counts["secondary"] != 25 || counts["fallback"] != 0 {
+		t.Errorf("Expected weighted selection of priority 0 servers, got %v", counts)
+	}
+
+	if s := pick([]*server{fallback}, 0.5); s != fallback {
+		t.Errorf("Expected fallback server, got %v", s)
+	}
+
+	if s := pick(nil, 0.5); s != nil {
+		t.Errorf("Expected no server, got %v", s)
+	}
+
+	// Servers of weight 0 are standbys.
+	standby := &server{url: "standby"}
+	for i := 0; i < 100; i++ {
+		if s := pick([]*server{standby, secondary}, float64(i)/100); s != secondary {
+			t.Fatalf("Expected standby server to be skipped, got %v", s)
+		}
+	}
+	if s := pick([]*server{standby, fallback}, 0.5); s != standby {
+		t.Errorf("Expected standby server of lower priority, got %v", s)
+	}
+
+	// Failures lower the share of a server.
+	primary.failure(time.Now())
+	primary.failure(time.Now())
+	counts = map[string]int{}
+	for i := 0; i < 100; i++ {
+		counts[pick(candidates, float64(i)/100).url]++
+	}
+
+	if counts["primary"] >= 75 {
+		t.Errorf("Expected failures to lower the share of primary server, got %v", counts)
+	}
+}
+
+func TestScore(t *testing.T) {
+	fast := &server{}
+	slow := &server{}
+
+	fast.success(10 * time.Millisecond)
+	slow.success(time.Second)
+
+	if fast.score() <= slow.score() {
+		t.Errorf("Expected fast server to score higher, got %f and %f", fast.score(), slow.score())
+	}
+
+	for i := 0; i < 100; i++ {
+		slow.failure(time.Now())
+	}
+	if slow.score() != minScore {
+		t.Errorf("Expected minimum score, got %f", slow.score())
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	s := &server{}
+
+	for i := 0; i < breakerThreshold-1; i++ {
+		s.failure(now)
+	}
+	if s.state(now) != StateClosed {
+		t.Errorf("Expected closed circuit below threshold, got %s", s.state(now))
+	}
+
+	s.failure(now)
+	if s.state(now) != StateOpen || s.available(now) {
+		t.Errorf("Expected open circuit, got %s", s.state(now))
+	}
+
+	now = now.Add(minBackoff)
+	if s.state(now) != StateHalfOpen || !s.available(now) {
+		t.Errorf("Expected half open circuit after back-off, got %s", s.state(now))
+	}
+
+	s.probing = true
+	if s.available(now) {
+		t.Error("Expected one probe at a time")
+	}
+
+	// A failed probe doubles the back-off.
+	s.failure(now)
+	if s.state(now.Add(minBackoff)) != StateOpen || s.state(now.Add(2*minBackoff)) != StateHalfOpen {
+		t.Errorf("Expected back-off of %v, got %v", 2*minBackoff, s.backoff)
+	}
+
+	for i := 0; i < 20; i++ {
+		s.failure(now)
+	}
+	if s.backoff != maxBackoff {
+		t.Errorf("Expected back-off of %v, got %v", maxBackoff, s.backoff)
+	}
+
+	s.success(time.Millisecond)
+	if s.state(now) != StateClosed || s.backoff != 0 {
+		t.Errorf("Expected closed circuit after success, got %s", s.state(now))
+	}
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Error codes of json-rpc 2.0 and neo nodes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeUnknown is returned by neo nodes for unknown blocks, transactions, assets and contracts.
+	CodeUnknown = -100
+)
+
+// ErrNotFound is matched by errors.Is if the requested item does not exist,
+// or no server has reached the requested block yet.
+var ErrNotFound = errors.New("rpc: not found")
+
+// ErrNoServer is wrapped in a *TransportError if no server was available within the retries.
+var ErrNoServer = errors.New("rpc: no server available")
+
+// Error is the error object of a json-rpc response,
+// it is returned when a node failed to serve the request.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for unknown items.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.Code == CodeUnknown
+}
+
+// TransportError is returned if no valid json-rpc response was received,
+// including when the request was cancelled.
+type TransportError struct {
+	// URL is the server which sent an invalid response, empty if none responded.
+	URL string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("rpc transport error: %v", e.Err)
+	}
+	return fmt.Sprintf("rpc transport error from %s: %v", e.URL, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// request is a json-rpc 2.0 request.
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      int           `json:"id"`
+}
+
+func newRequest(id int, method string, params []interface{}) request {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	return request{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      id,
+	}
+}
+
+// response is a json-rpc 2.0 response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// decode unmarshals the result into target, or returns the error of the response.
+// A null result is reported as ErrNotFound.
+func (r *response) decode(method string, target interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+
+	if len(r.Result) == 0 || string(r.Result) == "null" {
+		return fmt.Errorf("%w: empty result of %s", ErrNotFound, method)
+	}
+
+	return json.Unmarshal(r.Result, target)
+}
@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"squirrel/log"
+	"testing"
+	"time"
+)
+
+func TestNewRequest(t *testing.T) {
+	body, err := json.Marshal(newRequest(7, "invokescript", []interface{}{`a"b\c`, true, []int{1, 2}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"jsonrpc":"2.0","method":"invokescript","params":["a\"b\\c",true,[1,2]],"id":7}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+
+	body, _ = json.Marshal(newRequest(1, "getblockcount", nil))
+	expected = `{"jsonrpc":"2.0","method":"getblockcount","params":[],"id":1}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	log.Init()
+	defer os.Remove("error.log")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := testRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Params[0] {
+		case "unknown":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-100,"message":"Unknown transaction"}}`))
+		case "failed":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error"}}`))
+		case "null":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+		case "invalid":
+			w.Write([]byte(`<html></html>`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"txid":"ok"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	setTestServer(srv.URL, 100)
+
+	var result *RawApplicationLogResult
+	ctx := context.Background()
+
+	if err := call(ctx, 1, "getapplicationlog", []interface{}{"ok"}, &result); err != nil || result.TxID != "ok" {
+		t.Errorf("Expected result, got %v %v", result, err)
+	}
+
+	for _, param := range []string{"unknown", "null"} {
+		err := call(ctx, 1, "getapplicationlog", []interface{}{param}, &result)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected not found error of %s, got %v", param, err)
+		}
+	}
+
+	err := call(ctx, 1, "getapplicationlog", []interface{}{"failed"}, &result)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInternalError || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected node error, got %v", err)
+	}
+
+	err = call(ctx, 1, "getapplicationlog", []interface{}{"invalid"}, &result)
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || transportErr.URL != srv.URL {
+		t.Errorf("Expected transport error, got %v", err)
+	}
+
+	err = call(ctx, 101, "getblock", []interface{}{101, 1}, &result)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error beyond best height, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = call(cancelled, 1, "getapplicationlog", []interface{}{"ok"}, &result)
+	if !errors.As(err, &transportErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled transport error, got %v", err)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	log.Init()
+	defer os.Remove("error.log")
+
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	setTestServer(srv.URL, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var result *RawApplicationLogResult
+	err := call(ctx, 1, "getapplicationlog", []interface{}{"ok"}, &result)
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected transport error of deadline, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected call to return at deadline, took %v", time.Since(start))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{0, minRetryDelay, 2 * minRetryDelay, 4 * minRetryDelay}
+	for retry, d := range expected {
+		if backoff(retry) != d {
+			t.Errorf("Expected back-off %v of retry %d, got %v", d, retry, backoff(retry))
+		}
+	}
+
+	if backoff(100) != maxRetryDelay {
+		t.Errorf("Expected maximum back-off, got %v", backoff(100))
+	}
+}
+
+func TestCheckMagic(t *testing.T) {
+	log.Init()
+	defer os.Remove("error.log")
+
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"port":10333,"nonce":1,"useragent":"/NEO:2.12.2/"}}`))
+	}))
+	defer legacy.Close()
+
+	n3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"useragent":"/Neo:3.5.0/","protocol":{"network":860833102}}}`))
+	}))
+	defer n3.Close()
+
+	ctx := context.Background()
+	if err := checkMagic(ctx, []string{legacy.URL, n3.URL}, 860833102); err != nil {
+		t.Errorf("Expected matched magic, got %v", err)
+	}
+	if err := checkMagic(ctx, []string{legacy.URL}, 7630401); err != nil {
+		t.Errorf("Expected unreported magic to be skipped, got %v", err)
+	}
+	if err := checkMagic(ctx, []string{n3.URL}, 894710606); err == nil {
+		t.Error("Expected error for mismatched magic")
+	}
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "squirrel_rpc_request_duration_seconds",
+		Help:    "Duration of successful rpc requests by method, batch requests are labelled as '{method} batch'.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"method"})
+
+	serverErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "squirrel_rpc_server_errors_total",
+		Help: "Number of failed requests by rpc server.",
+	}, []string{"url"})
+
+	serverHeight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "squirrel_rpc_server_height",
+		Help: "Last known height of each rpc server, -1 if it is unavailable.",
+	}, []string{"url"})
+
+	serverScore = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "squirrel_rpc_server_score",
+		Help: "Health score of each rpc server by latency and error rate, 1 is the best.",
+	}, []string{"url"})
+
+	serverCircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "squirrel_rpc_server_circuit_open",
+		Help: "1 if requests to the rpc server are paused after consecutive failures.",
+	}, []string{"url"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "squirrel_rpc_best_height",
+		Help: "Highest height of all rpc servers.",
+	}, func() float64 {
+		return float64(BestHeight.Get())
+	})
+)
@@ -0,0 +1,245 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"squirrel/util"
+)
+
+// RawN3Block is the raw block structure of Neo N3 used in rpc response.
+type RawN3Block struct {
+	Hash              string `json:"hash"`
+	Size              int    `json:"size"`
+	Version           uint   `json:"version"`
+	PreviousBlockHash string `json:"previousblockhash"`
+	MerkleRoot        string `json:"merkleroot"`
+	// Time is in milliseconds.
+	Time          uint64         `json:"time"`
+	Nonce         string         `json:"nonce"`
+	Index         uint           `json:"index"`
+	Primary       int            `json:"primary"`
+	NextConsensus string         `json:"nextconsensus"`
+	Witnesses     []RawN3Witness `json:"witnesses"`
+	Tx            []RawN3Tx      `json:"tx"`
+	NextBlockHash string         `json:"nextblockhash"`
+}
+
+// RawN3Witness is the invocation and verification script pair of blocks and transactions.
+type RawN3Witness struct {
+	Invocation   string `json:"invocation"`
+	Verification string `json:"verification"`
+}
+
+// RawN3Signer is the signer of N3 transactions.
+type RawN3Signer struct {
+	Account          string   `json:"account"`
+	Scopes           string   `json:"scopes"`
+	AllowedContracts []string `json:"allowedcontracts"`
+	AllowedGroups    []string `json:"allowedgroups"`
+}
+
+// RawN3Tx is the transaction part of N3 block data.
+// Fees are integers in the smallest unit of GAS.
+type RawN3Tx struct {
+	Hash            string         `json:"hash"`
+	Size            uint           `json:"size"`
+	Version         uint           `json:"version"`
+	Nonce           uint32         `json:"nonce"`
+	Sender          string         `json:"sender"`
+	SysFee          string         `json:"sysfee"`
+	NetFee          string         `json:"netfee"`
+	ValidUntilBlock uint           `json:"validuntilblock"`
+	Signers         []RawN3Signer  `json:"signers"`
+	Script          string         `json:"script"`
+	Witnesses       []RawN3Witness `json:"witnesses"`
+}
+
+// RawN3ApplicationLog is the result of N3 'getapplicationlog' rpc call,
+// either of a transaction or of a block.
+type RawN3ApplicationLog struct {
+	TxID       string           `json:"txid"`
+	BlockHash  string           `json:"blockhash"`
+	Executions []RawN3Execution `json:"executions"`
+}
+
+// RawN3Execution is the execution part of N3 application log.
+// Trigger is 'Application' for transactions, 'OnPersist' or 'PostPersist' for blocks.
+type RawN3Execution struct {
+	Trigger       string              `json:"trigger"`
+	VMState       string              `json:"vmstate"`
+	Exception     string              `json:"exception"`
+	GasConsumed   string              `json:"gasconsumed"`
+	Stack         []RawN3StackItem    `json:"stack"`
+	Notifications []RawN3Notification `json:"notifications"`
+}
+
+// RawN3Notification is an event emitted by a contract.
+type RawN3Notification struct {
+	Contract  string         `json:"contract"`
+	EventName string         `json:"eventname"`
+	State     RawN3StackItem `json:"state"`
+}
+
+// RawN3StackItem is a NeoVM 3 stack item, Value depends on Type.
+type RawN3StackItem struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// RawN3ContractState is the result of 'getcontractstate' rpc call.
+type RawN3ContractState struct {
+	ID       int    `json:"id"`
+	Hash     string `json:"hash"`
+	Manifest struct {
+		Name               string   `json:"name"`
+		SupportedStandards []string `json:"supportedstandards"`
+	} `json:"manifest"`
+}
+
+// Array returns items of an 'Array' or 'Struct' item, or nil for other types.
+func (item *RawN3StackItem) Array() []RawN3StackItem {
+	if item.Type != "Array" && item.Type != "Struct" {
+		return nil
+	}
+
+	items := []RawN3StackItem{}
+	if json.Unmarshal(item.Value, &items) != nil {
+		return nil
+	}
+
+	return items
+}
+
+// Bytes returns the decoded value of a 'ByteString' or 'Buffer' item.
+func (item *RawN3StackItem) Bytes() ([]byte, bool) {
+	if item.Type != "ByteString" && item.Type != "Buffer" {
+		return nil, false
+	}
+
+	var str string
+	if json.Unmarshal(item.Value, &str) != nil {
+		return nil, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// Integer returns the value of an 'Integer' item,
+// or of a 'ByteString' item holding a little-endian two's complement integer.
+func (item *RawN3StackItem) Integer() (*big.Int, bool) {
+	if data, ok := item.Bytes(); ok {
+		return util.LEBytesToBigInt(data), true
+	}
+
+	if item.Type != "Integer" {
+		return nil, false
+	}
+
+	var str string
+	if json.Unmarshal(item.Value, &str) != nil {
+		return nil, false
+	}
+
+	return new(big.Int).SetString(str, 10)
+}
+
+// IsNull tells if the item is the null value, whose type is 'Any'.
+func (item *RawN3StackItem) IsNull() bool {
+	return item.Type == "Any"
+}
+
+// DownloadN3Block from rpc server.
+// The returned error matches ErrNotFound if the block is beyond the highest block index.
+func DownloadN3Block(ctx context.Context, index int) (*RawN3Block, error) {
+	params := []interface{}{index, true}
+
+	var block *RawN3Block
+	if err := call(ctx, index, "getblock", params, &block); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// DownloadN3Blocks fetches N3 blocks of all indexes in one batch request,
+// same as DownloadBlocks.
+func DownloadN3Blocks(ctx context.Context, indexes []int) ([]*RawN3Block, error) {
+	minHeight := 0
+	params := make([][]interface{}, len(indexes))
+	targets := make([]interface{}, len(indexes))
+	blocks := make([]*RawN3Block, len(indexes))
+
+	for i, index := range indexes {
+		if index > minHeight {
+			minHeight = index
+		}
+		params[i] = []interface{}{index, true}
+		targets[i] = &blocks[i]
+	}
+
+	var firstErr error
+	errs := batchCall(ctx, minHeight, "getblock", params, targets)
+	for i, err := range errs {
+		if err == nil && blocks[i] != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return blocks, &TransportError{Err: ctx.Err()}
+		}
+
+		blocks[i], err = DownloadN3Block(ctx, indexes[i])
+		if err != nil && !errors.Is(err, ErrNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return blocks, firstErr
+}
+
+// GetN3ApplicationLogs returns application logs of all transaction or block hashes in one batch request,
+// blockIndex is the highest block index of these hashes.
+// Logs failed in the batch are requested again one by one, same as GetApplicationLog.
+func GetN3ApplicationLogs(ctx context.Context, blockIndex int, hashes []string) ([]*RawN3ApplicationLog, error) {
+	params := make([][]interface{}, len(hashes))
+	targets := make([]interface{}, len(hashes))
+	logs := make([]*RawN3ApplicationLog, len(hashes))
+
+	for i, hash := range hashes {
+		params[i] = []interface{}{hash}
+		targets[i] = &logs[i]
+	}
+
+	errs := batchCall(ctx, blockIndex, "getapplicationlog", params, targets)
+	for i, err := range errs {
+		if err == nil && logs[i] != nil {
+			continue
+		}
+
+		if err := getApplicationLog(ctx, blockIndex, hashes[i], &logs[i]); err != nil {
+			return logs, err
+		}
+	}
+
+	return logs, nil
+}
+
+// GetN3ContractState returns the current state of a deployed contract.
+// The returned error matches ErrNotFound if the contract does not exist or was destroyed.
+func GetN3ContractState(ctx context.Context, minHeight int, hash string) (*RawN3ContractState, error) {
+	params := []interface{}{hash}
+
+	var state *RawN3ContractState
+	if err := call(ctx, minHeight, "getcontractstate", params, &state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
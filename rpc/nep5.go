@@ -1,19 +1,14 @@
 package rpc
 
 import (
+	"context"
+	"errors"
 	"math/big"
-	"math/rand"
+	"squirrel/config"
 	"squirrel/log"
-	"time"
 )
 
-// ApplicationLogResponse is the struct of returning data from 'getapplicationlog' rpc call.
-type ApplicationLogResponse struct {
-	jsonRPCResponse
-	Result *RawApplicationLogResult `json:"result"`
-}
-
-// RawApplicationLogResult is the inner struct of struct 'ApplicationLogResponse'.
+// RawApplicationLogResult is the result of 'getapplicationlog' rpc call.
 type RawApplicationLogResult struct {
 	TxID       string                       `json:"txid"`
 	Executions []RawApplicationLogExecution `json:"executions"`
@@ -36,34 +31,73 @@ type RawNotifications struct {
 }
 
 // GetApplicationLog returns application log of nep5 transaction.
-func GetApplicationLog(blockIndex int, txID string) *RawApplicationLogResult {
-	params := []interface{}{txID}
+// Logs not found or failed on the node are requested again with back-off,
+// as many times as max retries of 'getapplicationlog'.
+func GetApplicationLog(ctx context.Context, blockIndex int, txID string) (*RawApplicationLogResult, error) {
+	var appLog *RawApplicationLogResult
+	if err := getApplicationLog(ctx, blockIndex, txID, &appLog); err != nil {
+		return nil, err
+	}
+
+	return appLog, nil
+}
+
+// getApplicationLog unmarshals the application log of hash into target,
+// logs not found or failed on the node are requested again with back-off,
+// as many times as max retries of 'getapplicationlog'.
+func getApplicationLog(ctx context.Context, blockIndex int, hash string, target interface{}) error {
 	const method = "getapplicationlog"
-	args := getRPCRequestBody(method, params)
+	params := []interface{}{hash}
+	maxRetries := config.GetRPCMethodConfig(method).MaxRetries
+
+	var err error
+	for retry := 0; retry <= maxRetries; retry++ {
+		if !wait(ctx, backoff(retry)) {
+			return &TransportError{Err: ctx.Err()}
+		}
 
-	respData := ApplicationLogResponse{}
-	rpcCall(blockIndex, args, &respData)
+		err = call(ctx, blockIndex, method, params, target)
+		if err == nil {
+			return nil
+		}
 
-	if respData.Result != nil {
-		return respData.Result
+		// Transport errors are already retried.
+		var transportErr *TransportError
+		if errors.As(err, &transportErr) {
+			return err
+		}
+
+		log.With(log.Fields{"height": blockIndex, "txid": hash, "retry": retry}).Warnf("Can not get application log: %v\n", err)
 	}
 
-	retryTime := uint(0)
-	delay := 0
+	return err
+}
 
-	for {
-		retryTime++
-		if delay < 10*1000 {
-			delay = rand.Intn(1<<retryTime) + 1000
-		}
+// GetApplicationLogs returns application logs of all transactions in one batch request,
+// blockIndex is the highest block index of these transactions.
+// Logs failed in the batch are fetched by GetApplicationLog,
+// it returns the first error of them.
+func GetApplicationLogs(ctx context.Context, blockIndex int, txIDs []string) ([]*RawApplicationLogResult, error) {
+	params := make([][]interface{}, len(txIDs))
+	targets := make([]interface{}, len(txIDs))
+	logs := make([]*RawApplicationLogResult, len(txIDs))
 
-		log.Printf("Can not get application log of %s\n", txID)
-		log.Printf("Delay for %d msecs and try to connect again. RetryTime=%d\n", delay, retryTime)
+	for i, txID := range txIDs {
+		params[i] = []interface{}{txID}
+		targets[i] = &logs[i]
+	}
+
+	errs := batchCall(ctx, blockIndex, "getapplicationlog", params, targets)
+	for i, err := range errs {
+		if err == nil && logs[i] != nil {
+			continue
+		}
 
-		time.Sleep(time.Duration(delay) * time.Millisecond)
-		rpcCall(blockIndex, args, &respData)
-		if respData.Result != nil {
-			return respData.Result
+		logs[i], err = GetApplicationLog(ctx, blockIndex, txIDs[i])
+		if err != nil {
+			return logs, err
 		}
 	}
+
+	return logs, nil
 }
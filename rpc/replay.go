@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ReplayHandler is a json-rpc server which serves responses from fixtures,
+// batch requests are supported.
+// Requests which were not recorded get an error of CodeUnknown, and are reported by Missing.
+// 'getblockcount' never exceeds the highest recorded block,
+// so clients do not wait for blocks beyond the recording.
+type ReplayHandler struct {
+	fixtures *Fixtures
+
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+// replayRequest is a json-rpc request with raw params.
+type replayRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	ID     *int            `json:"id"`
+}
+
+// NewReplayHandler returns a handler which replays f.
+func NewReplayHandler(f *Fixtures) *ReplayHandler {
+	return &ReplayHandler{
+		fixtures: f,
+		missing:  make(map[string]bool),
+	}
+}
+
+func (h *ReplayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		req := replayRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			json.NewEncoder(w).Encode(response{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			return
+		}
+
+		json.NewEncoder(w).Encode(h.respond(req))
+		return
+	}
+
+	reqs := []replayRequest{}
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		json.NewEncoder(w).Encode(response{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: err.Error()}})
+		return
+	}
+
+	resps := make([]response, len(reqs))
+	for i, req := range reqs {
+		resps[i] = h.respond(req)
+	}
+	json.NewEncoder(w).Encode(resps)
+}
+
+// Missing returns keys of requests which were not recorded.
+func (h *ReplayHandler) Missing() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := []string{}
+	for key := range h.missing {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (h *ReplayHandler) respond(req replayRequest) response {
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+
+	if req.Method == "getblockcount" {
+		resp.Result, _ = json.Marshal(h.blockCount())
+		return resp
+	}
+
+	f, ok := h.fixtures.lookup(req.Method, req.Params)
+	if !ok {
+		h.mu.Lock()
+		h.missing[fixtureKey(req.Method, req.Params)] = true
+		h.mu.Unlock()
+
+		resp.Error = &Error{Code: CodeUnknown, Message: "Not recorded"}
+		return resp
+	}
+
+	resp.Result = f.Result
+	resp.Error = f.Error
+	return resp
+}
+
+// blockCount returns the recorded block count, limited to the highest recorded block.
+func (h *ReplayHandler) blockCount() int {
+	count := 0
+	for _, key := range h.fixtures.keys("getblock") {
+		params := []interface{}{}
+		if err := json.Unmarshal([]byte(key[len("getblock "):]), &params); err != nil || len(params) == 0 {
+			continue
+		}
+
+		if index, ok := params[0].(float64); ok && int(index)+1 > count {
+			count = int(index) + 1
+		}
+	}
+
+	if f, ok := h.fixtures.lookup("getblockcount", nil); ok {
+		recorded := 0
+		if json.Unmarshal(f.Result, &recorded) == nil && recorded < count {
+			return recorded
+		}
+	}
+
+	return count
+}
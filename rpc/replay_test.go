@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := newTestServer(t)
+
+	fixtures := NewFixtures()
+	Record(fixtures)
+	blocks, err := DownloadBlocks(context.Background(), []int{1, 2, 3})
+	Record(nil)
+	srv.Close()
+
+	if err != nil || len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %v %v", blocks, err)
+	}
+
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixtures.json")
+	if err := fixtures.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 3 {
+		t.Errorf("Expected 3 recorded responses, got %d", loaded.Len())
+	}
+
+	replay := NewReplayHandler(loaded)
+	replaySrv := httptest.NewServer(replay)
+	defer replaySrv.Close()
+
+	// Block 2 failed in the batch and was recorded by the single request.
+	setTestServer(replaySrv.URL, 100)
+	blocks, err = DownloadBlocks(context.Background(), []int{1, 2, 3})
+	for i, b := range blocks {
+		if b == nil || int(b.Index) != i+1 {
+			t.Errorf("Expected replayed block %d, got %v %v", i+1, b, err)
+		}
+	}
+
+	height, err := getHeightFrom(context.Background(), replaySrv.URL)
+	if err != nil || height != 3 {
+		t.Errorf("Expected height of the highest recorded block, got %d %v", height, err)
+	}
+
+	if _, err := DownloadBlock(context.Background(), 4); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error of block not recorded, got %v", err)
+	}
+	if missing := replay.Missing(); len(missing) != 1 || missing[0] != "getblock [4,1]" {
+		t.Errorf("Expected missing block 4, got %v", missing)
+	}
+}
@@ -1,150 +1,173 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"squirrel/config"
 	"squirrel/log"
-	"strings"
 	"time"
 
 	eParser "github.com/go-errors/errors"
 	"github.com/valyala/fasthttp"
 )
 
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
 var (
-	client     = &http.Client{Timeout: 20 * time.Second}
+	client     = &http.Client{}
 	fastClient = &fasthttp.Client{}
 )
 
-// JsonRPCResponse returns rpc response data.
-type jsonRPCResponse struct {
-	JSONRPC string `json:"jsonrpc"`
-	ID      int    `json:"id"`
-}
+// call sends a json-rpc request to a server whose height is at least minHeight,
+// and unmarshals its result into target.
+// Failed requests are retried on other servers as configured for the method.
+// The returned error matches ErrNotFound if the item does not exist,
+// or is an *Error if the node failed, or a *TransportError.
+func call(ctx context.Context, minHeight int, method string, params []interface{}, target interface{}) error {
+	requestBody, err := json.Marshal(newRequest(1, method, params))
+	if err != nil {
+		return err
+	}
 
-func getRPCRequestBody(method string, params []interface{}) string {
-	p := ""
-
-	for _, param := range params {
-		switch param.(type) {
-		case int8, uint8,
-			int16, uint16,
-			int, uint,
-			int32, uint32,
-			int64, uint64:
-			p += fmt.Sprintf("%d, ", param)
-		case string:
-			p += fmt.Sprintf("\"%s\", ", param)
-		default:
-			err := fmt.Errorf("the RPC parameter type must be integer or string. current type=%T, value=%v", param, param)
-			panic(err)
-		}
+	url, bodyBytes, err := post(ctx, minHeight, method, false, requestBody)
+	if err != nil {
+		return err
 	}
 
-	if p != "" {
-		p = p[:len(p)-2]
+	resp := response{}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return invalidResponse(url, requestBody, bodyBytes, err)
 	}
+	record(method, params, &resp)
 
-	body := `{
-		"jsonrpc": "2.0",
-		"method": "` + method + `",
-		"params": [
-			` + p + `
-		],
-		"id": 1
+	err = resp.decode(method, target)
+	var rpcErr *Error
+	if err != nil && !errors.As(err, &rpcErr) && !errors.Is(err, ErrNotFound) {
+		return invalidResponse(url, requestBody, bodyBytes, err)
 	}
-	`
-	return body
+
+	return err
 }
 
-func rpcCall(minHeight int, params string, target interface{}) {
-	call(minHeight, params, target)
+func invalidResponse(url string, requestBody []byte, bodyBytes []byte, err error) error {
+	serverUnavailable(url)
+	log.With(log.Fields{"rpc": url, "request": string(requestBody), "response": string(bodyBytes)}).
+		Errorf("Invalid response: %s\n", eParser.Wrap(err, 0).ErrorStack())
+
+	return &TransportError{URL: url, Err: err}
 }
 
-func call(minHeight int, params string, target interface{}) {
-	requestBody := []byte(params)
+// post sends requestBody of method to a server whose height is at least minHeight,
+// and returns the server url with its response body.
+// Each attempt is limited by the timeout of the method,
+// failed attempts are retried with back-off until max retries of the method is reached or ctx is cancelled.
+// If no server reaches minHeight for getblock, it returns ErrNotFound without retrying.
+func post(ctx context.Context, minHeight int, method string, batch bool, requestBody []byte) (string, []byte, error) {
+	label := method
+	if batch {
+		label += " batch"
+	}
+	callCfg := config.GetRPCMethodConfig(method)
+	timeout := time.Duration(callCfg.Timeout) * time.Second
+
 	resp := fasthttp.AcquireResponse()
 	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseResponse(resp)
+	defer fasthttp.ReleaseRequest(req)
+
 	req.Header.SetMethod("POST")
+	req.Header.SetContentType("application/json")
 	req.SetBody(requestBody)
 
-	for {
-		url, ok := getServer(minHeight)
+	var url string
+	var err error
+
+	for retry := 0; retry <= callCfg.MaxRetries; retry++ {
+		if !wait(ctx, backoff(retry)) {
+			return url, nil, &TransportError{URL: url, Err: ctx.Err()}
+		}
+
+		var ok bool
+		url, ok = getServer(minHeight)
 		if !ok {
-			if strings.Contains(params, `"getblock"`) {
-				// Exceed the highest block index, return nil target.
-				return
+			// Blocks beyond the highest block index are not found.
+			if method == "getblock" {
+				return "", nil, fmt.Errorf("%w: no server reached height %d", ErrNotFound, minHeight)
 			}
-			delay := 3
-			fmt.Printf("No server's height higher than or equal to %d\nWaiting for %d seconds before retry\n", minHeight, delay)
-			time.Sleep(time.Duration(delay) * time.Second)
+
+			err = fmt.Errorf("%w: no server reached height %d", ErrNoServer, minHeight)
+			log.With(log.Fields{"height": minHeight}).Warnf("No server's height higher than or equal to %d\n", minHeight)
 			PrintServerStatus()
 			continue
 		}
 
 		req.SetRequestURI(url)
-		err := fastClient.Do(req, resp)
+		start := time.Now()
+		err = fastClient.DoDeadline(req, resp, deadline(ctx, timeout))
+		if err == nil && resp.StatusCode() >= fasthttp.StatusInternalServerError {
+			err = fmt.Errorf("%s responded with status %d", url, resp.StatusCode())
+		}
 		if err != nil {
-			log.Error.Println(err)
+			log.With(log.Fields{"rpc": url, "method": label}).Errorf("Request failed: %v\n", err)
 			serverUnavailable(url)
-			time.Sleep(50 * time.Millisecond)
 			continue
 		}
 
-		break
+		latency := time.Since(start)
+		requestDuration.WithLabelValues(label).Observe(latency.Seconds())
+		serverSucceeded(url, latency)
 
+		// The response body is reused once released.
+		return url, append([]byte(nil), resp.Body()...), nil
 	}
 
-	bodyBytes := resp.Body()
+	return url, nil, &TransportError{URL: url, Err: err}
+}
 
-	err := json.Unmarshal(bodyBytes, target)
-	if err != nil {
-		log.Error.Println(errors.New(eParser.Wrap(err, 0).ErrorStack()))
-		log.Error.Printf("Request body: %v\n", string(requestBody))
-		log.Error.Printf("Response: %v\n", string(bodyBytes))
+// backoff returns the delay before the given retry, 0 for the first attempt.
+func backoff(retry int) time.Duration {
+	if retry == 0 {
+		return 0
 	}
+
+	d := minRetryDelay << uint(retry-1)
+	if d > maxRetryDelay || d <= 0 {
+		return maxRetryDelay
+	}
+	return d
 }
 
-// func call(minHeight int, params string, target interface{}) {
-// 	requestBody := []byte(params)
-// 	var resp *http.Response
-// 	var err error
-
-// 	for {
-// 		url, ok := getServer(minHeight)
-// 		if !ok {
-// 			if strings.Contains(params, `"getblock"`) {
-// 				// Exceed the highest block index, return nil target
-// 				return
-// 			}
-
-// 			delay := 3
-// 			fmt.Printf("No server's height higher than or equal to %d\nWaiting for %d seconds before retry\n", minHeight, delay)
-// 			time.Sleep(time.Duration(delay) * time.Second)
-// 			continue
-// 		}
-
-// 		resp, err = client.Post(url, "application/json", bytes.NewBuffer(requestBody))
-// 		if err != nil {
-// 			log.Println(err)
-// 			serverUnavailable(url)
-// 			time.Sleep(50 * time.Millisecond)
-// 			continue
-// 		}
-
-// 		break
-// 	}
-
-// 	defer resp.Body.Close()
-
-// 	bodyBytes, _ := ioutil.ReadAll(resp.Body)
-
-// 	err = json.Unmarshal(bodyBytes, target)
-// 	if err != nil {
-// 		log.Error.Println(errors.New(eParser.Wrap(err, 0).ErrorStack()))
-// 		log.Error.Printf("Request body: %v\n", string(requestBody))
-// 		log.Error.Printf("Response: %v\n", string(bodyBytes))
-// 	}
-// }
+// wait pauses for d, it returns false if ctx is cancelled.
+func wait(ctx context.Context, d time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if d == 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// deadline returns the deadline of an attempt, which is earlier than that of ctx.
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(d) {
+		return ctxDeadline
+	}
+	return d
+}
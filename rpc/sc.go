@@ -1,16 +1,11 @@
 package rpc
 
 import (
+	"context"
 	"math/big"
 )
 
-// SmartContractResponse is the struct of returning data from 'invokescript' rpc call.
-type SmartContractResponse struct {
-	jsonRPCResponse
-	Result *RawSmartContractCallResult `json:"result"`
-}
-
-// RawSmartContractCallResult is the inner struct of struct 'SmartContractResponse'.
+// RawSmartContractCallResult is the result of 'invokescript' rpc call.
 type RawSmartContractCallResult struct {
 	Script      string     `json:"script"`
 	State       string     `json:"state"`
@@ -57,12 +52,13 @@ type RawStack struct {
 }
 
 // SmartContractRPCCall returns result of 'invokescript' rpc call.
-func SmartContractRPCCall(minHeight int, scripts string) *RawSmartContractCallResult {
+func SmartContractRPCCall(ctx context.Context, minHeight int, scripts string) (*RawSmartContractCallResult, error) {
 	params := []interface{}{scripts}
-	args := getRPCRequestBody("invokescript", params)
 
-	respData := SmartContractResponse{}
-	rpcCall(minHeight, args, &respData)
+	var result *RawSmartContractCallResult
+	if err := call(ctx, minHeight, "invokescript", params, &result); err != nil {
+		return nil, err
+	}
 
-	return respData.Result
+	return result, nil
 }
@@ -2,24 +2,27 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"sort"
 	"squirrel/config"
-	"squirrel/mail"
+	"squirrel/log"
+	"squirrel/notify"
 	"squirrel/util"
-	"strings"
 	"sync"
 	"time"
 )
 
 var (
-	// servers stores all neo rpc urls with its height
+	// servers stores all neo rpc servers with their heights and health.
 	// For those (temporarily)unaccessable servers,
 	// their height will be set to -1.
 	// These servers' heights will be refreshed timely.
-	servers map[string]int
-	sLock   sync.RWMutex
+	servers map[string]*server
+	sLock   sync.Mutex
 
 	// BestHeight indicates current highest height.
 	BestHeight util.SafeCounter
@@ -31,113 +34,236 @@ type ServerInfo struct {
 	height int
 }
 
-// getServer randomly returns one of rpc servers whose height higher than minHeight.
+// ServerStatus is the current status of a rpc server.
+type ServerStatus struct {
+	URL      string
+	Priority int
+	Weight   int
+	// Height is -1 if the server failed to respond its height.
+	Height int
+	// State is one of StateClosed, StateOpen and StateHalfOpen.
+	State string
+	// OpenUntil is the end of the current back-off if State is StateOpen.
+	OpenUntil time.Time
+	// Latency is the moving average latency of successful requests.
+	Latency time.Duration
+	// ErrorRate is the moving average rate of failed requests.
+	ErrorRate float64
+	// Score rates the server by latency and error rate, 1 is the best.
+	Score    float64
+	Requests uint64
+	Failures uint64
+}
+
+// getServer returns one of rpc servers whose height higher than minHeight.
+// Servers of the lowest priority with closed circuits are preferred,
+// and selected randomly in proportion to their weights and scores.
 func getServer(minHeight int) (string, bool) {
 	if minHeight < 0 {
 		err := fmt.Errorf("minHeight(%d) cannot lower than zero", minHeight)
 		panic(err)
 	}
 
-	sLock.RLock()
-	defer sLock.RUnlock()
+	sLock.Lock()
+	defer sLock.Unlock()
 
-	// Suppose all servers are qualified.
-	candidates := []string{}
+	now := time.Now()
+	candidates := []*server{}
 
-	for url, height := range servers {
-		if height >= int(minHeight) {
-			// Always select localhost rpc server if valid.
-			if strings.Contains(url, "127.0.0.1") ||
-				strings.Contains(url, "localhost") {
-				candidates = append(candidates, url)
-			}
-
-			candidates = append(candidates, url)
+	for _, s := range servers {
+		if s.height >= minHeight && s.available(now) {
+			candidates = append(candidates, s)
 		}
 	}
 
-	l := len(candidates)
-	if l == 0 {
+	s := pick(candidates, rand.Float64())
+	if s == nil {
 		return "", false
 	}
 
-	return candidates[rand.Intn(l)], true
+	if s.state(now) == StateHalfOpen {
+		s.probing = true
+	}
+
+	return s.url, true
+}
+
+// serverSucceeded records a successful request to the server.
+func serverSucceeded(url string, latency time.Duration) {
+	sLock.Lock()
+	defer sLock.Unlock()
+
+	if s, ok := servers[url]; ok {
+		s.success(latency)
+	}
 }
 
 func serverUnavailable(url string) {
+	serverErrors.WithLabelValues(url).Inc()
+
 	sLock.Lock()
 	defer sLock.Unlock()
 
 	// Incase server changed(e.g., reloaded dut to config file change).
-	if _, ok := servers[url]; ok {
-		servers[url] = -1
+	if s, ok := servers[url]; ok {
+		s.failure(time.Now())
 	}
 }
 
-// PrintServerStatus prints rpc host with its current best height.
-func PrintServerStatus() {
-	sLock.RLock()
-	defer sLock.RUnlock()
+// AllServersDown returns true if every rpc server is unavailable or has an open circuit.
+func AllServersDown() bool {
+	sLock.Lock()
+	defer sLock.Unlock()
 
-	for host, height := range servers {
-		if height < 0 {
-			fmt.Printf("%s: %s\n", host, util.BRed("Failed to get server height"))
-		} else {
-			fmt.Printf("%s: %d\n", host, height)
+	if len(servers) == 0 {
+		return false
+	}
+
+	now := time.Now()
+	for _, s := range servers {
+		if s.height >= 0 && s.state(now) != StateOpen {
+			return false
 		}
 	}
+
+	return true
 }
 
-// TraceBestHeight starts a.
-func TraceBestHeight() {
-	defer mail.AlertIfErr()
+// Servers returns status of all rpc servers ordered by priority and url.
+func Servers() []ServerStatus {
+	sLock.Lock()
+	defer sLock.Unlock()
+
+	now := time.Now()
+	status := make([]ServerStatus, 0, len(servers))
+
+	for _, s := range servers {
+		st := ServerStatus{
+			URL:       s.url,
+			Priority:  s.priority,
+			Weight:    s.weight,
+			Height:    s.height,
+			State:     s.state(now),
+			Latency:   s.latency,
+			ErrorRate: s.errorRate,
+			Score:     s.score(),
+			Requests:  s.requests,
+			Failures:  s.failures,
+		}
+		if st.State == StateOpen {
+			st.OpenUntil = s.openUntil
+		}
+		status = append(status, st)
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Priority != status[j].Priority {
+			return status[i].Priority < status[j].Priority
+		}
+		return status[i].URL < status[j].URL
+	})
+
+	return status
+}
 
-	RefreshServers()
+// PrintServerStatus prints rpc host with its current best height and health.
+func PrintServerStatus() {
+	for _, s := range Servers() {
+		entry := log.With(log.Fields{"rpc": s.URL})
+		if s.Height < 0 {
+			entry.Warnf("Failed to get server height\n")
+			continue
+		}
+
+		entry.Infof("Height %d, %s, score %.2f, latency %v, error rate %.2f\n",
+			s.Height, s.State, s.Score, s.Latency.Round(time.Millisecond), s.ErrorRate)
+	}
+}
+
+// TraceBestHeight keeps refreshing heights of rpc servers until ctx is cancelled.
+func TraceBestHeight(ctx context.Context) {
+	defer notify.AlertIfErr()
+
+	RefreshServers(ctx)
 	//PrintServerStatus()
 
 	for {
-		RefreshServers()
+		RefreshServers(ctx)
 
-		time.Sleep(3 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+		}
 	}
 }
 
-// RefreshServers updates heights of all rpc servers.
-func RefreshServers() int {
+// RefreshServers updates heights of all rpc servers and returns the best height.
+// Health of servers is kept, servers removed from config are dropped.
+// Heights are unchanged if ctx is cancelled.
+func RefreshServers(ctx context.Context) int {
+	rpcs := config.GetRPCs()
 	// It takes time to get heights.
-	serverInfos := getHeights()
+	heights := getHeights(ctx, rpcs)
+	if ctx.Err() != nil {
+		return BestHeight.Get()
+	}
 
 	sLock.Lock()
 
-	servers = serverInfos
+	refreshed := make(map[string]*server, len(rpcs))
 	bestHeight := 0
-	for _, height := range serverInfos {
-		if bestHeight < height {
-			bestHeight = height
+	now := time.Now()
+
+	// Drop servers removed from config.
+	serverHeight.Reset()
+	serverScore.Reset()
+	serverCircuitOpen.Reset()
+
+	for _, rpc := range rpcs {
+		s, ok := servers[rpc.URL]
+		if !ok {
+			s = &server{url: rpc.URL}
+		}
+		s.priority = rpc.Priority
+		s.weight = rpc.Weight
+		s.height = heights[rpc.URL]
+		refreshed[rpc.URL] = s
+
+		serverHeight.WithLabelValues(s.url).Set(float64(s.height))
+		serverScore.WithLabelValues(s.url).Set(s.score())
+		if s.state(now) == StateOpen {
+			serverCircuitOpen.WithLabelValues(s.url).Set(1)
+		} else {
+			serverCircuitOpen.WithLabelValues(s.url).Set(0)
+		}
+
+		if bestHeight < s.height {
+			bestHeight = s.height
 		}
 	}
+
+	servers = refreshed
 	BestHeight.Set(bestHeight)
 
 	sLock.Unlock()
 	return bestHeight
 }
 
-// getHeights gets current height of all rpc servers
-// and returns best height from these servers.
-func getHeights() map[string]int {
+// getHeights gets current height of all rpc servers.
+func getHeights(ctx context.Context, rpcs []config.RPCConfig) map[string]int {
 	// log.Printf("Checking all rpc servers...")
 
-	rpcs := config.GetRPCs()
 	c := make(chan ServerInfo, len(rpcs))
 
-	for _, url := range rpcs {
+	for _, rpc := range rpcs {
 		go func(url string, c chan<- ServerInfo) {
-			height, _ := getHeightFrom(url)
+			height, _ := getHeightFrom(ctx, url)
 			c <- ServerInfo{
 				url:    url,
 				height: height,
 			}
-		}(url, c)
+		}(rpc.URL, c)
 	}
 
 	serverInfos := make(map[string]int)
@@ -153,17 +279,53 @@ func getHeights() map[string]int {
 }
 
 // getHeightFrom returns current block index of the given rpc server.
-func getHeightFrom(url string) (int, error) {
-	params := []interface{}{}
-	args := getRPCRequestBody("getblockcount", params)
+// The request is limited by the timeout of 'getblockcount' and never retried.
+func getHeightFrom(ctx context.Context, url string) (int, error) {
+	count := 0
+	if err := callServer(ctx, url, "getblockcount", &count); err != nil {
+		return -1, err
+	}
+
+	return count - 1, nil
+}
+
+// callServer sends a json-rpc request without params to the given rpc server,
+// and unmarshals its result into target.
+// The request is limited by the timeout of the method and never retried.
+func callServer(ctx context.Context, url string, method string, target interface{}) error {
+	args, _ := json.Marshal(newRequest(1, method, nil))
 
-	respData := BlockCountRespponse{}
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer([]byte(args)))
+	timeout := time.Duration(config.GetRPCMethodConfig(method).Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(args))
 	if err != nil {
-		return -1, err
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		serverErrors.WithLabelValues(url).Inc()
+		return &TransportError{URL: url, Err: err}
 	}
 	defer resp.Body.Close()
+	requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	respData := response{}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		serverErrors.WithLabelValues(url).Inc()
+		return &TransportError{URL: url, Err: err}
+	}
+
+	record(method, nil, &respData)
+
+	if err := respData.decode(method, target); err != nil {
+		serverErrors.WithLabelValues(url).Inc()
+		return err
+	}
 
-	json.NewDecoder(resp.Body).Decode(&respData)
-	return respData.Result - 1, nil
+	return nil
 }
@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"squirrel/config"
+	"squirrel/log"
+)
+
+// RawVersion is the result of 'getversion'.
+// Only Neo N3 nodes report the network magic.
+type RawVersion struct {
+	UserAgent string `json:"useragent"`
+	Protocol  struct {
+		Network uint32 `json:"network"`
+	} `json:"protocol"`
+}
+
+// CheckMagic returns an error if any rpc server reports a network magic other than magic.
+// Servers which cannot be reached or do not report the magic are skipped.
+func CheckMagic(ctx context.Context, magic uint32) error {
+	urls := []string{}
+	for _, rpc := range config.GetRPCs() {
+		urls = append(urls, rpc.URL)
+	}
+
+	return checkMagic(ctx, urls, magic)
+}
+
+func checkMagic(ctx context.Context, urls []string, magic uint32) error {
+	for _, url := range urls {
+		var version RawVersion
+		if err := callServer(ctx, url, "getversion", &version); err != nil {
+			log.With(log.Fields{"rpc": url}).Warnf("Failed to check network magic: %v\n", err)
+			continue
+		}
+
+		if version.Protocol.Network != 0 && version.Protocol.Network != magic {
+			return fmt.Errorf("rpc server %s runs network with magic %d, but the profile has magic %d", url, version.Protocol.Network, magic)
+		}
+	}
+
+	return nil
+}
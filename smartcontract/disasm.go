@@ -0,0 +1,210 @@
+package smartcontract
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"squirrel/util"
+	"strings"
+)
+
+// Instruction is a disassembled NeoVM 2 instruction.
+type Instruction struct {
+	// Offset is the position of the opcode in script.
+	Offset int
+	// Size is the number of bytes of the opcode, the length prefix and the operand.
+	Size   int
+	OpCode OpCode
+	// Operand is the data following the opcode without its length prefix.
+	Operand []byte
+}
+
+// Disassemble reads all instructions of a hex encoded script.
+// Instructions read before an unknown opcode or a truncated operand are returned with the error.
+func Disassemble(script string) ([]*Instruction, error) {
+	bytes, err := hex.DecodeString(script)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script: %v", err)
+	}
+
+	context := scriptContext{0, bytes}
+	instructions := []*Instruction{}
+
+	for context.Position < uint64(len(bytes)) {
+		offset := int(context.Position)
+		op := OpCode(context.readByte())
+		if !op.Valid() {
+			return instructions, fmt.Errorf("unknown opcode %#02x at %d", byte(op), offset)
+		}
+
+		operand, err := readOperand(op, &context)
+		if err != nil {
+			return instructions, fmt.Errorf("truncated operand of %s at %d", op, offset)
+		}
+
+		instructions = append(instructions, &Instruction{
+			Offset:  offset,
+			Size:    int(context.Position) - offset,
+			OpCode:  op,
+			Operand: operand,
+		})
+	}
+
+	return instructions, nil
+}
+
+func readOperand(op OpCode, context *scriptContext) ([]byte, error) {
+	switch {
+	case op >= PUSHBYTES1 && op <= PUSHBYTES75:
+		return context.readBytes(uint64(op))
+	case op == PUSHDATA1, op == PUSHDATA2, op == PUSHDATA4:
+		prefix, err := context.readBytes(uint64(1) << (op - PUSHDATA1))
+		if err != nil {
+			return nil, err
+		}
+		return context.readBytes(readLength(prefix))
+	case op == SYSCALL:
+		prefix, err := context.readBytes(1)
+		if err != nil {
+			return nil, err
+		}
+		switch prefix[0] {
+		case 0xFD:
+			prefix, err = context.readBytes(2)
+		case 0xFE:
+			prefix, err = context.readBytes(4)
+		case 0xFF:
+			prefix, err = context.readBytes(8)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return context.readBytes(readLength(prefix))
+	}
+
+	switch op {
+	case JMP, JMPIF, JMPIFNOT, CALL, CALLED, CALLEDT:
+		return context.readBytes(2)
+	case APPCALL, TAILCALL:
+		return context.readBytes(20)
+	case CALLI:
+		return context.readBytes(4)
+	case CALLE, CALLET:
+		return context.readBytes(22)
+	}
+
+	return nil, nil
+}
+
+// readLength reads a little-endian length prefix of 1, 2, 4 or 8 bytes.
+func readLength(prefix []byte) uint64 {
+	data := make([]byte, 8)
+	copy(data, prefix)
+	return binary.LittleEndian.Uint64(data)
+}
+
+// JumpTarget returns the offset jumped to by JMP, JMPIF, JMPIFNOT, CALL and CALL_I.
+func (i *Instruction) JumpTarget() (int, bool) {
+	switch i.OpCode {
+	case JMP, JMPIF, JMPIFNOT, CALL:
+		return i.Offset + int(int16(binary.LittleEndian.Uint16(i.Operand))), true
+	case CALLI:
+		return i.Offset + int(int16(binary.LittleEndian.Uint16(i.Operand[2:]))), true
+	}
+
+	return 0, false
+}
+
+// CalledScriptHash returns the script hash called by APPCALL, TAILCALL, CALL_E and CALL_ET,
+// which is empty for dynamic calls whose script hash is popped from stack.
+func (i *Instruction) CalledScriptHash() ([]byte, bool) {
+	var scriptHash []byte
+	switch i.OpCode {
+	case APPCALL, TAILCALL:
+		scriptHash = i.Operand
+	case CALLE, CALLET:
+		scriptHash = i.Operand[2:]
+	default:
+		return nil, false
+	}
+
+	for _, b := range scriptHash {
+		if b != 0 {
+			return scriptHash, true
+		}
+	}
+
+	return []byte{}, true
+}
+
+// String formats the instruction as offset, mnemonic and readable operand.
+func (i *Instruction) String() string {
+	operand := i.formatOperand()
+	if operand == "" {
+		return fmt.Sprintf("%04d  %s", i.Offset, i.OpCode)
+	}
+
+	return fmt.Sprintf("%04d  %-12s %s", i.Offset, i.OpCode, operand)
+}
+
+func (i *Instruction) formatOperand() string {
+	args := []string{}
+
+	switch i.OpCode {
+	case CALLI, CALLE, CALLED, CALLET, CALLEDT:
+		args = append(args, fmt.Sprintf("rvcount=%d", i.Operand[0]), fmt.Sprintf("pcount=%d", i.Operand[1]))
+	}
+
+	if target, ok := i.JumpTarget(); ok {
+		return strings.Join(append(args, fmt.Sprintf("%04d", target)), " ")
+	}
+
+	if scriptHash, ok := i.CalledScriptHash(); ok {
+		if len(scriptHash) == 0 {
+			return strings.Join(append(args, "dynamic"), " ")
+		}
+		return strings.Join(append(args, "0x"+util.GetAssetIDFromScriptHash(scriptHash)), " ")
+	}
+
+	if i.OpCode == SYSCALL && printable(i.Operand) {
+		return string(i.Operand)
+	}
+
+	// CALL_ED and CALL_EDT have no other operand.
+	if len(args) > 0 || len(i.Operand) == 0 {
+		return strings.Join(args, " ")
+	}
+
+	operand := hex.EncodeToString(i.Operand)
+	if printable(i.Operand) {
+		operand += fmt.Sprintf(" // %q", i.Operand)
+	}
+
+	return operand
+}
+
+// printable tells if data is a non-empty printable ascii string.
+func printable(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	for _, b := range data {
+		if b < 0x20 || b > 0x7E {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Format returns the disassembly text of instructions, one instruction per line.
+func Format(instructions []*Instruction) string {
+	var sb strings.Builder
+	for _, i := range instructions {
+		sb.WriteString(i.String())
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
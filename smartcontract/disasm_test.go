@@ -0,0 +1,58 @@
+package smartcontract
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	scsb := ScriptBuilder{
+		ScriptHash: []byte{0xbe, 0x39, 0xe7, 0xb5, 0x62, 0xf6, 0x0c, 0xbf, 0xe2, 0xae, 0xbc, 0xa3, 0x75, 0xa2, 0xe5, 0xee, 0x28, 0x73, 0x7c, 0xaf},
+		Method:     "balanceOf",
+		Params:     [][]byte{{0x01, 0x02}},
+	}
+	script := scsb.GetScript() +
+		// JMPIFNOT +3, SYSCALL Neo.Runtime.Log, TAILCALL dynamic, CALL_ED, RET
+		"640300" + "680f" + hex.EncodeToString([]byte("Neo.Runtime.Log")) +
+		"69" + "0000000000000000000000000000000000000000" + "e20102" + "66"
+
+	instructions, err := Disassemble(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "0000  PUSHBYTES2   0102\n" +
+		"0003  PUSH1\n" +
+		"0004  PACK\n" +
+		"0005  PUSHBYTES9   62616c616e63654f66 // \"balanceOf\"\n" +
+		"0015  APPCALL      0xaf7c7328eee5a275a3bcaee2bf0cf662b5e739be\n" +
+		"0036  JMPIFNOT     0039\n" +
+		"0039  SYSCALL      Neo.Runtime.Log\n" +
+		"0056  TAILCALL     dynamic\n" +
+		"0077  CALL_ED      rvcount=1 pcount=2\n" +
+		"0080  RET\n"
+	if text := Format(instructions); text != expected {
+		t.Errorf("Unexpected disassembly:\n%s", text)
+	}
+
+	if instructions[4].Size != 21 {
+		t.Errorf("Size of APPCALL should be 21, got %d", instructions[4].Size)
+	}
+}
+
+func TestDisassembleInvalid(t *testing.T) {
+	for script, valid := range map[string]int{
+		"51ff":   1,
+		"5104ab": 1,
+		"514c":   1,
+		"68fd01": 0,
+	} {
+		instructions, err := Disassemble(script)
+		if err == nil {
+			t.Errorf("Script %s should not be disassembled", script)
+		}
+		if len(instructions) != valid {
+			t.Errorf("Script %s should have %d valid instructions, got %d", script, valid, len(instructions))
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package smartcontract
+
+import "fmt"
+
+// OpCode is an instruction of NeoVM 2.
+type OpCode byte
+
+// OpCodes of NeoVM 2, PUSHBYTES2 to PUSHBYTES74 and PUSH2 to PUSH15 are omitted.
+const (
+	PUSH0       OpCode = 0x00
+	PUSHBYTES1  OpCode = 0x01
+	PUSHBYTES75 OpCode = 0x4B
+	PUSHDATA1   OpCode = 0x4C
+	PUSHDATA2   OpCode = 0x4D
+	PUSHDATA4   OpCode = 0x4E
+	PUSHM1      OpCode = 0x4F
+	PUSH1       OpCode = 0x51
+	PUSH16      OpCode = 0x60
+
+	NOP      OpCode = 0x61
+	JMP      OpCode = 0x62
+	JMPIF    OpCode = 0x63
+	JMPIFNOT OpCode = 0x64
+	CALL     OpCode = 0x65
+	RET      OpCode = 0x66
+	APPCALL  OpCode = 0x67
+	SYSCALL  OpCode = 0x68
+	TAILCALL OpCode = 0x69
+
+	DUPFROMALTSTACK OpCode = 0x6A
+	TOALTSTACK      OpCode = 0x6B
+	FROMALTSTACK    OpCode = 0x6C
+	XDROP           OpCode = 0x6D
+	XSWAP           OpCode = 0x72
+	XTUCK           OpCode = 0x73
+	DEPTH           OpCode = 0x74
+	DROP            OpCode = 0x75
+	DUP             OpCode = 0x76
+	NIP             OpCode = 0x77
+	OVER            OpCode = 0x78
+	PICK            OpCode = 0x79
+	ROLL            OpCode = 0x7A
+	ROT             OpCode = 0x7B
+	SWAP            OpCode = 0x7C
+	TUCK            OpCode = 0x7D
+
+	CAT    OpCode = 0x7E
+	SUBSTR OpCode = 0x7F
+	LEFT   OpCode = 0x80
+	RIGHT  OpCode = 0x81
+	SIZE   OpCode = 0x82
+
+	INVERT OpCode = 0x83
+	AND    OpCode = 0x84
+	OR     OpCode = 0x85
+	XOR    OpCode = 0x86
+	EQUAL  OpCode = 0x87
+
+	INC         OpCode = 0x8B
+	DEC         OpCode = 0x8C
+	SIGN        OpCode = 0x8D
+	NEGATE      OpCode = 0x8F
+	ABS         OpCode = 0x90
+	NOT         OpCode = 0x91
+	NZ          OpCode = 0x92
+	ADD         OpCode = 0x93
+	SUB         OpCode = 0x94
+	MUL         OpCode = 0x95
+	DIV         OpCode = 0x96
+	MOD         OpCode = 0x97
+	SHL         OpCode = 0x98
+	SHR         OpCode = 0x99
+	BOOLAND     OpCode = 0x9A
+	BOOLOR      OpCode = 0x9B
+	NUMEQUAL    OpCode = 0x9C
+	NUMNOTEQUAL OpCode = 0x9E
+	LT          OpCode = 0x9F
+	GT          OpCode = 0xA0
+	LTE         OpCode = 0xA1
+	GTE         OpCode = 0xA2
+	MIN         OpCode = 0xA3
+	MAX         OpCode = 0xA4
+	WITHIN      OpCode = 0xA5
+
+	SHA1          OpCode = 0xA7
+	SHA256        OpCode = 0xA8
+	HASH160       OpCode = 0xA9
+	HASH256       OpCode = 0xAA
+	CHECKSIG      OpCode = 0xAC
+	VERIFY        OpCode = 0xAD
+	CHECKMULTISIG OpCode = 0xAE
+
+	ARRAYSIZE OpCode = 0xC0
+	PACK      OpCode = 0xC1
+	UNPACK    OpCode = 0xC2
+	PICKITEM  OpCode = 0xC3
+	SETITEM   OpCode = 0xC4
+	NEWARRAY  OpCode = 0xC5
+	NEWSTRUCT OpCode = 0xC6
+	NEWMAP    OpCode = 0xC7
+	APPEND    OpCode = 0xC8
+	REVERSE   OpCode = 0xC9
+	REMOVE    OpCode = 0xCA
+	HASKEY    OpCode = 0xCB
+	KEYS      OpCode = 0xCC
+	VALUES    OpCode = 0xCD
+
+	CALLI   OpCode = 0xE0
+	CALLE   OpCode = 0xE1
+	CALLED  OpCode = 0xE2
+	CALLET  OpCode = 0xE3
+	CALLEDT OpCode = 0xE4
+
+	THROW      OpCode = 0xF0
+	THROWIFNOT OpCode = 0xF1
+)
+
+var opCodeNames = map[OpCode]string{
+	PUSH0:     "PUSH0",
+	PUSHDATA1: "PUSHDATA1",
+	PUSHDATA2: "PUSHDATA2",
+	PUSHDATA4: "PUSHDATA4",
+	PUSHM1:    "PUSHM1",
+
+	NOP:      "NOP",
+	JMP:      "JMP",
+	JMPIF:    "JMPIF",
+	JMPIFNOT: "JMPIFNOT",
+	CALL:     "CALL",
+	RET:      "RET",
+	APPCALL:  "APPCALL",
+	SYSCALL:  "SYSCALL",
+	TAILCALL: "TAILCALL",
+
+	DUPFROMALTSTACK: "DUPFROMALTSTACK",
+	TOALTSTACK:      "TOALTSTACK",
+	FROMALTSTACK:    "FROMALTSTACK",
+	XDROP:           "XDROP",
+	XSWAP:           "XSWAP",
+	XTUCK:           "XTUCK",
+	DEPTH:           "DEPTH",
+	DROP:            "DROP",
+	DUP:             "DUP",
+	NIP:             "NIP",
+	OVER:            "OVER",
+	PICK:            "PICK",
+	ROLL:            "ROLL",
+	ROT:             "ROT",
+	SWAP:            "SWAP",
+	TUCK:            "TUCK",
+
+	CAT:    "CAT",
+	SUBSTR: "SUBSTR",
+	LEFT:   "LEFT",
+	RIGHT:  "RIGHT",
+	SIZE:   "SIZE",
+
+	INVERT: "INVERT",
+	AND:    "AND",
+	OR:     "OR",
+	XOR:    "XOR",
+	EQUAL:  "EQUAL",
+
+	INC:         "INC",
+	DEC:         "DEC",
+	SIGN:        "SIGN",
+	NEGATE:      "NEGATE",
+	ABS:         "ABS",
+	NOT:         "NOT",
+	NZ:          "NZ",
+	ADD:         "ADD",
+	SUB:         "SUB",
+	MUL:         "MUL",
+	DIV:         "DIV",
+	MOD:         "MOD",
+	SHL:         "SHL",
+	SHR:         "SHR",
+	BOOLAND:     "BOOLAND",
+	BOOLOR:      "BOOLOR",
+	NUMEQUAL:    "NUMEQUAL",
+	NUMNOTEQUAL: "NUMNOTEQUAL",
+	LT:          "LT",
+	GT:          "GT",
+	LTE:         "LTE",
+	GTE:         "GTE",
+	MIN:         "MIN",
+	MAX:         "MAX",
+	WITHIN:      "WITHIN",
+
+	SHA1:          "SHA1",
+	SHA256:        "SHA256",
+	HASH160:       "HASH160",
+	HASH256:       "HASH256",
+	CHECKSIG:      "CHECKSIG",
+	VERIFY:        "VERIFY",
+	CHECKMULTISIG: "CHECKMULTISIG",
+
+	ARRAYSIZE: "ARRAYSIZE",
+	PACK:      "PACK",
+	UNPACK:    "UNPACK",
+	PICKITEM:  "PICKITEM",
+	SETITEM:   "SETITEM",
+	NEWARRAY:  "NEWARRAY",
+	NEWSTRUCT: "NEWSTRUCT",
+	NEWMAP:    "NEWMAP",
+	APPEND:    "APPEND",
+	REVERSE:   "REVERSE",
+	REMOVE:    "REMOVE",
+	HASKEY:    "HASKEY",
+	KEYS:      "KEYS",
+	VALUES:    "VALUES",
+
+	CALLI:   "CALL_I",
+	CALLE:   "CALL_E",
+	CALLED:  "CALL_ED",
+	CALLET:  "CALL_ET",
+	CALLEDT: "CALL_EDT",
+
+	THROW:      "THROW",
+	THROWIFNOT: "THROWIFNOT",
+}
+
+// Valid tells if op is an instruction of NeoVM 2.
+func (op OpCode) Valid() bool {
+	if op >= PUSHBYTES1 && op <= PUSHBYTES75 || op >= PUSH1 && op <= PUSH16 {
+		return true
+	}
+
+	_, ok := opCodeNames[op]
+	return ok
+}
+
+// String returns the mnemonic of op.
+func (op OpCode) String() string {
+	switch {
+	case op >= PUSHBYTES1 && op <= PUSHBYTES75:
+		return fmt.Sprintf("PUSHBYTES%d", op)
+	case op >= PUSH1 && op <= PUSH16:
+		return fmt.Sprintf("PUSH%d", op-PUSH1+1)
+	}
+
+	if name, ok := opCodeNames[op]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("UNKNOWN(%#02x)", byte(op))
+}
@@ -125,7 +125,7 @@ func ReadScript(script string) *DataStack {
 			break
 		}
 
-		if opCode == 0x66 {
+		if OpCode(opCode) == RET {
 			return &stack
 		}
 
@@ -143,71 +143,71 @@ func ReadScript(script string) *DataStack {
 }
 
 func getDataFromScript(opCode byte, context *scriptContext) ([]byte, error) {
-	switch {
-	case opCode == 0x00:
+	switch op := OpCode(opCode); {
+	case op == PUSH0:
 		return []byte{0}, nil
-	case opCode >= 0x01 && opCode <= 0x4B:
+	case op >= PUSHBYTES1 && op <= PUSHBYTES75:
 		data, err := context.readBytes(uint64(opCode))
 		return data, err
-	case opCode == 0x4C:
+	case op == PUSHDATA1:
 		dataLen := context.readByte()
 		data, err := context.readBytes(uint64(dataLen))
 		return data, err
-	case opCode == 0x4D:
+	case op == PUSHDATA2:
 		dataLen := context.readUint16()
 		data, err := context.readBytes(uint64(dataLen))
 		return data, err
-	case opCode == 0x4E:
+	case op == PUSHDATA4:
 		dataLen := context.readUint32()
 		data, err := context.readBytes(uint64(dataLen))
 		return data, err
-	case opCode == 0x4F:
+	case op == PUSHM1:
 		return []byte{0xFF, 0xFF}, nil
-	case opCode >= 0x51 && opCode <= 0x60:
-		data := opCode - 0x51 + 1
+	case op >= PUSH1 && op <= PUSH16:
+		data := opCode - byte(PUSH1) + 1
 		return []byte{data}, nil
-	case opCode == 0x61:
+	case op == NOP:
 		return nil, nil
-	case opCode >= 0x62 && opCode <= 0x64:
+	case op >= JMP && op <= JMPIFNOT:
 		value := context.readUint16()
 		data := make([]byte, 2)
 		binary.LittleEndian.PutUint16(data, value)
 		return data, nil
-	case opCode == 0x65:
+	case op == CALL:
 		data, err := context.readBytes(2)
 		return data, err
-	case opCode == 0x66:
+	case op == RET:
 		return nil, nil
-	case opCode == 0x67:
+	case op == APPCALL:
 		data, err := context.readBytes(20)
 		return data, err
-	case opCode == 0x68:
+	case op == SYSCALL:
 		data, err := context.readVarBytes()
 		return data, err
-	case opCode == 0x69:
+	case op == TAILCALL:
 		data, err := context.readBytes(20)
 		return data, err
-	case opCode >= 0x6A && opCode <= 0x6D:
+	case op >= DUPFROMALTSTACK && op <= XDROP:
 		return nil, nil
-	case opCode >= 0x72 && opCode <= 0x7F:
+	case op >= XSWAP && op <= SUBSTR:
 		return nil, nil
-	case opCode >= 0x80 && opCode <= 0x87:
+	case op >= LEFT && op <= EQUAL:
 		return nil, nil
-	case opCode >= 0x8B && opCode <= 0x8D:
+	case op >= INC && op <= SIGN:
 		return nil, nil
-	case opCode == 0x8F:
+	case op == NEGATE:
 		return nil, nil
-	case opCode >= 0x90 && opCode <= 0x9C:
+	case op >= ABS && op <= NUMEQUAL:
 		return nil, nil
-	case opCode >= 0x9E && opCode <= 0x9F:
+	case op >= NUMNOTEQUAL && op <= LT:
 		return nil, nil
-	case opCode >= 0xA0 && opCode <= 0xAA:
+	case op >= GT && op <= HASH256:
 		return nil, nil
-	case opCode == 0xAC || opCode == 0xAE:
+	case op == CHECKSIG || op == CHECKMULTISIG:
 		return nil, nil
-	case opCode >= 0xC0 && opCode <= 0xCD:
+	case op >= ARRAYSIZE && op <= VALUES:
 		return nil, nil
-	case opCode >= 0xF0 && opCode <= 0xF1:
+	case op >= THROW && op <= THROWIFNOT:
 		return nil, nil
 	default:
 		return nil, fmt.Errorf("unsupported opCode: %#02x", opCode)
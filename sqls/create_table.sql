@@ -1,9 +1,12 @@
-create database if not exists mainnet character set UTF8mb4 collate utf8mb4_bin;
-create database if not exists testnet character set UTF8mb4 collate utf8mb4_bin;
+/*
+    Tables are created by `squirrel migrate`, only the databases and server settings have to be prepared manually:
 
+    create database if not exists mainnet character set UTF8mb4 collate utf8mb4_bin;
+    create database if not exists testnet character set UTF8mb4 collate utf8mb4_bin;
 
-SET GLOBAL TX_ISOLATION = 'READ-COMMITTED';
-SET GLOBAL BINLOG_FORMAT = 'ROW';
+    SET GLOBAL TX_ISOLATION = 'READ-COMMITTED';
+    SET GLOBAL BINLOG_FORMAT = 'ROW';
+*/
 
 
 create table addr_asset
@@ -173,6 +176,13 @@ create index idx_script_hash
     on smartcontract_info(script_hash);
 
 
+create table tx_disassembly
+(
+    txid        char(66)   not null primary key,
+    disassembly mediumtext not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+
 create table nep5
 (
     id                int unsigned auto_increment primary key,
@@ -238,6 +248,15 @@ create index idx_nep5_tx_txid
     on nep5_tx(txid);
 
 
+create table nep5_decimals_repair
+(
+    asset_id    char(40)         not null primary key,
+    decimals    tinyint unsigned not null,
+    max_tx_pk   int unsigned     not null,
+    repaired_at bigint unsigned  not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+
 create table nep5_migrate
 (
     id           int unsigned auto_increment primary key,
@@ -374,11 +393,15 @@ create index idx_tx_attr_usage
 
 create table tx_claims
 (
-    id   int unsigned auto_increment primary key,
-    txid char(66)     not null,
-    vout int unsigned not null
+    id          int unsigned auto_increment primary key,
+    block_index int unsigned not null,
+    txid        char(66)     not null,
+    vout        int unsigned not null
 ) engine = InnoDB default charset = 'utf8mb4';
 
+create index idx_tx_claims_block_index
+    on tx_claims(block_index);
+
 create index idx_tx_claims_txid
     on tx_claims(txid);
 
@@ -848,3 +871,41 @@ create table addr_gas_balance_9
 
 create index `idx_address_date`
     on `addr_gas_balance_9`(`address`, `date`);
+
+create table watch_address
+(
+    address    varchar(128)  not null primary key,
+    asset_ids  varchar(1024) not null,
+    webhook    varchar(255)  not null,
+    created_at bigint        not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create table watch_event
+(
+    id              int unsigned auto_increment primary key,
+    address         varchar(128)    not null,
+    asset_type      varchar(8)      not null,
+    asset_id        varchar(66)     not null,
+    token_id        varchar(255)    not null,
+    txid            char(66)        not null,
+    block_index     int unsigned    not null,
+    block_time      bigint unsigned not null,
+    `from`          varchar(128)    not null,
+    `to`            varchar(128)    not null,
+    value           varchar(100)    not null,
+    reverts         int unsigned    not null,
+    webhook         varchar(255)    not null,
+    attempts        int             not null,
+    next_attempt_at bigint          not null,
+    delivered_at    bigint          not null,
+    last_error      varchar(255)    not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_watch_event_pending
+    on watch_event(delivered_at, next_attempt_at);
+
+create index idx_watch_event_address
+    on watch_event(address);
+
+create index idx_watch_event_block_index
+    on watch_event(block_index);
@@ -0,0 +1,138 @@
+/*
+    Tables of Neo N3 chains, created by schema migration 4 alongside the Neo Legacy tables.
+    Only one of them is filled, depending on config 'network'.
+*/
+
+
+create table n3_block
+(
+    id                  int unsigned auto_increment primary key,
+    hash                char(66)        not null,
+    size                int             not null,
+    version             int unsigned    not null,
+    previousblockhash   char(66)        not null,
+    merkleroot          char(66)        not null,
+    time                bigint unsigned not null,
+    `index`             int unsigned    not null,
+    nonce               char(16)        not null,
+    `primary`           int             not null,
+    nextconsensus       char(34)        not null,
+    script_invocation   text            not null,
+    script_verification text            not null,
+    tx_count            int unsigned    not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_n3_block_hash
+    on n3_block(hash);
+
+create unique index idx_n3_block_index
+    on n3_block(`index`);
+
+
+create table n3_tx
+(
+    id                int unsigned auto_increment primary key,
+    block_index       int unsigned    not null,
+    block_time        bigint unsigned not null,
+    hash              char(66)        not null,
+    size              int unsigned    not null,
+    version           int unsigned    not null,
+    nonce             bigint unsigned not null,
+    sender            varchar(128)    not null,
+    sys_fee           bigint          not null,
+    net_fee           bigint          not null,
+    valid_until_block int unsigned    not null,
+    script            longtext        not null,
+    vm_state          varchar(16)     not null,
+    exception         text            not null,
+    gas_consumed      bigint          not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create unique index idx_n3_tx_hash
+    on n3_tx(hash);
+
+create index idx_n3_tx_block_index
+    on n3_tx(block_index);
+
+create index idx_n3_tx_sender
+    on n3_tx(sender);
+
+
+create table n3_signer
+(
+    id                int unsigned auto_increment primary key,
+    txid              char(66)    not null,
+    account           char(42)    not null,
+    scopes            varchar(64) not null,
+    allowed_contracts text        not null,
+    allowed_groups    text        not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_n3_signer_txid
+    on n3_signer(txid);
+
+create index idx_n3_signer_account
+    on n3_signer(account);
+
+
+create table n3_witness
+(
+    id           int unsigned auto_increment primary key,
+    txid         char(66)     not null,
+    n            int unsigned not null,
+    invocation   text         not null,
+    verification text         not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_n3_witness_txid
+    on n3_witness(txid);
+
+
+create table n3_transfer
+(
+    id          int unsigned auto_increment primary key,
+    txid        char(66)        not null,
+    notify_idx  int unsigned    not null,
+    block_index int unsigned    not null,
+    block_time  bigint unsigned not null,
+    contract    char(42)        not null,
+    standard    varchar(16)     not null,
+    `from`      varchar(128)    not null,
+    `to`        varchar(128)    not null,
+    amount      decimal(65, 0)  not null,
+    token_id    varchar(256)    not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_n3_transfer_txid
+    on n3_transfer(txid);
+
+create index idx_n3_transfer_contract
+    on n3_transfer(contract);
+
+create index idx_n3_transfer_from
+    on n3_transfer(`from`);
+
+create index idx_n3_transfer_to
+    on n3_transfer(`to`);
+
+create index idx_n3_transfer_block_index
+    on n3_transfer(block_index);
+
+
+create table n3_contract
+(
+    id          int unsigned auto_increment primary key,
+    hash        char(42)        not null,
+    event       varchar(16)     not null,
+    txid        char(66)        not null,
+    block_index int unsigned    not null,
+    block_time  bigint unsigned not null,
+    name        varchar(256)    not null,
+    standards   varchar(256)    not null
+) engine = InnoDB default charset = 'utf8mb4';
+
+create index idx_n3_contract_hash
+    on n3_contract(hash);
+
+create index idx_n3_contract_block_index
+    on n3_contract(block_index);
@@ -0,0 +1,137 @@
+/*
+    PostgreSQL translation of create_table_n3.sql.
+*/
+
+
+create table n3_block
+(
+    id                  bigserial   primary key,
+    hash                varchar(66) not null,
+    size                integer     not null,
+    version             bigint      not null,
+    previousblockhash   varchar(66) not null,
+    merkleroot          varchar(66) not null,
+    time                bigint      not null,
+    "index"             bigint      not null,
+    nonce               varchar(16) not null,
+    "primary"           integer     not null,
+    nextconsensus       varchar(34) not null,
+    script_invocation   text        not null,
+    script_verification text        not null,
+    tx_count            bigint      not null
+);
+
+create index idx_n3_block_hash
+    on n3_block(hash);
+
+create unique index idx_n3_block_index
+    on n3_block("index");
+
+
+create table n3_tx
+(
+    id                bigserial    primary key,
+    block_index       bigint       not null,
+    block_time        bigint       not null,
+    hash              varchar(66)  not null,
+    size              bigint       not null,
+    version           bigint       not null,
+    nonce             bigint       not null,
+    sender            varchar(128) not null,
+    sys_fee           bigint       not null,
+    net_fee           bigint       not null,
+    valid_until_block bigint       not null,
+    script            text         not null,
+    vm_state          varchar(16)  not null,
+    exception         text         not null,
+    gas_consumed      bigint       not null
+);
+
+create unique index idx_n3_tx_hash
+    on n3_tx(hash);
+
+create index idx_n3_tx_block_index
+    on n3_tx(block_index);
+
+create index idx_n3_tx_sender
+    on n3_tx(sender);
+
+
+create table n3_signer
+(
+    id                bigserial   primary key,
+    txid              varchar(66) not null,
+    account           varchar(42) not null,
+    scopes            varchar(64) not null,
+    allowed_contracts text        not null,
+    allowed_groups    text        not null
+);
+
+create index idx_n3_signer_txid
+    on n3_signer(txid);
+
+create index idx_n3_signer_account
+    on n3_signer(account);
+
+
+create table n3_witness
+(
+    id           bigserial   primary key,
+    txid         varchar(66) not null,
+    n            bigint      not null,
+    invocation   text        not null,
+    verification text        not null
+);
+
+create index idx_n3_witness_txid
+    on n3_witness(txid);
+
+
+create table n3_transfer
+(
+    id          bigserial      primary key,
+    txid        varchar(66)    not null,
+    notify_idx  bigint         not null,
+    block_index bigint         not null,
+    block_time  bigint         not null,
+    contract    varchar(42)    not null,
+    standard    varchar(16)    not null,
+    "from"      varchar(128)   not null,
+    "to"        varchar(128)   not null,
+    amount      numeric(78, 0) not null,
+    token_id    varchar(256)   not null
+);
+
+create index idx_n3_transfer_txid
+    on n3_transfer(txid);
+
+create index idx_n3_transfer_contract
+    on n3_transfer(contract);
+
+create index idx_n3_transfer_from
+    on n3_transfer("from");
+
+create index idx_n3_transfer_to
+    on n3_transfer("to");
+
+create index idx_n3_transfer_block_index
+    on n3_transfer(block_index);
+
+
+create table n3_contract
+(
+    id          bigserial    primary key,
+    hash        varchar(42)  not null,
+    event       varchar(16)  not null,
+    txid        varchar(66)  not null,
+    block_index bigint       not null,
+    block_time  bigint       not null,
+    name        varchar(256) not null,
+    standards   varchar(256) not null
+);
+
+create index idx_n3_contract_hash
+    on n3_contract(hash);
+
+create index idx_n3_contract_block_index
+    on n3_contract(block_index);
@@ -0,0 +1,911 @@
+/*
+    PostgreSQL translation of create_table.sql.
+    Tables are created by `squirrel migrate`, only the databases have to be prepared manually:
+
+    createdb mainnet
+    createdb testnet
+
+    MySQL CHAR columns are declared as VARCHAR, because PostgreSQL pads CHAR values with spaces.
+*/
+
+
+create table addr_asset
+(
+    id                    bigserial       primary key,
+    address               varchar(128)    not null,
+    asset_id              varchar(66)     not null,
+    balance               numeric(64, 22) not null,
+    transactions          bigint          not null,
+    last_transaction_time bigint          not null
+);
+
+create index addr_asset_asset_id_balance_index
+    on addr_asset(asset_id, balance);
+
+create unique index addr_asset_address_asset_id_uindex
+    on addr_asset(address, asset_id);
+
+
+create table addr_tx
+(
+    id         bigserial    primary key,
+    txid       varchar(66)  not null,
+    address    varchar(128) not null,
+    block_time bigint       not null,
+    asset_type varchar(16)  not null
+);
+
+create unique index addr_tx_address_asset_type_txid_uindex
+    on addr_tx(address, asset_type, txid);
+
+create index addr_tx_txid
+    on addr_tx(txid);
+
+create index addr_tx_address
+    on addr_tx(address);
+
+
+create table address
+(
+    id                    bigserial    primary key,
+    address               varchar(128) not null,
+    created_at            bigint       not null,
+    last_transaction_time bigint       not null,
+    trans_asset           bigint       not null,
+    trans_nep5            bigint       default 0 not null,
+    trans_nft             bigint       default 0 not null
+);
+
+create unique index uk_address
+    on address(address);
+
+
+create table asset
+(
+    id           bigserial      primary key,
+    block_index  bigint         not null,
+    block_time   bigint         not null,
+    version      bigint         not null,
+    asset_id     varchar(66)    not null,
+    type         varchar(32)    not null,
+    name         varchar(256)   not null,
+    amount       numeric(35, 8) not null,
+    available    numeric(35, 8) not null,
+    "precision"  smallint       not null,
+    owner        varchar(66)    not null,
+    admin        varchar(34)    not null,
+    issuer       varchar(66)    not null,
+    expiration   bigint         not null,
+    frozen       boolean        not null,
+    addresses    bigint         not null,
+    transactions bigint         not null,
+    visible      boolean        default true not null
+);
+
+create index idx_asset_asset_id
+    on asset(asset_id);
+
+create index idx_asset_time
+    on asset(block_time);
+
+
+create table asset_tx
+(
+    id       bigserial   primary key,
+    address  varchar(34) not null,
+    asset_id varchar(66) not null,
+    txid     varchar(66) not null
+);
+
+create index idx_asset_tx_address_asset_id
+    on asset_tx(address, asset_id);
+
+create unique index idx_asset_tx_address_asset_id_txid
+    on asset_tx(address, asset_id, txid);
+
+
+create table block
+(
+    id                  bigserial   primary key,
+    hash                varchar(66) not null,
+    size                integer     not null,
+    version             bigint      not null,
+    previousblockhash   varchar(66) not null,
+    merkleroot          varchar(66) not null,
+    time                bigint      not null,
+    "index"             bigint      not null,
+    nonce               varchar(16) not null,
+    nextconsensus       varchar(34) not null,
+    script_invocation   text        not null,
+    script_verification text        not null,
+    nextblockhash       varchar(66) not null
+);
+
+create index idx_block_hash
+    on block(hash);
+
+create unique index idx_block_index
+    on block("index");
+
+create index idx_block_time
+    on block(time);
+
+
+create table counter
+(
+    id                     bigserial primary key,
+    last_block_index       integer   not null,
+    last_tx_pk             bigint    not null,
+    last_asset_tx_pk       bigint    not null,
+    last_tx_pk_for_nep5    bigint    not null,
+    app_log_idx            integer   not null,
+    last_tx_pk_for_nft     bigint    not null,
+    nft_app_log_idx        integer   not null,
+    last_tx_pk_for_sc      bigint    not null,
+    nep5_tx_pk_for_addr_tx bigint    not null,
+    nft_tx_pk_for_addr_tx  bigint    not null,
+    last_tx_pk_gas_balance bigint    not null,
+    cnt_addr               bigint    not null,
+    cnt_tx_reg             bigint    not null,
+    cnt_tx_miner           bigint    not null,
+    cnt_tx_issue           bigint    not null,
+    cnt_tx_invocation      bigint    not null,
+    cnt_tx_contract        bigint    not null,
+    cnt_tx_claim           bigint    not null,
+    cnt_tx_publish         bigint    not null,
+    cnt_tx_enrollment      bigint    not null
+);
+
+
+create table smartcontract_info
+(
+    id             bigserial     primary key,
+    txid           varchar(66)   not null,
+    script_hash    varchar(40)   not null,
+    name           varchar(255)  not null,
+    version        varchar(255)  not null,
+    author         varchar(255)  not null,
+    email          varchar(255)  not null,
+    description    varchar(2048) not null,
+    need_storage   boolean       not null,
+    parameter_list varchar(255)  not null,
+    return_type    varchar(255)  not null
+);
+
+create index idx_script_hash
+    on smartcontract_info(script_hash);
+
+
+create table tx_disassembly
+(
+    txid        varchar(66) not null primary key,
+    disassembly text        not null
+);
+
+
+create table nep5
+(
+    id                bigserial       primary key,
+    asset_id          varchar(40)     not null,
+    admin_address     varchar(40)     not null,
+    name              varchar(128)    not null,
+    symbol            varchar(16)     not null,
+    decimals          smallint        not null,
+    total_supply      numeric(64, 22) not null,
+    txid              varchar(66)     not null,
+    block_index       bigint          not null,
+    block_time        bigint          not null,
+    addresses         bigint          not null,
+    holding_addresses bigint          not null,
+    transfers         bigint          not null,
+    visible           boolean         default true not null
+);
+
+create index idx_nep5_txid
+    on nep5(txid);
+
+
+create table nep5_reg_info
+(
+    id             bigserial     primary key,
+    nep5_id        bigint        not null,
+    name           varchar(255)  not null,
+    version        varchar(255)  not null,
+    author         varchar(255)  not null,
+    email          varchar(255)  not null,
+    description    varchar(1024) not null,
+    need_storage   boolean       not null,
+    parameter_list varchar(255)  not null,
+    return_type    varchar(255)  not null
+);
+
+create index idx_nep5_id
+    on nep5_reg_info(nep5_id);
+
+
+create table nep5_tx
+(
+    id          bigserial       primary key,
+    txid        varchar(66)     not null,
+    asset_id    varchar(40)     not null,
+    "from"      varchar(128)    not null,
+    "to"        varchar(128)    not null,
+    value       numeric(64, 22) not null,
+    block_index bigint          not null,
+    block_time  bigint          not null
+);
+
+create index idx_nep5_tx_asset_id
+    on nep5_tx(asset_id);
+
+create index idx_nep5_tx_from
+    on nep5_tx("from");
+
+create index idx_nep5_tx_to
+    on nep5_tx("to");
+
+create index idx_nep5_tx_txid
+    on nep5_tx(txid);
+
+
+create table nep5_decimals_repair
+(
+    asset_id    varchar(40) not null primary key,
+    decimals    smallint    not null,
+    max_tx_pk   bigint      not null,
+    repaired_at bigint      not null
+);
+
+
+create table nep5_migrate
+(
+    id           bigserial   primary key,
+    old_asset_id varchar(40) not null,
+    new_asset_id varchar(40) not null,
+    migrate_txid varchar(66) not null
+);
+
+
+create table nft
+(
+    id                bigserial      primary key,
+    asset_id          varchar(40)    not null,
+    admin_address     varchar(40)    not null,
+    name              varchar(128)   not null,
+    symbol            varchar(16)    not null,
+    decimals          smallint       not null,
+    total_supply      numeric(35, 8) not null,
+    txid              varchar(66)    not null,
+    block_index       bigint         not null,
+    block_time        bigint         not null,
+    addresses         bigint         not null,
+    holding_addresses bigint         not null,
+    transfers         bigint         not null,
+    visible           boolean        default true not null
+);
+
+create index idx_nft_txid
+    on nft(txid);
+
+
+create table nft_reg_info
+(
+    id             bigserial    primary key,
+    nft_id         bigint       not null,
+    name           varchar(255) not null,
+    version        varchar(255) not null,
+    author         varchar(255) not null,
+    email          varchar(255) not null,
+    description    varchar(255) not null,
+    need_storage   boolean      not null,
+    parameter_list varchar(255) not null,
+    return_type    varchar(255) not null
+);
+
+create index idx_nft_id
+    on nft_reg_info(nft_id);
+
+
+create table nft_tx
+(
+    id          bigserial        primary key,
+    txid        varchar(66)      not null,
+    asset_id    varchar(40)      not null,
+    "from"      varchar(128)     not null,
+    "to"        varchar(128)     not null,
+    token_id    varchar(64)      not null,
+    value       double precision not null,
+    block_index bigint           not null,
+    block_time  bigint           not null
+);
+
+create index idx_nft_tx_asset_id
+    on nft_tx(asset_id);
+
+create index idx_nft_tx_from
+    on nft_tx("from");
+
+create index idx_nft_tx_to
+    on nft_tx("to");
+
+create index idx_nft_tx_txid
+    on nft_tx(txid);
+
+
+create table addr_asset_nft
+(
+    id       bigserial      primary key,
+    address  varchar(128)   not null,
+    asset_id varchar(66)    not null,
+    token_id varchar(66)    not null,
+    balance  numeric(35, 8) not null
+);
+
+
+create table nft_token
+(
+    id       bigserial     primary key,
+    asset_id varchar(66)   not null,
+    token_id varchar(66)   not null,
+    info     varchar(4096) not null
+);
+
+create table tx
+(
+    id          bigserial      primary key,
+    block_index bigint         not null,
+    block_time  bigint         not null,
+    txid        varchar(66)    not null,
+    size        bigint         not null,
+    type        varchar(32)    not null,
+    version     bigint         not null,
+    sys_fee     numeric(27, 8) not null,
+    net_fee     numeric(27, 8) not null,
+    nonce       bigint         not null,
+    script      text           not null,
+    gas         numeric(27, 8) not null
+);
+
+create index idx_tx_block_index
+    on tx(block_index);
+
+create index idx_tx_txid
+    on tx(txid);
+
+create index idx_tx_type
+    on tx(type);
+
+
+create table tx_attr
+(
+    id      bigserial   primary key,
+    txid    varchar(66) not null,
+    "usage" varchar(32) not null,
+    data    text        not null
+);
+
+create index idx_tx_attr_txid
+    on tx_attr(txid);
+
+create index idx_tx_attr_usage
+    on tx_attr("usage");
+
+
+create table tx_claims
+(
+    id          bigserial   primary key,
+    block_index bigint      not null,
+    txid        varchar(66) not null,
+    vout        bigint      not null
+);
+
+create index idx_tx_claims_block_index
+    on tx_claims(block_index);
+
+create index idx_tx_claims_txid
+    on tx_claims(txid);
+
+
+create table tx_scripts
+(
+    id           bigserial   primary key,
+    txid         varchar(66) not null,
+    invocation   text        not null,
+    verification text        not null
+);
+
+create index idx_tx_scripts_txid
+    on tx_scripts(txid);
+
+
+create table tx_vin
+(
+    id     bigserial   primary key,
+    "from" varchar(66) not null,
+    txid   varchar(66) not null,
+    vout   bigint      not null
+);
+
+create index idx_tx_vin_from
+    on tx_vin("from");
+
+create index idx_tx_vin_txid
+    on tx_vin(txid);
+
+
+create table tx_vout
+(
+    id       bigserial      primary key,
+    txid     varchar(66)    not null,
+    n        bigint         not null,
+    asset_id varchar(66)    not null,
+    value    numeric(35, 8) not null,
+    address  varchar(34)    not null
+);
+
+create index idx_tx_vout_address
+    on tx_vout(address);
+
+create index idx_tx_vout_asset_id
+    on tx_vout(asset_id);
+
+create index idx_tx_vout_txid
+    on tx_vout(txid);
+
+
+create table utxo
+(
+    id         bigserial      primary key,
+    address    varchar(34)    not null,
+    txid       varchar(66)    not null,
+    n          bigint         not null,
+    asset_id   varchar(66)    not null,
+    value      numeric(35, 8) not null,
+    used_in_tx varchar(66)
+);
+
+create index idx_utxo_address
+    on utxo(address);
+
+create index idx_utxo_asset_id
+    on utxo(asset_id);
+
+create index idx_utxo_txid
+    on utxo(txid);
+
+create index idx_utxo_used_in_tx
+    on utxo(used_in_tx);
+
+create table addr_gas_balance_a
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_a_address_date"
+    on "addr_gas_balance_a"("address", "date");
+
+create table addr_gas_balance_b
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_b_address_date"
+    on "addr_gas_balance_b"("address", "date");
+
+create table addr_gas_balance_c
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_c_address_date"
+    on "addr_gas_balance_c"("address", "date");
+
+create table addr_gas_balance_d
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_d_address_date"
+    on "addr_gas_balance_d"("address", "date");
+
+create table addr_gas_balance_e
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_e_address_date"
+    on "addr_gas_balance_e"("address", "date");
+
+create table addr_gas_balance_f
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_f_address_date"
+    on "addr_gas_balance_f"("address", "date");
+
+create table addr_gas_balance_g
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_g_address_date"
+    on "addr_gas_balance_g"("address", "date");
+
+create table addr_gas_balance_h
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_h_address_date"
+    on "addr_gas_balance_h"("address", "date");
+
+create table addr_gas_balance_i
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_i_address_date"
+    on "addr_gas_balance_i"("address", "date");
+
+create table addr_gas_balance_j
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_j_address_date"
+    on "addr_gas_balance_j"("address", "date");
+
+create table addr_gas_balance_k
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_k_address_date"
+    on "addr_gas_balance_k"("address", "date");
+
+create table addr_gas_balance_l
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_l_address_date"
+    on "addr_gas_balance_l"("address", "date");
+
+create table addr_gas_balance_m
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_m_address_date"
+    on "addr_gas_balance_m"("address", "date");
+
+create table addr_gas_balance_n
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_n_address_date"
+    on "addr_gas_balance_n"("address", "date");
+
+create table addr_gas_balance_o
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_o_address_date"
+    on "addr_gas_balance_o"("address", "date");
+
+create table addr_gas_balance_p
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_p_address_date"
+    on "addr_gas_balance_p"("address", "date");
+
+create table addr_gas_balance_q
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_q_address_date"
+    on "addr_gas_balance_q"("address", "date");
+
+create table addr_gas_balance_r
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_r_address_date"
+    on "addr_gas_balance_r"("address", "date");
+
+create table addr_gas_balance_s
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_s_address_date"
+    on "addr_gas_balance_s"("address", "date");
+
+create table addr_gas_balance_t
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_t_address_date"
+    on "addr_gas_balance_t"("address", "date");
+
+create table addr_gas_balance_u
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_u_address_date"
+    on "addr_gas_balance_u"("address", "date");
+
+create table addr_gas_balance_v
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_v_address_date"
+    on "addr_gas_balance_v"("address", "date");
+
+create table addr_gas_balance_w
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_w_address_date"
+    on "addr_gas_balance_w"("address", "date");
+
+create table addr_gas_balance_x
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_x_address_date"
+    on "addr_gas_balance_x"("address", "date");
+
+create table addr_gas_balance_y
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_y_address_date"
+    on "addr_gas_balance_y"("address", "date");
+
+create table addr_gas_balance_z
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_z_address_date"
+    on "addr_gas_balance_z"("address", "date");
+
+create table addr_gas_balance_0
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_0_address_date"
+    on "addr_gas_balance_0"("address", "date");
+
+create table addr_gas_balance_1
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_1_address_date"
+    on "addr_gas_balance_1"("address", "date");
+
+create table addr_gas_balance_2
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_2_address_date"
+    on "addr_gas_balance_2"("address", "date");
+
+create table addr_gas_balance_3
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_3_address_date"
+    on "addr_gas_balance_3"("address", "date");
+
+create table addr_gas_balance_4
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_4_address_date"
+    on "addr_gas_balance_4"("address", "date");
+
+create table addr_gas_balance_5
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_5_address_date"
+    on "addr_gas_balance_5"("address", "date");
+
+create table addr_gas_balance_6
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_6_address_date"
+    on "addr_gas_balance_6"("address", "date");
+
+create table addr_gas_balance_7
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_7_address_date"
+    on "addr_gas_balance_7"("address", "date");
+
+create table addr_gas_balance_8
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_8_address_date"
+    on "addr_gas_balance_8"("address", "date");
+
+create table addr_gas_balance_9
+(
+    id      bigserial      primary key,
+    address varchar(34)    not null,
+    date    date           not null,
+    balance numeric(35, 8) not null
+);
+
+create index "idx_addr_gas_balance_9_address_date"
+    on "addr_gas_balance_9"("address", "date");
+
+create table watch_address
+(
+    address    varchar(128)  not null primary key,
+    asset_ids  varchar(1024) not null,
+    webhook    varchar(255)  not null,
+    created_at bigint        not null
+);
+
+create table watch_event
+(
+    id              bigserial      primary key,
+    address         varchar(128)   not null,
+    asset_type      varchar(8)     not null,
+    asset_id        varchar(66)    not null,
+    token_id        varchar(255)   not null,
+    txid            varchar(66)    not null,
+    block_index     bigint         not null,
+    block_time      bigint         not null,
+    "from"          varchar(128)   not null,
+    "to"            varchar(128)   not null,
+    value           varchar(100)   not null,
+    reverts         bigint         not null,
+    webhook         varchar(255)   not null,
+    attempts        integer        not null,
+    next_attempt_at bigint         not null,
+    delivered_at    bigint         not null,
+    last_error      varchar(255)   not null
+);
+
+create index idx_watch_event_pending
+    on watch_event(delivered_at, next_attempt_at);
+
+create index idx_watch_event_address
+    on watch_event(address);
+
+create index idx_watch_event_block_index
+    on watch_event(block_index);
@@ -0,0 +1,9 @@
+// Package sqls embeds the sql scripts of this directory into the binary.
+package sqls
+
+import "embed"
+
+// FS holds all sql scripts.
+//
+//go:embed *.sql
+var FS embed.FS
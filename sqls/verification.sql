@@ -1,3 +1,5 @@
+/* The checks above the separator are also run by `squirrel verify`. */
+
 /* Verify nep5 addresses */
 select
     t.asset_id,
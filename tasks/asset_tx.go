@@ -1,11 +1,11 @@
 package tasks
 
 import (
+	"context"
 	"fmt"
 	"math/big"
-	"squirrel/db"
 	"squirrel/log"
-	"squirrel/mail"
+	"squirrel/notify"
 	"squirrel/tx"
 	"time"
 )
@@ -19,23 +19,27 @@ var (
 	maxTxPKforAssetTx         uint
 )
 
-func startAssetTxTask() {
-	assetTxChan := make(chan *txInfo, assetTxChanSize)
+func startAssetTxTask(r *runner) {
+	assetTxChan := make(chan *txInfo, r.channelSize(assetTxChanSize))
+	pipeline.trackQueue("asset_tx", func() int { return len(assetTxChan) })
 
-	go fetchAssetTx(assetTxChan)
-	go handleAssetTx(assetTxChan)
+	r.goTask("fetch_asset_tx", func() { fetchAssetTx(r.ctx, assetTxChan) })
+	r.goTask("handle_asset_tx", func() { handleAssetTx(assetTxChan) })
 }
 
-func fetchAssetTx(assetTxChan chan<- *txInfo) {
-	defer mail.AlertIfErr()
+// fetchAssetTx sends transactions with their vins and vouts to assetTxChan,
+// which is closed once ctx is cancelled.
+func fetchAssetTx(ctx context.Context, assetTxChan chan<- *txInfo) {
+	defer close(assetTxChan)
+	defer notify.AlertIfErr()
 
-	nextPK := db.GetLastAssetTxPkCounter() + 1
+	nextPK := storage.GetLastAssetTxPkCounter() + 1
 
-	for {
-		txs := db.GetTxs(nextPK, 50, "")
+	for ctx.Err() == nil {
+		txs := storage.GetTxs(nextPK, 50, "")
 		if len(txs) == 0 {
 			// log.Printf("Waiting for new transactions...\n")
-			time.Sleep(2 * time.Second)
+			sleep(ctx, 2*time.Second)
 			continue
 		}
 
@@ -46,30 +50,40 @@ func fetchAssetTx(assetTxChan chan<- *txInfo) {
 			txIDs = append(txIDs, tx.TxID)
 		}
 
-		vinMap, voutMap, err := db.GetVinVout(txIDs)
+		vinMap, voutMap, err := storage.GetVinVout(txIDs)
 		if err != nil {
 			panic(err)
 		}
 
+		// Transactions not sent are fetched again on next start.
 		for _, tx := range txs {
-			assetTxChan <- &txInfo{
+			select {
+			case assetTxChan <- &txInfo{
 				tx:    tx,
 				vins:  vinMap[tx.TxID],
 				vouts: voutMap[tx.TxID],
+			}:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}
 }
 
+// handleAssetTx records transactions from assetTxChan until it is closed.
 func handleAssetTx(assetTxChan <-chan *txInfo) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	records := []tx.AddrAssetIDTx{}
 	maxPK := uint64(0)
 
 	for {
 		select {
-		case t := <-assetTxChan:
+		case t, ok := <-assetTxChan:
+			if !ok {
+				recordAddrAssetIDTx(records, int64(maxPK))
+				return
+			}
 			maxPK = uint64(t.tx.ID)
 			records = processAssetTx(records, t)
 		case <-time.After(2 * time.Second):
@@ -89,7 +103,7 @@ func processAssetTx(records []tx.AddrAssetIDTx, t *txInfo) []tx.AddrAssetIDTx {
 			if vin == nil {
 				continue
 			}
-			vinVout, err := db.GetVout(vin.TxID, vin.Vout)
+			vinVout, err := storage.GetVout(vin.TxID, vin.Vout)
 			if err != nil {
 				panic(err)
 			}
@@ -144,7 +158,7 @@ func recordAddrAssetIDTx(records []tx.AddrAssetIDTx, maxPK int64) {
 		return
 	}
 
-	err := db.RecordAddrAssetIDTx(records, maxPK)
+	err := storage.RecordAddrAssetIDTx(records, maxPK)
 	if err != nil {
 		panic(err)
 	}
@@ -155,8 +169,9 @@ func recordAddrAssetIDTx(records []tx.AddrAssetIDTx, maxPK int64) {
 func showAssetTxProgress(currentTxPk uint) {
 	if maxTxPKforAssetTx == 0 || AssetTxMaxPkShouldRefresh {
 		AssetTxMaxPkShouldRefresh = false
-		maxTxPKforAssetTx = db.GetHighestTxPk()
+		maxTxPKforAssetTx = storage.GetHighestTxPk()
 	}
+	pipeline.setPk("asset_tx", currentTxPk, maxTxPKforAssetTx)
 
 	now := time.Now()
 	if assetProgress.LastOutputTime == (time.Time{}) {
@@ -190,6 +205,6 @@ func showAssetTxProgress(currentTxPk uint) {
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", assetProgress.InitTime, time.Now())
 
-		mail.SendNotify("Transactions Fully Synced", msg)
+		notify.Send("Transactions Fully Synced", msg)
 	}
 }
@@ -1,20 +1,26 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"squirrel/block"
 	"squirrel/buffer"
-	"squirrel/db"
+	"squirrel/config"
 	"squirrel/log"
-	"squirrel/mail"
+	"squirrel/notify"
 	"squirrel/rpc"
 	"squirrel/tx"
 	"time"
 )
 
-// bufferSize is the capacity of pending blocks waiting to be persisted to db.
-const bufferSize = 5000
+const (
+	// bufferSize is the default capacity of pending blocks waiting to be persisted to db.
+	bufferSize = 5000
+	// blockBatchSize is the number of blocks fetched per rpc request while far behind the chain.
+	blockBatchSize = 20
+)
 
 var (
 	// bestRPCHeight util.SafeCounter.
@@ -24,7 +30,24 @@ var (
 	blockChannel chan *rpc.RawBlock
 )
 
-func fetchBlock() {
+// startBlockTask fetches and stores blocks after height.
+func startBlockTask(r *runner, height int) {
+	blockBuffer = buffer.NewBuffer(height)
+	size := r.channelSize(bufferSize)
+	blockChannel = make(chan *rpc.RawBlock, size)
+	pipeline.trackQueue("block_buffer", blockBuffer.Size)
+	pipeline.trackQueue("block_channel", func() int { return len(blockChannel) })
+
+	for i := 0; i < r.workers(config.GetGoroutines()); i++ {
+		r.goWorker(func() { fetchBlock(r.ctx, size) })
+	}
+	rewinds := make(chan int)
+	r.goTask("arrange_block", func() { arrangeBlock(r.ctx, height, blockChannel, rewinds) })
+	r.goTask("store_block", func() { storeBlock(r.ctx, height, blockChannel, rewinds) })
+}
+
+// fetchBlock puts blocks into blockBuffer while it holds less than maxBuffered blocks.
+func fetchBlock(ctx context.Context, maxBuffered int) {
 	worker.add()
 	log.Printf("Create new worker to fetch blocks\n")
 
@@ -36,11 +59,16 @@ func fetchBlock() {
 		log.Printf("%s. Remaining workers=%d\n", hint, worker.num())
 	}()
 
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	for {
+		if ctx.Err() != nil {
+			worker.remove()
+			return
+		}
+
 		// Control size of the blockBuffer.
-		if blockBuffer.Size() > bufferSize {
+		if blockBuffer.Size() > maxBuffered {
 			time.Sleep(time.Millisecond * 20)
 			continue
 		}
@@ -55,7 +83,35 @@ func fetchBlock() {
 			// }
 		}
 
-		b := rpc.DownloadBlock(nextHeight)
+		// Far behind the chain, fetch blocks in batches.
+		if nextHeight+blockBatchSize <= rpc.BestHeight.Get() {
+			blocks, err := rpc.DownloadBlocks(ctx, nextBatch(nextHeight))
+			if err != nil && ctx.Err() == nil {
+				log.With(log.Fields{"task": "block", "height": nextHeight}).Errorf("Failed to download blocks: %v\n", err)
+			}
+			for _, b := range blocks {
+				if b != nil {
+					blockBuffer.Put(b)
+				}
+			}
+
+			waited = 0
+			nextHeight = nextPending()
+			continue
+		}
+
+		b, err := rpc.DownloadBlock(ctx, nextHeight)
+		if ctx.Err() != nil {
+			worker.remove()
+			return
+		}
+
+		// Missing blocks are fetched again by arrangeBlock.
+		if err != nil && !errors.Is(err, rpc.ErrNotFound) {
+			log.With(log.Fields{"task": "block", "height": nextHeight}).Errorf("Failed to download block: %v\n", err)
+			nextHeight = nextPending()
+			continue
+		}
 
 		// Beyond the latest block.
 		if b == nil {
@@ -71,25 +127,65 @@ func fetchBlock() {
 
 		waited = 0
 		blockBuffer.Put(b)
+		nextHeight = nextPending()
+	}
+}
 
-		if worker.num() == 1 {
-			nextHeight = blockBuffer.GetHighest() + 1
-		} else {
-			nextHeight = blockBuffer.GetNextPending()
-		}
+// nextPending returns the next block index the current worker should fetch.
+func nextPending() int {
+	if worker.num() == 1 {
+		return blockBuffer.GetHighest() + 1
+	}
+	return blockBuffer.GetNextPending()
+}
+
+// nextBatch returns indexes of a batch of blocks starting at nextHeight.
+// Other indexes are reserved from the buffer while several workers are fetching.
+func nextBatch(nextHeight int) []int {
+	if worker.num() > 1 {
+		return append([]int{nextHeight}, blockBuffer.GetNextPendings(blockBatchSize-1)...)
+	}
+
+	indexes := make([]int, blockBatchSize)
+	for i := range indexes {
+		indexes[i] = nextHeight + i
 	}
+	return indexes
 }
 
-func arrangeBlock(dbHeight int, queue chan<- *rpc.RawBlock) {
-	defer mail.AlertIfErr()
+// arrangeBlock pushes blocks in order into queue, and closes it once ctx is cancelled.
+// On rewinds it drops buffered blocks and pushes nil, then continues after the fork point.
+func arrangeBlock(ctx context.Context, dbHeight int, queue chan<- *rpc.RawBlock, rewinds <-chan int) {
+	defer close(queue)
+	defer notify.AlertIfErr()
 
 	const sleepTime = 20
 	height := dbHeight + 1
 	delay := 0
 
 	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fork := <-rewinds:
+			blockBuffer.Reset(fork)
+			height = fork + 1
+			delay = 0
+
+			select {
+			case queue <- nil:
+			case <-ctx.Done():
+				return
+			}
+		default:
+		}
+
 		if b, ok := blockBuffer.Pop(height); ok {
-			queue <- b
+			select {
+			case queue <- b:
+			case <-ctx.Done():
+				return
+			}
 			height++
 			delay = 0
 			continue
@@ -106,30 +202,58 @@ func arrangeBlock(dbHeight int, queue chan<- *rpc.RawBlock) {
 		}
 
 		if delay%(1000*10) == 0 {
-			err := fmt.Errorf("block height %d is missing while downloading blocks", height)
-			log.Println(err)
+			log.With(log.Fields{"task": "block", "height": height}).Warnf("Block is missing while downloading blocks\n")
 
-			getMissingBlock(height)
+			getMissingBlock(ctx, height)
 		}
 	}
 }
 
-func getMissingBlock(height int) {
-	log.Printf("Try fetching given block of height: %d\n", height)
+func getMissingBlock(ctx context.Context, height int) {
+	entry := log.With(log.Fields{"task": "block", "height": height})
+	entry.Infof("Try fetching missing block\n")
 
-	b := rpc.DownloadBlock(height)
-	if b != nil {
-		blockBuffer.Put(b)
+	b, err := rpc.DownloadBlock(ctx, height)
+	if err != nil {
+		entry.Warnf("Failed to fetch missing block: %v\n", err)
+		return
 	}
+
+	blockBuffer.Put(b)
 }
 
-func storeBlock(ch <-chan *rpc.RawBlock) {
-	defer mail.AlertIfErr()
+// storeBlock persists blocks from ch until it is closed.
+func storeBlock(ctx context.Context, dbHeight int, ch <-chan *rpc.RawBlock, rewinds chan<- int) {
+	defer notify.AlertIfErr()
 
 	const size = 15
 	rawBlocks := []*rpc.RawBlock{}
+	chain := newBlockChain(dbHeight, rewinds)
 
 	for block := range ch {
+		// Stale block arranged before the last rewind.
+		if int(block.Index) != chain.nextIndex {
+			continue
+		}
+
+		if !chain.links(block) {
+			if len(rawBlocks) > 0 {
+				store(rawBlocks)
+				rawBlocks = nil
+			}
+
+			// Blocks after the fork will be synchronized on next start.
+			if ctx.Err() != nil {
+				return
+			}
+
+			if !chain.rewind(ctx, ch) {
+				break
+			}
+			continue
+		}
+
+		chain.append(block)
 		rawBlocks = append(rawBlocks, block)
 		if block.Index%size == 0 ||
 			int(block.Index) == blockBuffer.GetHighest() {
@@ -137,6 +261,12 @@ func storeBlock(ch <-chan *rpc.RawBlock) {
 			rawBlocks = nil
 		}
 	}
+
+	if len(rawBlocks) > 0 {
+		store(rawBlocks)
+	}
+
+	log.Printf("Block storage stopped at height %d\n", chain.nextIndex-1)
 }
 
 func store(rawBlocks []*rpc.RawBlock) {
@@ -144,10 +274,11 @@ func store(rawBlocks []*rpc.RawBlock) {
 	blocks := block.ParseBlocks(rawBlocks)
 	txBulk := tx.ParseTxs(rawBlocks)
 
-	err := db.InsertBlock(maxIndex, blocks, txBulk)
+	err := storage.InsertBlock(maxIndex, blocks, txBulk)
 	if err != nil {
 		panic(err)
 	}
+	pipeline.setHeight("block", uint(maxIndex))
 
 	// Auxiliary signal for tx task.
 	TxMaxPkShouldRefresh = true
@@ -203,6 +334,6 @@ func showBlockStorageProgress(maxIndex int64, highestIndex int64) {
 		}
 
 		msg := fmt.Sprintf("Block counts: %d", highestIndex)
-		mail.SendNotify("Block data Fully Synced", msg)
+		notify.Send("Block data Fully Synced", msg)
 	}
 }
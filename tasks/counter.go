@@ -1,64 +1,66 @@
 package tasks
 
 import (
-	"squirrel/db"
-	"squirrel/mail"
+	"context"
+	"squirrel/notify"
 	"time"
 )
 
-func startUpdateCounterTask() {
-	go insertNep5AddrTxRecord()
-	go insertNftAddrTxRecord()
+func startUpdateCounterTask(r *runner) {
+	r.goTask("nep5_addr_tx", func() { insertNep5AddrTxRecord(r.ctx) })
+	r.goTask("nft_addr_tx", func() { insertNftAddrTxRecord(r.ctx) })
 }
 
-func insertNep5AddrTxRecord() {
-	defer mail.AlertIfErr()
+func insertNep5AddrTxRecord(ctx context.Context) {
+	defer notify.AlertIfErr()
 
-	lastPk := db.GetNep5TxPkForAddrTx()
+	lastPk := storage.GetNep5TxPkForAddrTx()
 
-	for {
-		Nep5TxRecs, err := db.GetNep5TxRecords(lastPk, 1000)
+	for ctx.Err() == nil {
+		Nep5TxRecs, err := storage.GetNep5TxRecords(lastPk, 1000)
 		if err != nil {
 			panic(err)
 		}
 
 		if len(Nep5TxRecs) > 0 {
 			lastPk = Nep5TxRecs[len(Nep5TxRecs)-1].ID
-			err = db.InsertNep5AddrTxRec(Nep5TxRecs, lastPk)
+			err = storage.InsertNep5AddrTxRec(Nep5TxRecs, lastPk)
 			if err != nil {
 				panic(err)
 			}
+			pipeline.setPk("nep5_addr_tx", lastPk, 0)
 
 			time.Sleep(time.Millisecond * 10)
 			continue
 		}
 
-		time.Sleep(time.Second)
+		sleep(ctx, time.Second)
 	}
 }
 
-func insertNftAddrTxRecord() {
-	defer mail.AlertIfErr()
+func insertNftAddrTxRecord(ctx context.Context) {
+	defer notify.AlertIfErr()
 
-	lastPk := db.GetNftTxPkForAddrTx()
+	lastPk := storage.GetNftTxPkForAddrTx()
 
-	for {
-		NftTxRecs, err := db.GetNftTxRecords(lastPk, 1000)
+	for ctx.Err() == nil {
+		NftTxRecs, err := storage.GetNftTxRecords(lastPk, 1000)
 		if err != nil {
 			panic(err)
 		}
 
 		if len(NftTxRecs) > 0 {
 			lastPk = NftTxRecs[len(NftTxRecs)-1].ID
-			err = db.InsertNftAddrTxRec(NftTxRecs, lastPk)
+			err = storage.InsertNftAddrTxRec(NftTxRecs, lastPk)
 			if err != nil {
 				panic(err)
 			}
+			pipeline.setPk("nft_addr_tx", lastPk, 0)
 
 			time.Sleep(time.Millisecond * 10)
 			continue
 		}
 
-		time.Sleep(time.Second)
+		sleep(ctx, time.Second)
 	}
 }
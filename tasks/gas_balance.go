@@ -3,10 +3,9 @@ package tasks
 import (
 	"fmt"
 	"math/big"
-	"squirrel/asset"
-	"squirrel/db"
+	"squirrel/config"
 	"squirrel/log"
-	"squirrel/mail"
+	"squirrel/notify"
 	"time"
 )
 
@@ -18,26 +17,31 @@ var (
 	maxTxPkForGas         uint
 )
 
-func startGasBalanceTask() {
-	gasBalanceChan := make(chan txInfo, gasBalanceChainSize)
-	nextPK := db.GetLastTxPkForGasBalance() + 1
+func startGasBalanceTask(r *runner) {
+	gasBalanceChan := make(chan txInfo, r.channelSize(gasBalanceChainSize))
+	nextPK := storage.GetLastTxPkForGasBalance() + 1
+	pipeline.trackQueue("gas_balance", func() int { return len(gasBalanceChan) })
 
-	go fetchTx(gasBalanceChan, nextPK)
-	go handleTxGASBalance(gasBalanceChan)
+	r.goTask("fetch_gas_balance", func() { fetchTx(r.ctx, gasBalanceChan, nextPK) })
+	r.goTask("handle_gas_balance", func() { handleTxGASBalance(gasBalanceChan) })
 }
 
 func handleTxGASBalance(gasBalanceChan <-chan txInfo) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
+
+	// Profile changes require a restart.
+	gasAssetID := config.GetProfile().GASAssetID
 
 	for info := range gasBalanceChan {
-		gasChangeMap := getGASChange(info)
+		gasChangeMap := getGASChange(info, gasAssetID)
 
 		if len(gasChangeMap) == 0 {
+			pipeline.setPk("gas_balance", info.tx.ID, 0)
 			continue
 		}
 
 		date := time.Unix(int64(info.tx.BlockTime), 0)
-		err := db.ApplyGASAssetChange(info.tx, date.Format("2006-01-02"), gasChangeMap)
+		err := storage.ApplyGASAssetChange(info.tx, date.Format("2006-01-02"), gasChangeMap)
 		if err != nil {
 			panic(err)
 		}
@@ -46,7 +50,7 @@ func handleTxGASBalance(gasBalanceChan <-chan txInfo) {
 	}
 }
 
-func getGASChange(info txInfo) map[string]*big.Float {
+func getGASChange(info txInfo, gasAssetID string) map[string]*big.Float {
 	vins := info.vins
 	vouts := info.vouts
 
@@ -57,7 +61,7 @@ func getGASChange(info txInfo) map[string]*big.Float {
 	gasMap := make(map[string]*big.Float)
 
 	for _, vin := range vins {
-		vinVout, err := db.GetVout(vin.TxID, vin.Vout)
+		vinVout, err := storage.GetVout(vin.TxID, vin.Vout)
 		if err != nil {
 			panic(err)
 		}
@@ -65,14 +69,14 @@ func getGASChange(info txInfo) map[string]*big.Float {
 			continue
 		}
 
-		if vinVout.AssetID == asset.GASAssetID {
+		if vinVout.AssetID == gasAssetID {
 			negAmount := new(big.Float).SetPrec(256).Neg(vinVout.Value)
 			updateMapValue(gasMap, vinVout.Address, negAmount)
 		}
 	}
 
 	for _, vout := range vouts {
-		if vout.AssetID == asset.GASAssetID {
+		if vout.AssetID == gasAssetID {
 			updateMapValue(gasMap, vout.Address, vout.Value)
 		}
 	}
@@ -97,8 +101,9 @@ func updateMapValue(mp map[string]*big.Float, key string, offset *big.Float) {
 func showGasDateBalanceProgress(currentTxPK uint) {
 	if maxTxPkForGas == 0 || gasMaxPkShouldRefresh {
 		gasMaxPkShouldRefresh = false
-		maxTxPkForGas = db.GetHighestTxPk()
+		maxTxPkForGas = storage.GetHighestTxPk()
 	}
+	pipeline.setPk("gas_balance", currentTxPK, maxTxPkForGas)
 
 	now := time.Now()
 	if gasProgress.LastOutputTime == (time.Time{}) {
@@ -132,6 +137,6 @@ func showGasDateBalanceProgress(currentTxPK uint) {
 		}
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", gasProgress.InitTime, time.Now())
-		mail.SendNotify("Addr-Date-Gas Fully Synced", msg)
+		notify.Send("Addr-Date-Gas Fully Synced", msg)
 	}
 }
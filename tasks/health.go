@@ -0,0 +1,154 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"squirrel/config"
+	"squirrel/rpc"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Health reports liveness and readiness of all tasks.
+var Health = newHealth()
+
+type health struct {
+	mu sync.Mutex
+	// dead lists goroutines of each task which exited before the task was stopped.
+	dead map[string][]string
+	// stalls tracks since when each task made no progress while having pending work.
+	stalls map[string]*stall
+}
+
+type stall struct {
+	progress taskProgress
+	since    time.Time
+}
+
+func newHealth() *health {
+	return &health{dead: make(map[string][]string), stalls: make(map[string]*stall)}
+}
+
+// goTask runs f as a goroutine of the task which must keep running until ctx is cancelled,
+// the process is no longer live once it returns earlier, until the task is stopped.
+// Panics recovered by notify.AlertIfErr inside f end up here as well.
+func goTask(ctx context.Context, task string, name string, f func()) {
+	go func() {
+		defer Health.exited(ctx, task, name)
+		f()
+	}()
+}
+
+func (h *health) exited(ctx context.Context, task string, name string) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dead[task] = append(h.dead[task], name)
+}
+
+// stopped drops exited goroutines of the task, which is started again with new ones.
+func (h *health) stopped(task string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.dead, task)
+}
+
+// forget drops stall tracking of the tasks, e.g. once they are stopped.
+func (h *health) forget(tasks ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, task := range tasks {
+		delete(h.stalls, task)
+	}
+}
+
+// Live returns an error if a task goroutine exited or stalled,
+// or if all rpc servers are unavailable.
+func (h *health) Live() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if dead := h.exitedGoroutines(); len(dead) > 0 {
+		return fmt.Errorf("task goroutine exited: %s", strings.Join(dead, ", "))
+	}
+
+	if rpc.AllServersDown() {
+		return errors.New("all rpc servers are unavailable")
+	}
+
+	timeout := time.Duration(config.GetHealthConfig().StallTimeout) * time.Second
+	if stalled := h.stalled(pipeline, rpc.BestHeight.Get(), timeout, time.Now()); len(stalled) > 0 {
+		return fmt.Errorf("task stalled for over %v: %s", timeout, strings.Join(stalled, ", "))
+	}
+
+	return nil
+}
+
+// exitedGoroutines returns goroutines which exited before their task was stopped.
+func (h *health) exitedGoroutines() []string {
+	dead := []string{}
+	for _, names := range h.dead {
+		dead = append(dead, names...)
+	}
+
+	sort.Strings(dead)
+	return dead
+}
+
+// stalled returns tasks whose progress did not change for timeout
+// while they lag behind bestHeight or have items queued.
+func (h *health) stalled(m *pipelineMetrics, bestHeight int, timeout time.Duration, now time.Time) []string {
+	stalled := []string{}
+
+	for task, p := range m.snapshot() {
+		pending := p.lag(bestHeight) > 0 || m.queued(task) > 0
+
+		s, ok := h.stalls[task]
+		if !ok || !pending || s.progress.pk != p.pk || s.progress.height != p.height {
+			h.stalls[task] = &stall{progress: p, since: now}
+			continue
+		}
+
+		if now.Sub(s.since) > timeout {
+			stalled = append(stalled, task)
+		}
+	}
+
+	sort.Strings(stalled)
+	return stalled
+}
+
+// Ready returns an error until block sync started,
+// or while any task lags behind the rpc best height more than the configured blocks.
+func (h *health) Ready() error {
+	tasks := pipeline.snapshot()
+	if _, ok := tasks["block"]; !ok {
+		return errors.New("block sync has not started")
+	}
+
+	maxLag := config.GetHealthConfig().MaxLag
+	bestHeight := rpc.BestHeight.Get()
+
+	lagging := []string{}
+	for task, p := range tasks {
+		if lag := p.lag(bestHeight); lag > maxLag {
+			lagging = append(lagging, fmt.Sprintf("%s(%d blocks)", task, lag))
+		}
+	}
+
+	if len(lagging) > 0 {
+		sort.Strings(lagging)
+		return fmt.Errorf("task lagging behind: %s", strings.Join(lagging, ", "))
+	}
+
+	return nil
+}
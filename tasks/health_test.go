@@ -0,0 +1,77 @@
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStalled(t *testing.T) {
+	h := newHealth()
+	m := newPipelineMetrics()
+	m.setHeight("block", 90)
+	m.setPk("nep5", 7, 0)
+	m.setPk("tx", 7, 0)
+
+	queued := 0
+	m.trackQueue("nep5_store", func() int { return queued })
+
+	start := time.Now()
+	timeout := time.Minute
+
+	if stalled := h.stalled(m, 100, timeout, start); len(stalled) != 0 {
+		t.Fatalf("Expected no stalled task on first check, got %v", stalled)
+	}
+
+	// Only block has pending work, idle tasks never stall.
+	stalled := h.stalled(m, 100, timeout, start.Add(2*time.Minute))
+	if len(stalled) != 1 || stalled[0] != "block" {
+		t.Fatalf("Expected block to be stalled, got %v", stalled)
+	}
+
+	// Progress resets the timer.
+	m.setHeight("block", 95)
+	queued = 1
+	if stalled := h.stalled(m, 100, timeout, start.Add(3*time.Minute)); len(stalled) != 0 {
+		t.Fatalf("Expected no stalled task after progress, got %v", stalled)
+	}
+
+	stalled = h.stalled(m, 100, timeout, start.Add(5*time.Minute))
+	if len(stalled) != 2 || stalled[0] != "block" || stalled[1] != "nep5" {
+		t.Fatalf("Expected block and nep5 to be stalled, got %v", stalled)
+	}
+}
+
+func TestGoTaskExited(t *testing.T) {
+	Health = newHealth()
+	defer func() { Health = newHealth() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	goTask(ctx, "block", "stopped", func() {})
+	goTask(ctx, "block", "shutdown", func() {
+		<-ctx.Done()
+		close(done)
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+	time.Sleep(10 * time.Millisecond)
+
+	Health.mu.Lock()
+	dead := Health.exitedGoroutines()
+	Health.mu.Unlock()
+
+	if len(dead) != 1 || dead[0] != "stopped" {
+		t.Errorf("Expected only 'stopped' to be reported dead, got %v", dead)
+	}
+
+	// Restarted tasks run new goroutines.
+	Health.stopped("block")
+	if err := Health.Live(); err != nil && strings.Contains(err.Error(), "exited") {
+		t.Errorf("Expected exited goroutines of the stopped task to be dropped, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package tasks
+
+import (
+	"squirrel/rpc"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	processedPkDesc = prometheus.NewDesc(
+		"squirrel_task_processed_pk",
+		"Pk of the last transaction processed by the task.",
+		[]string{"task"}, nil,
+	)
+	maxPkDesc = prometheus.NewDesc(
+		"squirrel_task_max_pk",
+		"Highest transaction pk the task can process so far.",
+		[]string{"task"}, nil,
+	)
+	heightDesc = prometheus.NewDesc(
+		"squirrel_task_height",
+		"Block index of the last block or transaction processed by the task.",
+		[]string{"task"}, nil,
+	)
+	lagDesc = prometheus.NewDesc(
+		"squirrel_task_lag_blocks",
+		"Number of blocks the task is behind rpc best height.",
+		[]string{"task"}, nil,
+	)
+	queueDesc = prometheus.NewDesc(
+		"squirrel_queue_length",
+		"Number of items waiting in the queue.",
+		[]string{"queue"}, nil,
+	)
+)
+
+// pipeline holds progress of all tasks.
+var pipeline = newPipelineMetrics()
+
+func init() {
+	prometheus.MustRegister(pipeline)
+}
+
+// pipelineMetrics reports task progress and queue lengths at scrape time,
+// so lag keeps growing while a task is stuck.
+type pipelineMetrics struct {
+	mu     sync.Mutex
+	tasks  map[string]*taskProgress
+	queues map[string]func() int
+}
+
+// taskProgress is the last processed tx pk and/or block index of a task.
+type taskProgress struct {
+	pk        uint
+	maxPK     uint
+	height    uint
+	hasPK     bool
+	hasHeight bool
+}
+
+// lag returns how many blocks the task is behind bestHeight.
+func (p taskProgress) lag(bestHeight int) int {
+	if !p.hasHeight || bestHeight < int(p.height) {
+		return 0
+	}
+	return bestHeight - int(p.height)
+}
+
+func newPipelineMetrics() *pipelineMetrics {
+	return &pipelineMetrics{
+		tasks:  make(map[string]*taskProgress),
+		queues: make(map[string]func() int),
+	}
+}
+
+func (m *pipelineMetrics) get(task string) *taskProgress {
+	p, ok := m.tasks[task]
+	if !ok {
+		p = &taskProgress{}
+		m.tasks[task] = p
+	}
+	return p
+}
+
+// setPk records the last processed tx pk of task, a zero maxPK is not reported.
+func (m *pipelineMetrics) setPk(task string, pk uint, maxPK uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.get(task)
+	p.pk = pk
+	p.hasPK = true
+	if maxPK > 0 {
+		p.maxPK = maxPK
+	}
+}
+
+// setHeight records the block index task has processed up to.
+func (m *pipelineMetrics) setHeight(task string, height uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.get(task)
+	p.height = height
+	p.hasHeight = true
+}
+
+// trackQueue reports the result of length as the size of queue.
+func (m *pipelineMetrics) trackQueue(queue string, length func() int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queues[queue] = length
+}
+
+// untrack stops reporting progress and queues of the names.
+func (m *pipelineMetrics) untrack(names ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		delete(m.tasks, name)
+		delete(m.queues, name)
+	}
+}
+
+// queued returns the number of items waiting in queues of task,
+// which are named after the task or prefixed with its name and '_'.
+func (m *pipelineMetrics) queued(task string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for queue, length := range m.queues {
+		if queue == task || strings.HasPrefix(queue, task+"_") {
+			n += length()
+		}
+	}
+	return n
+}
+
+// snapshot returns a copy of progress of all tasks.
+func (m *pipelineMetrics) snapshot() map[string]taskProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := make(map[string]taskProgress, len(m.tasks))
+	for task, p := range m.tasks {
+		tasks[task] = *p
+	}
+	return tasks
+}
+
+// Describe implements prometheus.Collector.
+func (m *pipelineMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- processedPkDesc
+	ch <- maxPkDesc
+	ch <- heightDesc
+	ch <- lagDesc
+	ch <- queueDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *pipelineMetrics) Collect(ch chan<- prometheus.Metric) {
+	bestHeight := rpc.BestHeight.Get()
+
+	for task, p := range m.snapshot() {
+		if p.hasPK {
+			ch <- prometheus.MustNewConstMetric(processedPkDesc, prometheus.GaugeValue, float64(p.pk), task)
+		}
+		if p.maxPK > 0 {
+			ch <- prometheus.MustNewConstMetric(maxPkDesc, prometheus.GaugeValue, float64(p.maxPK), task)
+		}
+		if p.hasHeight {
+			ch <- prometheus.MustNewConstMetric(heightDesc, prometheus.GaugeValue, float64(p.height), task)
+			ch <- prometheus.MustNewConstMetric(lagDesc, prometheus.GaugeValue, float64(p.lag(bestHeight)), task)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for queue, length := range m.queues {
+		ch <- prometheus.MustNewConstMetric(queueDesc, prometheus.GaugeValue, float64(length()), queue)
+	}
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"squirrel/rpc"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	m := newPipelineMetrics()
+	m.setHeight("block", 90)
+	m.setHeight("tx", 120)
+	m.setPk("nep5", 7, 0)
+	m.trackQueue("tx", func() int { return 3 })
+
+	rpc.BestHeight.Set(100)
+	defer rpc.BestHeight.Set(0)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			labels := []string{}
+			for _, l := range metric.GetLabel() {
+				labels = append(labels, l.GetValue())
+			}
+			values[f.GetName()+"/"+strings.Join(labels, ",")] = metric.GetGauge().GetValue()
+		}
+	}
+
+	expected := map[string]float64{
+		"squirrel_task_lag_blocks/block":  10,
+		"squirrel_task_lag_blocks/tx":     0,
+		"squirrel_task_processed_pk/nep5": 7,
+		"squirrel_queue_length/tx":        3,
+	}
+	for key, v := range expected {
+		if values[key] != v {
+			t.Errorf("Expected %s to be %v, got %v", key, v, values[key])
+		}
+	}
+
+	if _, ok := values["squirrel_task_max_pk/nep5"]; ok {
+		t.Errorf("Expected unknown max pk not to be reported")
+	}
+}
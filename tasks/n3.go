@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"squirrel/log"
+	"squirrel/n3"
+	"squirrel/notify"
+	"squirrel/rpc"
+	"strings"
+	"time"
+)
+
+const (
+	// n3BatchSize is the number of N3 blocks fetched and persisted together.
+	n3BatchSize = 20
+	// n3QueueSize is the default number of fetched batches waiting to be persisted.
+	n3QueueSize = 10
+)
+
+// startN3BlockTask indexes a Neo N3 chain after height until r is stopped.
+// Blocks are fetched in order together with application logs of the blocks and their transactions,
+// and persisted batch by batch in one database transaction, so there are no other counters to keep.
+// dBFT blocks are final once persisted, there is no reorganisation to handle.
+func startN3BlockTask(r *runner, height int) {
+	queue := make(chan *n3.Bulk, r.channelSize(n3QueueSize))
+	pipeline.trackQueue("block_channel", func() int { return len(queue) })
+
+	r.goTask("fetch_n3_block", func() { fetchN3Blocks(r.ctx, height, queue) })
+	r.goTask("store_n3_block", func() { storeN3Blocks(height, queue) })
+}
+
+func initN3Task(dbHeight int) {
+	bestHeight := rpc.RefreshServers(context.Background())
+
+	log.Printf("Current params for N3 block persistance:\n")
+	log.Printf("\tdb block height = %d\n", dbHeight)
+	log.Printf("\trpc best height = %d\n", bestHeight)
+}
+
+// fetchN3Blocks pushes batches of blocks after height in order into queue,
+// and closes it once ctx is cancelled.
+func fetchN3Blocks(ctx context.Context, height int, queue chan<- *n3.Bulk) {
+	defer close(queue)
+	defer notify.AlertIfErr()
+
+	next := height + 1
+
+	for ctx.Err() == nil {
+		// Blocks beyond the best height are requested one at a time until they are produced.
+		size := rpc.BestHeight.Get() - next + 1
+		if size > n3BatchSize {
+			size = n3BatchSize
+		}
+		if size < 1 {
+			size = 1
+		}
+
+		indexes := make([]int, size)
+		for i := range indexes {
+			indexes[i] = next + i
+		}
+
+		blocks, err := rpc.DownloadN3Blocks(ctx, indexes)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.With(log.Fields{"task": "n3_block", "height": next}).Errorf("Failed to download N3 blocks: %v\n", err)
+		}
+
+		// Only blocks in order can be persisted, the rest is fetched again.
+		fetched := []*rpc.RawN3Block{}
+		for _, b := range blocks {
+			if b == nil {
+				break
+			}
+			fetched = append(fetched, b)
+		}
+
+		if len(fetched) == 0 {
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		bulk, err := fetchN3Bulk(ctx, fetched)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		select {
+		case queue <- bulk:
+		case <-ctx.Done():
+			return
+		}
+
+		next += len(fetched)
+	}
+}
+
+// fetchN3Bulk fetches application logs and deployed contracts of blocks, and parses them.
+func fetchN3Bulk(ctx context.Context, blocks []*rpc.RawN3Block) (*n3.Bulk, error) {
+	maxIndex := int(blocks[len(blocks)-1].Index)
+
+	hashes := n3.LogHashes(blocks)
+	logs, err := rpc.GetN3ApplicationLogs(ctx, maxIndex, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	logMap := make(map[string]*rpc.RawN3ApplicationLog, len(hashes))
+	for i, hash := range hashes {
+		logMap[hash] = logs[i]
+	}
+
+	bulk, err := n3.Parse(blocks, logMap)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range bulk.Contracts {
+		if c.Event == "Destroy" {
+			continue
+		}
+
+		state, err := rpc.GetN3ContractState(ctx, int(c.BlockIndex), c.Hash)
+		// Destroyed since, its manifest is gone.
+		if errors.Is(err, rpc.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.Name = state.Manifest.Name
+		c.Standards = strings.Join(state.Manifest.SupportedStandards, ",")
+	}
+
+	return bulk, nil
+}
+
+// storeN3Blocks persists batches from queue until it is closed.
+func storeN3Blocks(dbHeight int, queue <-chan *n3.Bulk) {
+	defer notify.AlertIfErr()
+
+	maxIndex := dbHeight
+
+	for bulk := range queue {
+		if err := storage.InsertN3Bulk(bulk); err != nil {
+			panic(err)
+		}
+
+		maxIndex = int(bulk.Blocks[len(bulk.Blocks)-1].Index)
+		pipeline.setHeight("block", uint(maxIndex))
+
+		bestHeight := rpc.BestHeight.Get()
+		if bestHeight < maxIndex {
+			bestHeight = maxIndex
+			rpc.BestHeight.Set(maxIndex)
+		}
+
+		showBlockStorageProgress(int64(maxIndex), int64(bestHeight))
+	}
+
+	log.Printf("N3 block storage stopped at height %d\n", maxIndex)
+}
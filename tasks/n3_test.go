@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"context"
+	"squirrel/config"
+	"squirrel/db"
+	"testing"
+	"time"
+)
+
+func TestRunN3Replay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	replay, cleanup := startReplay(t, config.NetworkN3)
+	defer cleanup()
+
+	store := db.Init()
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, store, LastHeight(store), nil)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for store.GetN3LastHeight() != 2 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if height := store.GetN3LastHeight(); height != 2 {
+		t.Fatalf("Expected N3 blocks 0-2 to be synced, got height %d, requests not recorded: %v", height, replay.Missing())
+	}
+	if missing := replay.Missing(); len(missing) > 0 {
+		t.Errorf("Expected all requests to be recorded, got %v", missing)
+	}
+
+	// Mint of block 0, fee burn and reward mint of block 1.
+	gas, err := store.GetN3Transfers("0xd2a4cff31913016155e38e474a2c06d08be276cf", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gas) != 3 || gas[1].To != "" || gas[1].Amount.String() != "2228388" {
+		t.Errorf("Expected 3 GAS transfers with a fee burn, got %+v", gas)
+	}
+
+	token, err := store.GetN3Transfers("0xc612f8fc618ef8d453f3437ad375586c9d9e463c", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 1 || token[0].From != "" || token[0].To != "NPuo2nmLLkcQxvbKPdeKa5B9fHg9C6dQbA" || token[0].Amount.String() != "1000" {
+		t.Errorf("Expected NEP-17 mint of the deployed contract, got %+v", token)
+	}
+
+	// The transfer of the faulted transaction is reverted.
+	nft, err := store.GetN3Transfers("0xe887d34bbae57e47007826a8d87b80120b10cbb8", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nft) != 1 || nft[0].Standard != "NEP-11" || nft[0].TokenID != "746f6b656e31" || nft[0].To != "NXjpeExPy1EhAyg5nD3E4izZSRmh2p2JF3" {
+		t.Errorf("Expected one NEP-11 transfer, got %+v", nft)
+	}
+}
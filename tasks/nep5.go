@@ -1,18 +1,7 @@
 /*
-To restart this task from beginning, execute the following sqls:
-
-DELETE FROM `addr_asset` WHERE LENGTH(`asset_id`) = 40;
-DELETE FROM `addr_tx` WHERE `asset_type` = 'nep5';
-UPDATE `address` SET `trans_nep5` = 0 WHERE 1=1;
-UPDATE `counter` SET
-	`last_tx_pk_for_nep5` = 0,
-	`app_log_idx` = -1
-WHERE `id` = 1;
-TRUNCATE TABLE `nep5`;
-TRUNCATE TABLE `nep5_reg_info`;
-TRUNCATE TABLE `nep5_tx`;
-TRUNCATE TABLE `nep5_migrate`;
-UPDATE `counter` SET `nep5_tx_pk_for_addr_tx`=0 WHERE `id`=1;
+To restart this task from beginning, stop the indexer and run:
+
+./squirrel reset -yes nep5
 
 To check if rpc node has enabled smart contract log,
 check if the first nep5 transfer exists:
@@ -58,28 +47,38 @@ curl -X POST \
 package tasks
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math"
 	"math/big"
 	"reflect"
 	"squirrel/cache"
+	"squirrel/config"
 	"squirrel/log"
-	"squirrel/mail"
+	"squirrel/notify"
 	"squirrel/smartcontract"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"squirrel/addr"
-	"squirrel/db"
 	"squirrel/nep5"
 	"squirrel/rpc"
 	"squirrel/tx"
 	"squirrel/util"
 )
 
-const nep5ChanSize = 5000
+const (
+	nep5ChanSize = 5000
+	// defaultNep5Decimals is used for transfers of assets whose decimals are unknown.
+	defaultNep5Decimals = 8
+	// appLogBatchSize is the max number of application logs fetched per rpc request.
+	appLogBatchSize = 20
+	// appLogWorkers is the default number of goroutines fetching application logs for nep5 and nft.
+	appLogWorkers = 4
+)
 
 var maxVal *big.Float
 
@@ -94,6 +93,9 @@ var (
 
 	// appLogs stores txid with its applicationlog rpc response
 	appLogs sync.Map
+
+	// nonNep5Contracts are contracts emitting transfers which answered invalid nep5 definitions.
+	nonNep5Contracts = map[string]bool{}
 )
 
 type nep5TxInfo struct {
@@ -107,6 +109,8 @@ type nep5Store struct {
 	// 1: nep5 tx
 	// 2: nep5 addr balance and total supply
 	// 3: update counter(last_tx_pk_for_nep5, app_log_idx)
+	// 4: nep5 migrate
+	// 5: nep5 discovered from its transfer
 	t int
 	d interface{}
 }
@@ -119,6 +123,12 @@ type nep5AssetStore struct {
 	atHeight  uint
 }
 
+type nep5DiscoveredStore struct {
+	tx      *tx.Transaction
+	nep5    *nep5.Nep5
+	regInfo *nep5.RegInfo
+}
+
 type nep5TxStore struct {
 	tx            *tx.Transaction
 	applogIdx     int
@@ -158,23 +168,33 @@ func init() {
 	maxVal, _ = new(big.Float).SetPrec(256).SetString("99999999999999999999999999999999999.99999999")
 }
 
-func startNep5Task() {
-	nep5AssetDecimals = db.GetNep5AssetDecimals()
-	nep5TxChan := make(chan *nep5TxInfo, nep5ChanSize)
-	applogChan := make(chan *tx.Transaction, nep5ChanSize)
-	nep5StoreChan := make(chan *nep5Store, nep5ChanSize)
+func startNep5Task(r *runner) {
+	nep5AssetDecimals = storage.GetNep5AssetDecimals()
+	nonNep5Contracts = map[string]bool{}
+	size := r.channelSize(nep5ChanSize)
+	nep5TxChan := make(chan *nep5TxInfo, size)
+	applogChan := make(chan *tx.Transaction, size)
+	nep5StoreChan := make(chan *nep5Store, size)
 
-	lastPk, applogIdx := db.GetLastTxPkForNep5()
+	lastPk, applogIdx := storage.GetLastTxPkForNep5()
 
-	go fetchNep5Tx(nep5TxChan, applogChan, lastPk, applogIdx)
-	go fetchAppLog(4, applogChan)
+	pipeline.trackQueue("nep5_tx", func() int { return len(nep5TxChan) })
+	pipeline.trackQueue("nep5_applog", func() int { return len(applogChan) })
+	pipeline.trackQueue("nep5_store", func() int { return len(nep5StoreChan) })
 
-	go handleNep5Tx(nep5TxChan, nep5StoreChan, applogIdx)
-	go handleNep5Store(nep5StoreChan)
+	r.goTask("fetch_nep5_tx", func() { fetchNep5Tx(r.ctx, nep5TxChan, applogChan, lastPk, applogIdx) })
+	fetchAppLog(r, r.workers(appLogWorkers), applogChan)
+
+	r.goTask("handle_nep5_tx", func() { handleNep5Tx(nep5TxChan, nep5StoreChan, applogIdx) })
+	r.goTask("handle_nep5_store", func() { handleNep5Store(nep5StoreChan) })
 }
 
-func fetchNep5Tx(nep5TxChan chan<- *nep5TxInfo, applogChan chan<- *tx.Transaction, lastPk uint, applogIdx int) {
-	defer mail.AlertIfErr()
+// fetchNep5Tx sends transactions with their application logs to nep5TxChan,
+// both channels are closed once ctx is cancelled.
+func fetchNep5Tx(ctx context.Context, nep5TxChan chan<- *nep5TxInfo, applogChan chan<- *tx.Transaction, lastPk uint, applogIdx int) {
+	defer close(nep5TxChan)
+	defer close(applogChan)
+	defer notify.AlertIfErr()
 
 	// If there are some transfers in this transaction,
 	// this variable will be the last index(starts from 0).
@@ -187,34 +207,44 @@ func fetchNep5Tx(nep5TxChan chan<- *nep5TxInfo, applogChan chan<- *tx.Transactio
 		nextTxPK++
 	}
 
-	for {
-		txs := db.GetInvocationTxs(nextTxPK, 1000)
+	for ctx.Err() == nil {
+		txs := storage.GetInvocationTxs(nextTxPK, 1000)
+		profile := config.GetProfile()
 
 		for i := len(txs) - 1; i >= 0; i-- {
 			// cannot be app call
 			if len(txs[i].Script) <= 42 ||
-				txs[i].TxID == "0xb00a0d7b752ba935206e1db67079c186ba38a4696d3afe28814a4834b2254cbe" {
+				profile.IsSkippedTx(txs[i].TxID) {
 				txs = append(txs[:i], txs[i+1:]...)
 			}
 		}
 
 		if len(txs) == 0 {
-			time.Sleep(2 * time.Second)
+			sleep(ctx, 2*time.Second)
 			continue
 		}
 
 		nextTxPK = txs[len(txs)-1].ID + 1
 
 		for _, tx := range txs {
-			applogChan <- tx
+			select {
+			case applogChan <- tx:
+			case <-ctx.Done():
+				return
+			}
 		}
 
 		for _, tx := range txs {
-			fmt.Printf("%s\n", tx.TxID)
+			log.With(log.Fields{"task": "nep5", "txid": tx.TxID}).Debugf("Waiting for application log\n")
 			for {
 				// Get applicationlog from map.
 				appLogResult, ok := appLogs.Load(tx.TxID)
 				if !ok {
+					// Transactions without application log will be fetched again on next start.
+					if ctx.Err() != nil {
+						return
+					}
+
 					time.Sleep(10 * time.Millisecond)
 					continue
 				}
@@ -234,23 +264,52 @@ func fetchNep5Tx(nep5TxChan chan<- *nep5TxInfo, applogChan chan<- *tx.Transactio
 	}
 }
 
-func fetchAppLog(goroutines int, applogChan <-chan *tx.Transaction) {
-	defer mail.AlertIfErr()
+func fetchAppLog(r *runner, goroutines int, applogChan <-chan *tx.Transaction) {
+	ctx := r.ctx
 
 	for i := 0; i < goroutines; i++ {
-		go func(ch <-chan *tx.Transaction) {
-			for tx := range ch {
-				appLogResult := rpc.GetApplicationLog(int(tx.BlockIndex), tx.TxID)
-				appLogs.Store(tx.TxID, appLogResult)
+		r.goTask("fetch_nep5_applog", func() {
+			defer notify.AlertIfErr()
+
+			for tx := range applogChan {
+				txs := receiveAppLogBatch(applogChan, tx)
+				fetchAppLogs(ctx, "nep5", txs, func(txID string, appLog *rpc.RawApplicationLogResult) {
+					appLogs.Store(txID, appLog)
+				})
 			}
-		}(applogChan)
+		})
 	}
 }
 
+// receiveAppLogBatch returns tx with transactions already queued in ch after it,
+// so their application logs are fetched in one batch request.
+func receiveAppLogBatch(ch <-chan *tx.Transaction, first *tx.Transaction) []*tx.Transaction {
+	txs := []*tx.Transaction{first}
+
+	for len(txs) < appLogBatchSize {
+		select {
+		case t, ok := <-ch:
+			if !ok {
+				return txs
+			}
+			txs = append(txs, t)
+		default:
+			return txs
+		}
+	}
+
+	return txs
+}
+
 func handleNep5Tx(nep5TxChan <-chan *nep5TxInfo, nep5StoreChan chan<- *nep5Store, applogIdx int) {
-	defer mail.AlertIfErr()
+	defer close(nep5StoreChan)
+	defer notify.AlertIfErr()
 
 	for nep5Info := range nep5TxChan {
+		if atomic.CompareAndSwapInt32(&nep5AssetsShouldReload, 1, 0) {
+			nep5AssetDecimals = storage.GetNep5AssetDecimals()
+		}
+
 		tx := nep5Info.tx
 		opCodeDataStack := nep5Info.dataStack
 		appLogResult := nep5Info.appLogResult
@@ -284,15 +343,15 @@ func handleNep5Tx(nep5TxChan <-chan *nep5TxInfo, nep5StoreChan chan<- *nep5Store
 
 					if strings.Contains(exec.VMState, "FAULT") ||
 						len(exec.Notifications) == 0 {
-						fmt.Printf("Failure VM execution for nep5 data stack:%d\n",
-							len(exec.Notifications))
+						log.With(log.Fields{"task": "nep5", "txid": tx.TxID, "vmstate": exec.VMState}).
+							Debugf("Skipped execution with %d notifications\n", len(exec.Notifications))
 						continue
 					}
 
 					notifs = append(notifs, exec.Notifications...)
 				}
 
-				fmt.Printf("len: %d\n", len(notifs))
+				log.With(log.Fields{"task": "nep5", "txid": tx.TxID}).Debugf("Handling %d notifications\n", len(notifs))
 				handleNep5TxCall(nep5StoreChan, tx, notifs, applogIdx)
 			}
 
@@ -348,7 +407,7 @@ func handleNep5Migrate(opCodeDataStack *smartcontract.DataStack, nep5StoreChan c
 }
 
 func handleNep5Store(nep5Store <-chan *nep5Store) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	for s := range nep5Store {
 		txPK := uint(0)
@@ -364,6 +423,8 @@ func handleNep5Store(nep5Store <-chan *nep5Store) {
 			txPK = handleNep5CounterStore(s)
 		case 4:
 			txPK = handleNEP5Migrate(s)
+		case 5:
+			txPK = handleNep5DiscoveredStore(s)
 		default:
 			err := fmt.Errorf("error nep5 store type %d: %+v", s.t, s.d)
 			panic(err)
@@ -380,7 +441,7 @@ func handleNep5AssetStore(s *nep5Store) uint {
 		panic(err)
 	}
 
-	err := db.InsertNep5Asset(d.tx,
+	err := storage.InsertNep5Asset(d.tx,
 		d.nep5,
 		d.regInfo,
 		d.addrAsset,
@@ -392,6 +453,21 @@ func handleNep5AssetStore(s *nep5Store) uint {
 	return d.tx.ID
 }
 
+func handleNep5DiscoveredStore(s *nep5Store) uint {
+	d, ok := s.d.(nep5DiscoveredStore)
+	if !ok {
+		err := fmt.Errorf("error nep5 store type %d: %+v", s.t, s.d)
+		panic(err)
+	}
+
+	err := storage.InsertDiscoveredNep5Asset(d.tx, d.nep5, d.regInfo)
+	if err != nil {
+		panic(err)
+	}
+
+	return d.tx.ID
+}
+
 func handleNep5TxStore(s *nep5Store) uint {
 	d, ok := s.d.(nep5TxStore)
 	if !ok {
@@ -399,7 +475,7 @@ func handleNep5TxStore(s *nep5Store) uint {
 		panic(err)
 	}
 
-	err := db.InsertNep5transaction(d.tx,
+	err := storage.InsertNep5transaction(d.tx,
 		d.applogIdx,
 		d.assetID,
 		d.fromAddr,
@@ -422,7 +498,7 @@ func handleNep5BalanceTotalSupplyStore(s *nep5Store) uint {
 		panic(err)
 	}
 
-	err := db.UpdateNep5TotalSupplyAndAddrAsset(
+	err := storage.UpdateNep5TotalSupplyAndAddrAsset(
 		d.blockTime,
 		d.blockIndex,
 		d.addr,
@@ -443,7 +519,7 @@ func handleNep5CounterStore(s *nep5Store) uint {
 		panic(err)
 	}
 
-	err := db.UpdateLastTxPkForNep5(d.txPK, d.applogIdx)
+	err := storage.UpdateLastTxPkForNep5(d.txPK, d.applogIdx)
 	if err != nil {
 		panic(err)
 	}
@@ -498,7 +574,7 @@ func handleNEP5Migrate(s *nep5Store) uint {
 		panic(err)
 	}
 
-	err := db.HandleNEP5Migrate(d.newAssetAdmin, d.oldAssetID, d.newAssetID, d.txPK, d.txID)
+	err := storage.HandleNEP5Migrate(d.newAssetAdmin, d.oldAssetID, d.newAssetID, d.txPK, d.txID)
 	if err != nil {
 		panic(err)
 	}
@@ -511,7 +587,7 @@ func handleNep5NonTxCall(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, op
 	for len(*opCodeDataStack) >= 2 {
 		opCode, data := opCodeDataStack.PopItem()
 
-		if opCode != 0x67 { // APPCALL
+		if opCode != byte(smartcontract.APPCALL) {
 			continue
 		}
 
@@ -563,7 +639,6 @@ func handleNep5NonTxCall(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, op
 func handleNep5TxCall(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, notifs []rpc.RawNotifications, applogIdx int) {
 	// Get all transfers.
 	for applogIdx++; applogIdx < len(notifs); applogIdx++ {
-		fmt.Printf("tx call\n")
 		notification := notifs[applogIdx]
 		state := notification.State
 
@@ -597,19 +672,73 @@ func handleNep5TxCall(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, notif
 		val := stackValues[3].Value.(string)
 		assetID := notification.Contract[2:]
 
-		// Check if this is a valid assetID.
-		// if _, ok := nep5AssetDecimals[assetID]; !ok {
-		// 	fmt.Println("Sorry i dont know you :(")
-		// 	continue
-		// }
+		// Transfers of contracts which are not nep5 are still recorded.
+		discoverNep5Asset(nep5StoreChan, tx, assetID)
 
 		recordNep5Transfer(nep5StoreChan, tx, assetID, fromSc, toSc, val, valType, applogIdx)
 		scanAttrAddrBalance(nep5StoreChan, tx, assetID)
 	}
 }
 
+// discoverNep5Asset registers the asset of a transfer if its registration transaction was not recognized,
+// e.g. deployed by a proxy contract, with definitions queried from the contract.
+// Registration info of such asset is unknown, and the transaction of its first transfer is recorded instead.
+// It returns false if the contract is not nep5.
+func discoverNep5Asset(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, assetID string) bool {
+	if _, ok := nep5AssetDecimals[assetID]; ok {
+		return true
+	}
+	if nonNep5Contracts[assetID] {
+		return false
+	}
+
+	scriptHash := util.GetScriptHashFromAssetID(assetID)
+	scripts := createSCSB(scriptHash, "name", nil)
+	scripts += createSCSB(scriptHash, "symbol", nil)
+	scripts += createSCSB(scriptHash, "decimals", nil)
+	scripts += createSCSB(scriptHash, "totalSupply", nil)
+
+	minHeight := getMinHeight(tx.BlockIndex)
+	result := invokeScript(minHeight, scripts)
+	// Failed rpc calls are tried again on the next transfer.
+	if result == nil {
+		return false
+	}
+
+	// The contract may be not deployed yet on a lagging node, faulted queries are tried again.
+	if strings.Contains(result.State, "FAULT") {
+		log.With(log.Fields{"task": "nep5", "txid": tx.TxID, "asset": assetID}).Warnf("Failed to query nep5 definitions: %s\n", result.State)
+		return false
+	}
+
+	asset, ok := parseNep5Definitions(assetID, result.Stack)
+	if !ok {
+		nonNep5Contracts[assetID] = true
+		return false
+	}
+
+	asset.TxID = tx.TxID
+	asset.BlockIndex = tx.BlockIndex
+	asset.BlockTime = tx.BlockTime
+
+	cache.UpdateAssetTotalSupply(assetID, asset.TotalSupply, uint(minHeight))
+
+	nep5StoreChan <- &nep5Store{
+		t: 5,
+		d: nep5DiscoveredStore{
+			tx:      tx,
+			nep5:    asset,
+			regInfo: nep5.NewUnknownRegInfo(tx.TxID, scriptHash),
+		},
+	}
+
+	nep5AssetDecimals[assetID] = asset.Decimals
+	log.With(log.Fields{"task": "nep5", "txid": tx.TxID, "asset": assetID}).Infof("Discovered nep5 asset %s\n", asset.Symbol)
+	return true
+}
+
 func scanAttrAddrBalance(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, assetID string) {
-	attrs := db.GetTxAttrs(tx.TxID)
+	attrs := storage.GetTxAttrs(tx.TxID)
 	if len(attrs) == 0 {
 		return
 	}
@@ -662,7 +791,7 @@ func recordNep5Transfer(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, ass
 	toAddr := util.GetAddressFromScriptHash(to)
 
 	if len(fromAddr) > 128 || len(toAddr) > 128 {
-		log.Error.Printf("TxID: %s, from=%s, to=%s", tx.TxID, fromAddr, toAddr)
+		log.With(log.Fields{"task": "nep5", "txid": tx.TxID}).Errorf("Invalid transfer addresses from=%s, to=%s\n", fromAddr, toAddr)
 		return
 	}
 
@@ -678,11 +807,11 @@ func recordNep5Transfer(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, ass
 
 	// Handle possibility of storage injection attack.
 	var totalSupply *big.Float
-	if toSc == "746f74616c537570706c79" || tx.BlockIndex > 6200000 {
+	if toSc == "746f74616c537570706c79" || tx.BlockIndex > config.GetProfile().TotalSupplyHeight {
 		totalSupply, _ = queryNep5TotalSupply(tx.BlockIndex, tx.BlockTime, scriptHash)
 	}
 
-	fmt.Printf("Saving nep5 tx\n")
+	log.With(log.Fields{"task": "nep5", "txid": tx.TxID, "asset": assetID}).Debugf("Saving nep5 transfer of %s\n", transferValue.Text('f', -1))
 	nep5StoreChan <- &nep5Store{
 		t: 1,
 		d: nep5TxStore{
@@ -697,13 +826,20 @@ func recordNep5Transfer(nep5StoreChan chan<- *nep5Store, tx *tx.Transaction, ass
 	}
 }
 
+// getTransferValue returns the transfer value scaled by decimals of the asset,
+// assets not registered yet are assumed to have the most common 8 decimals.
 func getTransferValue(assetID string, val string, valType string) (*big.Float, bool) {
 	value, ok := extractValue(val, valType)
 	if !ok {
 		return nil, false
 	}
 
-	return getReadableValue(value), true
+	decimals, ok := nep5AssetDecimals[assetID]
+	if !ok {
+		decimals = defaultNep5Decimals
+	}
+
+	return getReadableValue(value, decimals), true
 }
 
 func extractValue(val interface{}, valType string) (*big.Float, bool) {
@@ -734,7 +870,7 @@ func extractValue(val interface{}, valType string) (*big.Float, bool) {
 }
 
 func getCallerAddr(tx *tx.Transaction) ([]byte, bool) {
-	txScrpits, err := db.GetTxScripts(tx.TxID)
+	txScrpits, err := storage.GetTxScripts(tx.TxID)
 	if err != nil {
 		panic(err)
 	}
@@ -790,7 +926,7 @@ func queryNep5AssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 	scripts += createSCSB(scriptHash, "balanceOf", [][]byte{addrBytes})
 
 	minHeight := getMinHeight(tx.BlockIndex)
-	result := rpc.SmartContractRPCCall(minHeight, scripts)
+	result := invokeScript(minHeight, scripts)
 	if result == nil || strings.Contains(result.State, "FAULT") {
 		return nil, nil, 0, false
 	}
@@ -798,56 +934,16 @@ func queryNep5AssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 		return nil, nil, 0, false
 	}
 
-	nameBytesStr, ok := result.Stack[0].Value.(string)
-	if !ok {
-		return nil, nil, 0, false
-	}
-	nameBytes, err := hex.DecodeString(nameBytesStr)
-	if err != nil {
-		return nil, nil, 0, false
-	}
-	name := strings.Replace(string(nameBytes), "'", "\\'", -1)
-	if name == "" {
-		return nil, nil, 0, false
-	}
-
-	symbolBytesStr, ok := result.Stack[1].Value.(string)
+	nep5, ok := parseNep5Definitions(assetID, result.Stack)
 	if !ok {
 		return nil, nil, 0, false
 	}
-	symbolBytes, _ := hex.DecodeString(symbolBytesStr)
-	symbol := string(symbolBytes)
-	if symbol == "" {
-		return nil, nil, 0, false
-	}
-	if len(symbol) > 16 {
-		symbol = symbol[:16]
-	}
-
-	decimalsHexStr, ok := result.Stack[2].Value.(string)
-	if !ok {
-		return nil, nil, 0, false
-	}
-	decimals := util.HexToBigInt(decimalsHexStr).Int64()
-	if decimals < 0 {
-		return nil, nil, 0, false
-	}
-
-	totalSupply, ok := extractValue(result.Stack[3].Value, result.Stack[3].Type)
-	if !ok {
-		return nil, nil, 0, false
-	}
-	totalSupply = new(big.Float).SetPrec(256).Quo(totalSupply, big.NewFloat(math.Pow10(int(decimals))))
-	if totalSupply.Cmp(maxVal) > 0 {
-		totalSupply = big.NewFloat(0)
-	}
-
 	adminBalance, ok := extractValue(result.Stack[4].Value, result.Stack[4].Type)
 	if !ok {
 		return nil, nil, 0, false
 	}
 	if adminBalance.Cmp(big.NewFloat(0)) == 1 {
-		adminBalance = new(big.Float).SetPrec(256).Quo(adminBalance, big.NewFloat(math.Pow10(int(decimals))))
+		adminBalance = new(big.Float).SetPrec(256).Quo(adminBalance, big.NewFloat(math.Pow10(int(nep5.Decimals))))
 	}
 	if adminBalance.Cmp(maxVal) > 0 {
 		adminBalance = big.NewFloat(0)
@@ -855,20 +951,12 @@ func queryNep5AssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 
 	addrHasBalance := adminBalance.Cmp(big.NewFloat(0))
 
-	nep5 := &nep5.Nep5{
-		AssetID:          assetID,
-		AdminAddress:     adminAddr,
-		Name:             name,
-		Symbol:           symbol,
-		Decimals:         uint8(decimals),
-		TotalSupply:      totalSupply,
-		TxID:             tx.TxID,
-		BlockIndex:       tx.BlockIndex,
-		BlockTime:        tx.BlockTime,
-		Addresses:        uint64(addrHasBalance),
-		HoldingAddresses: uint64(addrHasBalance),
-		Transfers:        0,
-	}
+	nep5.AdminAddress = adminAddr
+	nep5.TxID = tx.TxID
+	nep5.BlockIndex = tx.BlockIndex
+	nep5.BlockTime = tx.BlockTime
+	nep5.Addresses = uint64(addrHasBalance)
+	nep5.HoldingAddresses = uint64(addrHasBalance)
 
 	var addrAsset *addr.Asset
 
@@ -889,6 +977,65 @@ func queryNep5AssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 	return nep5, addrAsset, uint(minHeight), true
 }
 
+// parseNep5Definitions parses results of 'name', 'symbol', 'decimals' and 'totalSupply' at the top of stack.
+func parseNep5Definitions(assetID string, stack []rpc.RawStack) (*nep5.Nep5, bool) {
+	if len(stack) < 4 {
+		return nil, false
+	}
+
+	nameBytesStr, ok := stack[0].Value.(string)
+	if !ok {
+		return nil, false
+	}
+	nameBytes, err := hex.DecodeString(nameBytesStr)
+	if err != nil {
+		return nil, false
+	}
+	name := string(nameBytes)
+	if name == "" {
+		return nil, false
+	}
+
+	symbolBytesStr, ok := stack[1].Value.(string)
+	if !ok {
+		return nil, false
+	}
+	symbolBytes, _ := hex.DecodeString(symbolBytesStr)
+	symbol := string(symbolBytes)
+	if symbol == "" {
+		return nil, false
+	}
+	if len(symbol) > 16 {
+		symbol = symbol[:16]
+	}
+
+	decimalsHexStr, ok := stack[2].Value.(string)
+	if !ok {
+		return nil, false
+	}
+	decimals := util.HexToBigInt(decimalsHexStr).Int64()
+	if decimals < 0 {
+		return nil, false
+	}
+
+	totalSupply, ok := extractValue(stack[3].Value, stack[3].Type)
+	if !ok {
+		return nil, false
+	}
+	totalSupply = new(big.Float).SetPrec(256).Quo(totalSupply, big.NewFloat(math.Pow10(int(decimals))))
+	if totalSupply.Cmp(maxVal) > 0 {
+		totalSupply = big.NewFloat(0)
+	}
+
+	return &nep5.Nep5{
+		AssetID:     assetID,
+		Name:        name,
+		Symbol:      symbol,
+		Decimals:    uint8(decimals),
+		TotalSupply: totalSupply,
+	}, true
+}
+
 func createNep5BalanceSCSB(scriptHash []byte, addrBytes []byte) string {
 	if len(addrBytes) == 0 {
 		return ""
@@ -927,7 +1074,7 @@ func queryCallerBalance(txBlockIndex uint, blockTime uint64, scriptHash []byte,
 	scripts := createSCSB(scriptHash, "balanceOf", [][]byte{callerAddrBytes})
 
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, scripts)
+	result := invokeScript(minHeight, scripts)
 	if result == nil ||
 		strings.Contains(result.State, "FAULT") ||
 		result.Stack == nil ||
@@ -949,7 +1096,8 @@ func queryCallerBalance(txBlockIndex uint, blockTime uint64, scriptHash []byte,
 
 func queryBalances(txBlockIndex uint, scriptHash []byte, assetID string, addrBytesList [][]byte) ([]*big.Float, bool) {
 	// Check if this is a valid assetID.
-	if _, ok := nep5AssetDecimals[assetID]; !ok {
+	decimals, ok := nep5AssetDecimals[assetID]
+	if !ok {
 		return nil, false
 	}
 
@@ -977,7 +1125,7 @@ func queryBalances(txBlockIndex uint, scriptHash []byte, assetID string, addrByt
 	}
 
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, scsb)
+	result := invokeScript(minHeight, scsb)
 
 	// If this nep5 asset is broken(for example forgot to check 'need storage').
 	if result == nil || strings.Contains(result.State, "FAULT") {
@@ -997,7 +1145,7 @@ func queryBalances(txBlockIndex uint, scriptHash []byte, assetID string, addrByt
 		if !ok {
 			continue
 		}
-		balances[i] = getReadableValue(balance)
+		balances[i] = getReadableValue(balance, decimals)
 		if balances[i].Cmp(maxVal) > 0 {
 			balances[i] = big.NewFloat(0)
 		}
@@ -1023,7 +1171,7 @@ func queryNep5TotalSupply(txBlockIndex uint, blockTime uint64, scriptHash []byte
 
 	totalSupplyScsb := createSCSB(scriptHash, "totalSupply", nil)
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, totalSupplyScsb)
+	result := invokeScript(minHeight, totalSupplyScsb)
 	if result == nil || strings.Contains(result.State, "FAULT") {
 		return nil, false
 	}
@@ -1065,21 +1213,23 @@ func queryNep5TotalSupply(txBlockIndex uint, blockTime uint64, scriptHash []byte
 	return totalSupply, true
 }
 
-func getReadableValue(balance *big.Float) *big.Float {
+// getReadableValue divides the raw integer value by 10^decimals.
+func getReadableValue(balance *big.Float, decimals uint8) *big.Float {
 	zeroValue := big.NewFloat(0)
 	if balance.Cmp(zeroValue) == 0 {
 		return zeroValue
 	}
 
-	decimals := 8
-	return new(big.Float).SetPrec(256).Quo(balance, big.NewFloat(math.Pow10(int(decimals))))
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Float).SetPrec(256).Quo(balance, new(big.Float).SetInt(unit))
 }
 
 func showNep5Progress(txPk uint) {
 	if maxNep5PK == 0 || Nep5MaxPkShouldRefresh {
 		Nep5MaxPkShouldRefresh = false
-		maxNep5PK = db.GetMaxNonEmptyScriptTxPk()
+		maxNep5PK = storage.GetMaxNonEmptyScriptTxPk()
 	}
+	pipeline.setPk("nep5", txPk, maxNep5PK)
 
 	now := time.Now()
 	if nProgress.LastOutputTime == (time.Time{}) {
@@ -1112,7 +1262,7 @@ func showNep5Progress(txPk uint) {
 		}
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", nProgress.InitTime, time.Now())
-		mail.SendNotify("NEP5 TX Fully Synced", msg)
+		notify.Send("NEP5 TX Fully Synced", msg)
 	}
 }
 
@@ -1,27 +1,20 @@
 package tasks
 
 import (
-	"os"
+	"context"
 	"squirrel/config"
-	"squirrel/log"
+	"squirrel/nep5"
 	"squirrel/rpc"
+	"squirrel/tx"
 	"squirrel/util"
 	"testing"
 )
 
 func TestQueryNep5AssetBalance(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
-
-	log.Init()
-	defer func() {
-		os.Remove("error.log")
-	}()
+	_, cleanup := startReplay(t, config.NetworkLegacy)
+	defer cleanup()
 
-	config.Load(false)
-
-	rpc.RefreshServers()
+	rpc.RefreshServers(context.Background())
 
 	assetID := "af7c7328eee5a275a3bcaee2bf0cf662b5e739be"
 	scriptHash := util.GetScriptHashFromAssetID(assetID)
@@ -35,9 +28,76 @@ func TestQueryNep5AssetBalance(t *testing.T) {
 		"af7c7328eee5a275a3bcaee2bf0cf662b5e739be": 8,
 	}
 
-	_, ok := queryBalances(99999999, scriptHash, assetID, addrBytesList)
+	balances, ok := queryBalances(99999999, scriptHash, assetID, addrBytesList)
 	if !ok {
 		t.Errorf("Failed to get nep5 balance from rpc")
 		return
 	}
+
+	if balances[0].String() != "1" || balances[1].Sign() != 0 {
+		t.Errorf("Expected balances 1 and 0, got %v", balances)
+	}
+}
+
+func TestGetTransferValue(t *testing.T) {
+	nep5AssetDecimals = map[string]uint8{"six": 6, "zero": 0}
+
+	for assetID, expected := range map[string]string{
+		"six":     "1.234567",
+		"zero":    "1234567",
+		"unknown": "0.01234567",
+	} {
+		value, ok := getTransferValue(assetID, "1234567", "Integer")
+		if !ok {
+			t.Fatalf("Value of %s should be extracted", assetID)
+		}
+		if actual := value.Text('f', -1); actual != expected {
+			t.Errorf("Value of %s should be %s, got %s", assetID, expected, actual)
+		}
+	}
+}
+
+func TestDiscoverNep5Asset(t *testing.T) {
+	_, cleanup := startReplay(t, config.NetworkLegacy)
+	defer cleanup()
+
+	rpc.RefreshServers(context.Background())
+
+	nep5AssetDecimals = map[string]uint8{}
+	trans := &tx.Transaction{ID: 1, TxID: "0x01", BlockIndex: 1, BlockTime: 1500000000}
+	storeChan := make(chan *nep5Store, 2)
+
+	nonNep5Contracts = map[string]bool{}
+
+	const faulted = "d1c2b3a4958677685940a1b2c3d4e5f607182930"
+	if discoverNep5Asset(storeChan, trans, faulted) || nonNep5Contracts[faulted] {
+		t.Fatalf("Contract %s should not be discovered, and queried again", faulted)
+	}
+
+	const nonNep5 = "e2c2b3a4958677685940a1b2c3d4e5f607182930"
+	if discoverNep5Asset(storeChan, trans, nonNep5) || !nonNep5Contracts[nonNep5] {
+		t.Fatalf("Contract %s should not be discovered as nep5", nonNep5)
+	}
+
+	const assetID = "3c5d2a1b4f6e7d8c9b0a1f2e3d4c5b6a79881726"
+	for i := 0; i < 2; i++ {
+		if !discoverNep5Asset(storeChan, trans, assetID) {
+			t.Fatalf("Asset %s should be discovered", assetID)
+		}
+	}
+
+	if len(storeChan) != 1 {
+		t.Fatalf("Asset should be stored once, got %d stores", len(storeChan))
+	}
+
+	d := (<-storeChan).d.(nep5DiscoveredStore)
+	if d.nep5.Name != "Proxy Token" || d.nep5.Symbol != "PXT" || d.nep5.Decimals != 6 || d.nep5.TotalSupply.String() != "1000" {
+		t.Errorf("Unexpected definitions of discovered asset: %+v", d.nep5)
+	}
+	if d.nep5.TxID != trans.TxID || d.regInfo.Author != nep5.UnknownRegInfo {
+		t.Errorf("Registration of discovered asset should be unknown, got %+v", d.regInfo)
+	}
+	if nep5AssetDecimals[assetID] != 6 {
+		t.Errorf("Decimals of discovered asset should be cached")
+	}
 }
@@ -1,40 +1,31 @@
 /*
-To restart this task from beginning, execute the following sqls:
-
-TRUNCATE TABLE `addr_asset_nft`;
-DELETE FROM `addr_tx` WHERE `asset_type` = 'nft';
-UPDATE `address` SET `trans_nft` = 0 WHERE 1=1;
-UPDATE `counter` SET
-	`last_tx_pk_for_nft` = 0,
-	`nft_app_log_idx` = -1,
-	`nft_tx_pk_for_addr_tx`=0
-WHERE `id` = 1;
-TRUNCATE TABLE `nft`;
-TRUNCATE TABLE `nft_reg_info`;
-TRUNCATE TABLE `nft_tx`;
-TRUNCATE TABLE `nft_token`;
+To restart this task from beginning, stop the indexer and run:
+
+./squirrel reset -yes nft
 
 */
 
 package tasks
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math"
 	"math/big"
 	"reflect"
 	"squirrel/cache"
+	"squirrel/config"
 	"squirrel/log"
-	"squirrel/mail"
 	"squirrel/nft"
+	"squirrel/notify"
 	"squirrel/smartcontract"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
-	"squirrel/db"
 	"squirrel/rpc"
 	"squirrel/tx"
 	"squirrel/util"
@@ -112,23 +103,32 @@ type nftCounterStore struct {
 // 	txID          string
 // }
 
-func startNftTask() {
-	nftAssetDecimals = db.GetNftAssetDecimals()
-	nftTxChan := make(chan *nftTxInfo, nftChanSize)
-	applogChan := make(chan *tx.Transaction, nftChanSize)
-	nftStoreChan := make(chan *nftStore, nftChanSize)
+func startNftTask(r *runner) {
+	nftAssetDecimals = storage.GetNftAssetDecimals()
+	size := r.channelSize(nftChanSize)
+	nftTxChan := make(chan *nftTxInfo, size)
+	applogChan := make(chan *tx.Transaction, size)
+	nftStoreChan := make(chan *nftStore, size)
+
+	lastPk, applogIdx := storage.GetLastTxPkForNft()
 
-	lastPk, applogIdx := db.GetLastTxPkForNft()
+	pipeline.trackQueue("nft_tx", func() int { return len(nftTxChan) })
+	pipeline.trackQueue("nft_applog", func() int { return len(applogChan) })
+	pipeline.trackQueue("nft_store", func() int { return len(nftStoreChan) })
 
-	go fetchNftTx(nftTxChan, applogChan, lastPk, applogIdx)
-	go fetchNftAppLog(4, applogChan)
+	r.goTask("fetch_nft_tx", func() { fetchNftTx(r.ctx, nftTxChan, applogChan, lastPk, applogIdx) })
+	fetchNftAppLog(r, r.workers(appLogWorkers), applogChan)
 
-	go handleNftTx(nftTxChan, nftStoreChan, applogIdx)
-	go handleNftStore(nftStoreChan)
+	r.goTask("handle_nft_tx", func() { handleNftTx(nftTxChan, nftStoreChan, applogIdx) })
+	r.goTask("handle_nft_store", func() { handleNftStore(nftStoreChan) })
 }
 
-func fetchNftTx(nftTxChan chan<- *nftTxInfo, applogChan chan<- *tx.Transaction, lastPk uint, applogIdx int) {
-	defer mail.AlertIfErr()
+// fetchNftTx sends transactions with their application logs to nftTxChan,
+// both channels are closed once ctx is cancelled.
+func fetchNftTx(ctx context.Context, nftTxChan chan<- *nftTxInfo, applogChan chan<- *tx.Transaction, lastPk uint, applogIdx int) {
+	defer close(nftTxChan)
+	defer close(applogChan)
+	defer notify.AlertIfErr()
 
 	// If there are some transfers in this transaction,
 	// this variable will be the last index(starts from 0).
@@ -141,26 +141,31 @@ func fetchNftTx(nftTxChan chan<- *nftTxInfo, applogChan chan<- *tx.Transaction,
 		nextTxPK++
 	}
 
-	for {
-		txs := db.GetNftInvocationTxs(nextTxPK, 1000)
+	for ctx.Err() == nil {
+		txs := storage.GetNftInvocationTxs(nextTxPK, 1000)
+		profile := config.GetProfile()
 
 		for i := len(txs) - 1; i >= 0; i-- {
 			// cannot be app call
 			if len(txs[i].Script) <= 42 ||
-				txs[i].TxID == "0xb00a0d7b752ba935206e1db67079c186ba38a4696d3afe28814a4834b2254cbe" {
+				profile.IsSkippedTx(txs[i].TxID) {
 				txs = append(txs[:i], txs[i+1:]...)
 			}
 		}
 
 		if len(txs) == 0 {
-			time.Sleep(2 * time.Second)
+			sleep(ctx, 2*time.Second)
 			continue
 		}
 
 		nextTxPK = txs[len(txs)-1].ID + 1
 
 		for _, tx := range txs {
-			applogChan <- tx
+			select {
+			case applogChan <- tx:
+			case <-ctx.Done():
+				return
+			}
 		}
 
 		for _, tx := range txs {
@@ -168,6 +173,11 @@ func fetchNftTx(nftTxChan chan<- *nftTxInfo, applogChan chan<- *tx.Transaction,
 				// Get applicationlog from map.
 				appLogResult, ok := nftAppLogs.Load(tx.TxID)
 				if !ok {
+					// Transactions without application log will be fetched again on next start.
+					if ctx.Err() != nil {
+						return
+					}
+
 					time.Sleep(5 * time.Millisecond)
 					continue
 				}
@@ -187,23 +197,32 @@ func fetchNftTx(nftTxChan chan<- *nftTxInfo, applogChan chan<- *tx.Transaction,
 	}
 }
 
-func fetchNftAppLog(goroutines int, applogChan <-chan *tx.Transaction) {
-	defer mail.AlertIfErr()
+func fetchNftAppLog(r *runner, goroutines int, applogChan <-chan *tx.Transaction) {
+	ctx := r.ctx
 
 	for i := 0; i < goroutines; i++ {
-		go func(ch <-chan *tx.Transaction) {
-			for tx := range ch {
-				appLogResult := rpc.GetApplicationLog(int(tx.BlockIndex), tx.TxID)
-				nftAppLogs.Store(tx.TxID, appLogResult)
+		r.goTask("fetch_nft_applog", func() {
+			defer notify.AlertIfErr()
+
+			for tx := range applogChan {
+				txs := receiveAppLogBatch(applogChan, tx)
+				fetchAppLogs(ctx, "nft", txs, func(txID string, appLog *rpc.RawApplicationLogResult) {
+					nftAppLogs.Store(txID, appLog)
+				})
 			}
-		}(applogChan)
+		})
 	}
 }
 
 func handleNftTx(nftTxChan <-chan *nftTxInfo, nftStoreChan chan<- *nftStore, applogIdx int) {
-	defer mail.AlertIfErr()
+	defer close(nftStoreChan)
+	defer notify.AlertIfErr()
 
 	for nftInfo := range nftTxChan {
+		if atomic.CompareAndSwapInt32(&nftAssetsShouldReload, 1, 0) {
+			nftAssetDecimals = storage.GetNftAssetDecimals()
+		}
+
 		tx := nftInfo.tx
 		opCodeDataStack := nftInfo.dataStack
 		appLogResult := nftInfo.appLogResult
@@ -295,7 +314,7 @@ func handleNftTx(nftTxChan <-chan *nftTxInfo, nftStoreChan chan<- *nftStore, app
 // }
 
 func handleNftStore(nftStore <-chan *nftStore) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	for s := range nftStore {
 		txPK := uint(0)
@@ -327,7 +346,7 @@ func handleNftAssetStore(s *nftStore) uint {
 		panic(err)
 	}
 
-	err := db.InsertNftAsset(d.tx,
+	err := storage.InsertNftAsset(d.tx,
 		d.nft,
 		d.regInfo,
 		d.atHeight)
@@ -345,7 +364,7 @@ func handleNftTxStore(s *nftStore) uint {
 		panic(err)
 	}
 
-	err := db.InsertNftTransaction(
+	err := storage.InsertNftTransaction(
 		d.tx,
 		d.applogIdx,
 		d.assetID,
@@ -372,7 +391,7 @@ func handleNftBalanceTotalSupplyStore(s *nftStore) uint {
 		panic(err)
 	}
 
-	err := db.UpdateNftTotalSupplyAndAddrAsset(
+	err := storage.UpdateNftTotalSupplyAndAddrAsset(
 		d.blockTime,
 		d.blockIndex,
 		d.assetID,
@@ -391,7 +410,7 @@ func handleNftCounterStore(s *nftStore) uint {
 		panic(err)
 	}
 
-	err := db.UpdateLastTxPkForNft(d.txPK, d.applogIdx)
+	err := storage.UpdateLastTxPkForNft(d.txPK, d.applogIdx)
 	if err != nil {
 		panic(err)
 	}
@@ -445,7 +464,7 @@ func handleNftRegTx(nftStoreChan chan<- *nftStore, tx *tx.Transaction, opCodeDat
 // 		panic(err)
 // 	}
 
-// 	err := db.HandleNftMigrate(d.newAssetAdmin, d.oldAssetID, d.newAssetID, d.txPK, d.txID)
+// 	err := storage.HandleNftMigrate(d.newAssetAdmin, d.oldAssetID, d.newAssetID, d.txPK, d.txID)
 // 	if err != nil {
 // 		panic(err)
 // 	}
@@ -458,7 +477,7 @@ func handleNftNonTxCall(nftStoreChan chan<- *nftStore, tx *tx.Transaction, opCod
 	for len(*opCodeDataStack) >= 2 {
 		opCode, data := opCodeDataStack.PopItem()
 
-		if opCode != 0x67 { // APPCALL
+		if opCode != byte(smartcontract.APPCALL) {
 			continue
 		}
 
@@ -550,7 +569,7 @@ func recordNftTransfer(nftStoreChan chan<- *nftStore, tx *tx.Transaction, assetI
 	toAddr := util.GetAddressFromScriptHash(to)
 
 	if len(fromAddr) > 128 || len(toAddr) > 128 {
-		log.Error.Printf("TxID: %s, from=%s, to=%s", tx.TxID, fromAddr, toAddr)
+		log.With(log.Fields{"task": "nft", "txid": tx.TxID}).Errorf("Invalid transfer addresses from=%s, to=%s\n", fromAddr, toAddr)
 		return
 	}
 
@@ -581,7 +600,7 @@ func recordNftTransfer(nftStoreChan chan<- *nftStore, tx *tx.Transaction, assetI
 
 	nftJSONInfo := ""
 	// Query NFT token info if it's new.
-	if tokenID != "" && !db.NftTokenExists(assetID, tokenID) {
+	if tokenID != "" && !storage.NftTokenExists(assetID, tokenID) {
 		nftJSONInfo, _ = queryNFTTokenInfo(tx, scriptHash, tokenID)
 	}
 
@@ -616,7 +635,7 @@ func queryNFTTokenInfo(tx *tx.Transaction, scriptHash []byte, tokenID string) (s
 	// in this case, or the tokenID is just invalid.
 
 	for i := 0; i < 3; i++ {
-		result := rpc.SmartContractRPCCall(minHeight, scripts)
+		result := invokeScript(minHeight, scripts)
 		if result == nil || strings.Contains(result.State, "FAULT") {
 			time.Sleep(1 * time.Second)
 			continue
@@ -713,7 +732,7 @@ func queryNftAssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 	scripts += createSCSB(scriptHash, "totalSupply", nil)
 
 	minHeight := getMinHeight(tx.BlockIndex)
-	result := rpc.SmartContractRPCCall(minHeight, scripts)
+	result := invokeScript(minHeight, scripts)
 	if result == nil || strings.Contains(result.State, "FAULT") {
 		return nil, 0, false
 	}
@@ -729,7 +748,7 @@ func queryNftAssetInfo(tx *tx.Transaction, scriptHash []byte, addrBytes []byte)
 	if err != nil {
 		return nil, 0, false
 	}
-	name := strings.Replace(string(nameBytes), "'", "\\'", -1)
+	name := string(nameBytes)
 	if name == "" {
 		return nil, 0, false
 	}
@@ -805,7 +824,7 @@ func queryNftCallerBalance(txBlockIndex uint, blockTime uint64, scriptHash []byt
 	scripts := createSCSB(scriptHash, "balanceOf", [][]byte{callerAddrBytes})
 
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, scripts)
+	result := invokeScript(minHeight, scripts)
 	if result == nil ||
 		strings.Contains(result.State, "FAULT") ||
 		result.Stack == nil ||
@@ -852,7 +871,7 @@ func queryNftBalances(txBlockIndex uint, scriptHash []byte, assetID string, addr
 	}
 
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, scsb)
+	result := invokeScript(minHeight, scsb)
 
 	// If this nft asset is broken(for example forgot to check 'need storage').
 	if result == nil || strings.Contains(result.State, "FAULT") {
@@ -895,7 +914,7 @@ func queryNftTotalSupply(txBlockIndex uint, blockTime uint64, scriptHash []byte)
 
 	totalSupplyScsb := createSCSB(scriptHash, "totalSupply", nil)
 	minHeight := rpc.BestHeight.Get()
-	result := rpc.SmartContractRPCCall(minHeight, totalSupplyScsb)
+	result := invokeScript(minHeight, totalSupplyScsb)
 	if result == nil || strings.Contains(result.State, "FAULT") {
 		return nil, false
 	}
@@ -954,8 +973,9 @@ func getNftReadableValue(assetID string, balance *big.Float) *big.Float {
 func showNftProgress(txPk uint) {
 	if maxNftPK == 0 || nftMaxPkShouldRefresh {
 		nftMaxPkShouldRefresh = false
-		maxNftPK = db.GetMaxNonEmptyScriptTxPk()
+		maxNftPK = storage.GetMaxNonEmptyScriptTxPk()
 	}
+	pipeline.setPk("nft", txPk, maxNftPK)
 
 	now := time.Now()
 	if nftProgress.LastOutputTime == (time.Time{}) {
@@ -988,6 +1008,6 @@ func showNftProgress(txPk uint) {
 		}
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", nftProgress.InitTime, time.Now())
-		mail.SendNotify("nft TX Fully Synced", msg)
+		notify.Send("nft TX Fully Synced", msg)
 	}
 }
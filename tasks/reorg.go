@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"squirrel/log"
+	"squirrel/notify"
+	"squirrel/rpc"
+	"sync/atomic"
+)
+
+// maxReorgDepth is the maximum number of blocks to walk back when looking for the fork point.
+const maxReorgDepth = 1000
+
+var (
+	// nep5AssetsShouldReload is set to 1 if cached nep5 decimals should be reloaded from db.
+	nep5AssetsShouldReload int32
+	// nftAssetsShouldReload is set to 1 if cached nft decimals should be reloaded from db.
+	nftAssetsShouldReload int32
+)
+
+// blockChain keeps the hash of the last persisted block,
+// so every incoming block can be checked to extend it.
+type blockChain struct {
+	nextIndex int
+	lastHash  string
+	// rewinds notifies arrangeBlock of the fork point blocks must be rearranged from.
+	rewinds chan<- int
+}
+
+func newBlockChain(dbHeight int, rewinds chan<- int) *blockChain {
+	return &blockChain{
+		nextIndex: dbHeight + 1,
+		rewinds:   rewinds,
+	}
+}
+
+// links tells if the given block extends the persisted chain.
+func (c *blockChain) links(b *rpc.RawBlock) bool {
+	if c.nextIndex == 0 {
+		return true
+	}
+
+	if c.lastHash == "" {
+		c.lastHash = storage.GetBlockHash(c.nextIndex - 1)
+		// Nothing to compare with.
+		if c.lastHash == "" {
+			return true
+		}
+	}
+
+	return b.PreviousBlockHash == c.lastHash
+}
+
+func (c *blockChain) append(b *rpc.RawBlock) {
+	c.nextIndex = int(b.Index) + 1
+	c.lastHash = b.Hash
+}
+
+// rewind rolls back all persisted blocks above the fork point
+// and restarts block arrangement from there.
+// Other tasks are stopped during the rollback and started again from their rewound counters.
+// Blocks queued in ch before arrangeBlock restarted are discarded,
+// it returns false if ch is closed or ctx is cancelled meanwhile.
+func (c *blockChain) rewind(ctx context.Context, ch <-chan *rpc.RawBlock) bool {
+	height := c.nextIndex - 1
+	fork := findForkHeight(height)
+
+	msg := fmt.Sprintf("Chain reorganisation detected at height %d, rolling back to %d", height, fork)
+	log.Error.Println(msg)
+	notify.Send("Chain Reorganisation", msg)
+
+	// The fork is detected again on next start.
+	resume := pauseDependents(ctx)
+	if resume == nil {
+		return false
+	}
+	defer resume()
+
+	if err := storage.RollbackToHeight(fork); err != nil {
+		panic(err)
+	}
+
+	c.nextIndex = fork + 1
+	c.lastHash = ""
+
+	// Assets registered in orphaned blocks no longer exist.
+	atomic.StoreInt32(&nep5AssetsShouldReload, 1)
+	atomic.StoreInt32(&nftAssetsShouldReload, 1)
+
+	TxMaxPkShouldRefresh = true
+	AssetTxMaxPkShouldRefresh = true
+	Nep5MaxPkShouldRefresh = true
+	nftMaxPkShouldRefresh = true
+	gasMaxPkShouldRefresh = true
+	scMaxPkShouldRefresh = true
+
+	// Orphaned blocks may still be queued, and the one after the fork point links to it.
+	// arrangeBlock answers the rewind with a nil block, which ends the stale ones.
+	rewinds := c.rewinds
+	for {
+		select {
+		case rewinds <- fork:
+			rewinds = nil
+		case b, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if b == nil && rewinds == nil {
+				return true
+			}
+		}
+	}
+}
+
+// findForkHeight returns the highest persisted block which is still on the main chain.
+func findForkHeight(height int) int {
+	for h := height; h >= 0 && h > height-maxReorgDepth; h-- {
+		hash := storage.GetBlockHash(h)
+		if hash == "" {
+			return h
+		}
+
+		// Rollback must not be interrupted once started.
+		b, err := rpc.DownloadBlock(context.Background(), h)
+		if err != nil && !errors.Is(err, rpc.ErrNotFound) {
+			panic(err)
+		}
+		if b != nil && b.Hash == hash {
+			return h
+		}
+	}
+
+	panic(fmt.Errorf("failed to find fork point within %d blocks below height %d", maxReorgDepth, height))
+}
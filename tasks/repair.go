@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"squirrel/cache"
+	"squirrel/db"
+	"squirrel/log"
+	"squirrel/rpc"
+	"squirrel/util"
+)
+
+// repairBatchSize is the number of holders whose balances are queried per 'invokescript' call.
+const repairBatchSize = 50
+
+// RepairNep5Decimals fixes nep5 data stored while transfer values were divided by 10^8 regardless of decimals:
+// values of the affected transfers are rescaled once,
+// and balances of all holders of the affected assets are queried again from rpc servers.
+// Tasks must not be running meanwhile, it can be run again if interrupted.
+func RepairNep5Decimals(ctx context.Context, s db.Store) error {
+	storage = s
+
+	repairs, err := storage.GetNep5DecimalsRepairs()
+	if err != nil {
+		return err
+	}
+	if len(repairs) == 0 {
+		log.Printf("No nep5 transfer needs repair\n")
+		return nil
+	}
+
+	cache.LoadAddrAssetInfo(storage.GetAddrAssetInfo())
+	nep5AssetDecimals = storage.GetNep5AssetDecimals()
+	height := rpc.RefreshServers(ctx)
+
+	// Balances are queried at the best height, addresses created by the repair are dated by its block.
+	b, err := rpc.DownloadBlock(ctx, height)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range repairs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		n, err := storage.RepairNep5TxValues(r.AssetID)
+		if err != nil {
+			return err
+		}
+		log.Printf("Repaired %d transfers of nep5 asset %s(decimals=%d)\n", n, r.AssetID, r.Decimals)
+
+		if err := repairNep5Balances(r.AssetID, uint(height), b.Time); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repairNep5Balances updates balances of all holders of the asset to the ones at height,
+// zero balances included.
+func repairNep5Balances(assetID string, height uint, blockTime uint64) error {
+	holders, err := storage.GetNep5Holders(assetID)
+	if err != nil {
+		return err
+	}
+
+	scriptHash := util.GetScriptHashFromAssetID(assetID)
+
+	for start := 0; start < len(holders); start += repairBatchSize {
+		end := start + repairBatchSize
+		if end > len(holders) {
+			end = len(holders)
+		}
+
+		addrBytesList := [][]byte{}
+		for _, holder := range holders[start:end] {
+			addrBytesList = append(addrBytesList, util.GetScriptHashFromAddress(holder))
+		}
+
+		balances, ok := queryBalances(height, scriptHash, assetID, addrBytesList)
+		if !ok {
+			return fmt.Errorf("failed to query balances of nep5 asset %s", assetID)
+		}
+
+		for i, balance := range balances {
+			if balance == nil {
+				continue
+			}
+
+			err := storage.UpdateNep5TotalSupplyAndAddrAsset(blockTime, height, holders[start+i], balance, assetID, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("Repaired balances of %d holders of nep5 asset %s\n", len(holders), assetID)
+	return nil
+}
@@ -0,0 +1,259 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"squirrel/config"
+	"squirrel/db"
+	"squirrel/log"
+	"squirrel/rpc"
+	"testing"
+	"time"
+)
+
+// startReplay serves fixtures of the network and loads a config using it with a sqlite database.
+// testdata/replay.json holds legacy blocks 0-4 with an issue, a contract and a nep5 transfer transaction.
+// testdata/replay_n3.json holds N3 blocks 0-2 with a contract deployment, a NEP-11 transfer and a faulted transaction.
+func startReplay(t *testing.T, network string) (*rpc.ReplayHandler, func()) {
+	replay, _, cleanup := startReplayConfig(t, network, "")
+	return replay, cleanup
+}
+
+// startReplayConfig is startReplay with extra configs appended to the config,
+// it also returns a function which loads the config again with other extra configs.
+func startReplayConfig(t *testing.T, network string, extra string) (*rpc.ReplayHandler, func(extra string), func()) {
+	log.Init()
+
+	path := "testdata/replay.json"
+	if network == config.NetworkN3 {
+		path = "testdata/replay_n3.json"
+	}
+
+	fixtures, err := rpc.LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := rpc.NewReplayHandler(fixtures)
+	srv := httptest.NewServer(replay)
+
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(extra string) {
+		cfg := fmt.Sprintf(`{
+			"driver": "sqlite",
+			"database": %q,
+			"rpc_url": [%q],
+			"network": %q,
+			"workers": 1%s
+		}`, filepath.Join(dir, "squirrel.db"), srv.URL, network, extra)
+
+		cfgPath := filepath.Join(dir, "config.json")
+		if err := ioutil.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.LoadFile(cfgPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load(extra)
+
+	return replay, load, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+		os.Remove("error.log")
+	}
+}
+
+func TestRunReplay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	replay, cleanup := startReplay(t, config.NetworkLegacy)
+	defer cleanup()
+
+	store := db.Init()
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, store, store.GetLastHeight(), nil)
+		close(done)
+	}()
+
+	// Only the issue and contract transactions have vins or vouts.
+	synced := func(c db.Counter) bool {
+		return c.LastBlockIndex == 4 &&
+			c.LastTxPk == 5 &&
+			c.LastTxPkForNep5 == 7 &&
+			c.Nep5TxPkForAddrTx > 0
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for !synced(store.GetCounter()) && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if c := store.GetCounter(); !synced(c) {
+		t.Fatalf("Expected all tasks to sync blocks 0-4, got counter %+v, requests not recorded: %v", c, replay.Missing())
+	}
+
+	assets, err := store.GetAddrAssets("AGpZWheMgyQDASZJr5iCdpbMNFrWt4k8aX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].Balance.String() != "10" {
+		t.Errorf("Expected NEO balance 10 of the receiver, got %+v", assets)
+	}
+
+	transfers, err := store.GetNep5Transfers("af7c7328eee5a275a3bcaee2bf0cf662b5e739be", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || transfers[0].To != "AGpZWheMgyQDASZJr5iCdpbMNFrWt4k8aX" {
+		t.Errorf("Expected one nep5 transfer to the receiver, got %+v", transfers)
+	}
+}
+
+func TestRunReloadTasks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	_, load, cleanup := startReplayConfig(t, config.NetworkLegacy, `,
+		"tasks": {"block": {"enabled": true}}`)
+	defer cleanup()
+
+	store := db.Init()
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, store, store.GetLastHeight(), nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor := func(desc string, cond func() bool) {
+		deadline := time.Now().Add(30 * time.Second)
+		for !cond() && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if !cond() {
+			t.Fatalf("Timed out waiting for %s, counter %+v", desc, store.GetCounter())
+		}
+	}
+
+	waitFor("blocks", func() bool { return store.GetCounter().LastBlockIndex == 4 })
+	time.Sleep(500 * time.Millisecond)
+	if c := store.GetCounter(); c.LastTxPk != 0 || c.LastTxPkForNep5 != 0 {
+		t.Fatalf("Only block task should run, got counter %+v", c)
+	}
+
+	load(`,
+		"tasks": {"block": {"enabled": true}, "tx": {"enabled": true, "channel_size": 10}}`)
+	reload <- struct{}{}
+	waitFor("tx task to start", func() bool { return store.GetCounter().LastTxPk == 5 })
+
+	load(`,
+		"tasks": {"block": {"enabled": true}}`)
+	reload <- struct{}{}
+	waitFor("tx task to stop", func() bool {
+		_, ok := pipeline.snapshot()["tx"]
+		return !ok
+	})
+}
+
+func TestRoundDelay(t *testing.T) {
+	expected := []time.Duration{0, minRoundDelay, 2 * minRoundDelay, 4 * minRoundDelay}
+	for round, d := range expected {
+		if roundDelay(round) != d {
+			t.Errorf("Expected delay %v of round %d, got %v", d, round, roundDelay(round))
+		}
+	}
+
+	if roundDelay(100) != maxRoundDelay {
+		t.Errorf("Expected maximum delay, got %v", roundDelay(100))
+	}
+}
+
+func TestRunPauseDependents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	_, _, cleanup := startReplayConfig(t, config.NetworkLegacy, `,
+		"tasks": {"block": {"enabled": true}, "tx": {"enabled": true}}`)
+	defer cleanup()
+
+	store := db.Init()
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, store, store.GetLastHeight(), nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Queues are tracked from start to stop of tasks.
+	running := func(queue string) bool {
+		pipeline.mu.Lock()
+		defer pipeline.mu.Unlock()
+
+		_, ok := pipeline.queues[queue]
+		return ok
+	}
+	txRunning := func() bool { return running("tx") }
+
+	deadline := time.Now().Add(30 * time.Second)
+	for store.GetCounter().LastTxPk != 5 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	resume := pauseDependents(ctx)
+	if resume == nil {
+		t.Fatal("Expected tasks to be paused")
+	}
+	if txRunning() {
+		t.Fatal("Expected the tx task to be stopped while paused")
+	}
+	if !running("block_channel") {
+		t.Fatal("Expected the block task to keep running while paused")
+	}
+
+	resume()
+	deadline = time.Now().Add(5 * time.Second)
+	for !txRunning() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !txRunning() {
+		t.Fatal("Expected the tx task to be started again")
+	}
+}
@@ -0,0 +1,271 @@
+package tasks
+
+import (
+	"context"
+	"squirrel/config"
+	"squirrel/log"
+	"sync"
+)
+
+// reload is signaled when configs are reloaded, so tasks are started or stopped following them.
+var reload = make(chan struct{}, 1)
+
+func init() {
+	config.OnChange(func() {
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// pauses are requests of the block task to stop all other tasks while it rolls back blocks.
+var pauses = make(chan *pause)
+
+// pause stops tasks depending on persisted blocks until resumed is closed.
+type pause struct {
+	paused  chan struct{}
+	resumed chan struct{}
+}
+
+// pauseDependents stops all tasks but the block task, so they do not keep cursors past rolled back rows.
+// The returned func starts them again, they resume from their counters.
+// It returns nil if ctx is cancelled before the tasks are stopped.
+func pauseDependents(ctx context.Context) func() {
+	p := &pause{paused: make(chan struct{}), resumed: make(chan struct{})}
+
+	select {
+	case pauses <- p:
+	case <-ctx.Done():
+		return nil
+	}
+
+	<-p.paused
+	return func() { close(p.resumed) }
+}
+
+// taskMetrics are names of progress and queues reported by each task.
+var taskMetrics = map[string][]string{
+	config.TaskBlock:      {"block", "block_buffer", "block_channel"},
+	config.TaskTx:         {"tx"},
+	config.TaskNep5:       {"nep5", "nep5_tx", "nep5_applog", "nep5_store"},
+	config.TaskNft:        {"nft", "nft_tx", "nft_applog", "nft_store"},
+	config.TaskAddrTx:     {"nep5_addr_tx", "nft_addr_tx"},
+	config.TaskAssetTx:    {"asset_tx"},
+	config.TaskGasBalance: {"gas_balance"},
+	config.TaskSC:         {"sc"},
+}
+
+// runner runs goroutines of a started task.
+type runner struct {
+	name   string
+	cfg    config.TaskConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// goTask runs f as a goroutine of the task, it must return once r.ctx is cancelled.
+func (r *runner) goTask(name string, f func()) {
+	r.wg.Add(1)
+	goTask(r.ctx, r.name, name, func() {
+		defer r.wg.Done()
+		f()
+	})
+}
+
+// goWorker runs f as a goroutine of the task which may return before r.ctx is cancelled.
+func (r *runner) goWorker(f func()) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		f()
+	}()
+}
+
+// workers returns the configured number of workers, or def if not set.
+func (r *runner) workers(def int) int {
+	if r.cfg.Workers > 0 {
+		return r.cfg.Workers
+	}
+	return def
+}
+
+// channelSize returns the configured channel size, or def if not set.
+func (r *runner) channelSize(def int) int {
+	if r.cfg.ChannelSize > 0 {
+		return r.cfg.ChannelSize
+	}
+	return def
+}
+
+// stop cancels the task and waits until it has flushed pending data and updated its counter.
+func (r *runner) stop() {
+	r.cancel()
+	r.wg.Wait()
+
+	pipeline.untrack(taskMetrics[r.name]...)
+	Health.forget(taskMetrics[r.name]...)
+	Health.stopped(r.name)
+}
+
+// scheduler starts and stops tasks following configs.
+type scheduler struct {
+	ctx context.Context
+	// height is where block sync starts on first start of the block task,
+	// it resumes from the last persisted block on later starts.
+	height       int
+	blockStarted bool
+	// selection overrides tasks enabled in config if not nil.
+	selection Selection
+	running   map[string]*runner
+}
+
+func newScheduler(ctx context.Context, height int, selection Selection) *scheduler {
+	return &scheduler{
+		ctx:       ctx,
+		height:    height,
+		selection: selection,
+		running:   make(map[string]*runner),
+	}
+}
+
+// run applies configs until ctx is cancelled, then stops all tasks.
+func (s *scheduler) run() {
+	s.apply()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.stopAll()
+			return
+		case <-reload:
+			s.apply()
+		case p := <-pauses:
+			s.pause(p)
+		}
+	}
+}
+
+// apply stops tasks which are disabled or whose configs changed, then starts enabled tasks which are not running.
+func (s *scheduler) apply() {
+	enabled := s.enabled()
+
+	for i := len(config.TaskNames) - 1; i >= 0; i-- {
+		name := config.TaskNames[i]
+		r, ok := s.running[name]
+		if !ok {
+			continue
+		}
+
+		c, keep := enabled[name]
+		if keep && c == r.cfg {
+			continue
+		}
+
+		log.With(log.Fields{"task": name}).Infof("Stopping task\n")
+		r.stop()
+		delete(s.running, name)
+		log.With(log.Fields{"task": name}).Infof("Task stopped\n")
+	}
+
+	for _, name := range config.TaskNames {
+		c, ok := enabled[name]
+		if !ok || s.running[name] != nil {
+			continue
+		}
+
+		log.With(log.Fields{"task": name, "workers": c.Workers, "channel_size": c.ChannelSize}).Infof("Starting task\n")
+		s.running[name] = s.start(name, c)
+	}
+}
+
+// pause stops running tasks but the block task until p is resumed, then starts enabled tasks again.
+func (s *scheduler) pause(p *pause) {
+	for i := len(config.TaskNames) - 1; i >= 0; i-- {
+		name := config.TaskNames[i]
+		r, ok := s.running[name]
+		if !ok || name == config.TaskBlock {
+			continue
+		}
+
+		log.With(log.Fields{"task": name}).Infof("Pausing task for rollback\n")
+		r.stop()
+		delete(s.running, name)
+	}
+
+	close(p.paused)
+	<-p.resumed
+
+	if s.ctx.Err() == nil {
+		s.apply()
+	}
+}
+
+// enabled returns configs of tasks which should be running.
+func (s *scheduler) enabled() map[string]config.TaskConfig {
+	configs := config.GetTasks()
+	if s.selection == nil {
+		return configs
+	}
+
+	enabled := map[string]config.TaskConfig{}
+	for name := range s.selection {
+		if name != config.TaskBlock && config.GetNetwork() == config.NetworkN3 {
+			continue
+		}
+		c := configs[name]
+		c.Enabled = true
+		enabled[name] = c
+	}
+	return enabled
+}
+
+func (s *scheduler) start(name string, c config.TaskConfig) *runner {
+	ctx, cancel := context.WithCancel(s.ctx)
+	r := &runner{name: name, cfg: c, ctx: ctx, cancel: cancel}
+
+	switch name {
+	case config.TaskBlock:
+		height := s.height
+		if s.blockStarted {
+			height = LastHeight(storage)
+		}
+		s.blockStarted = true
+
+		if config.GetNetwork() == config.NetworkN3 {
+			startN3BlockTask(r, height)
+		} else {
+			startBlockTask(r, height)
+		}
+	case config.TaskTx:
+		startTxTask(r)
+	case config.TaskNep5:
+		startNep5Task(r)
+	case config.TaskNft:
+		startNftTask(r)
+	case config.TaskAddrTx:
+		startUpdateCounterTask(r)
+	case config.TaskAssetTx:
+		startAssetTxTask(r)
+	case config.TaskGasBalance:
+		startGasBalanceTask(r)
+	case config.TaskSC:
+		startSCTask(r)
+	}
+
+	return r
+}
+
+// stopAll waits for all tasks, which are cancelled together with the scheduler.
+func (s *scheduler) stopAll() {
+	var wg sync.WaitGroup
+	for _, r := range s.running {
+		wg.Add(1)
+		go func(r *runner) {
+			defer wg.Done()
+			r.stop()
+		}(r)
+	}
+	wg.Wait()
+}
@@ -1,20 +1,21 @@
 /*
-To restart this task from beginning, execute the following sqls:
+To restart this task from beginning, stop the indexer and run:
 
-UPDATE `counter` SET `last_tx_pk_for_sc` = 0 WHERE `id` = 1 LIMIT 1;
-TRUNCATE TABLE `smartcontract_info`;
+./squirrel reset -yes sc
 
 */
 
 package tasks
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"squirrel/config"
 	"squirrel/db"
 	"squirrel/log"
-	"squirrel/mail"
 	"squirrel/nep5"
+	"squirrel/notify"
 	"squirrel/smartcontract"
 	"strings"
 	"time"
@@ -37,62 +38,67 @@ type scStore struct {
 type scriptInfo struct {
 	txID   string
 	script string
+	// skipped scripts are only disassembled.
+	skipped bool
 }
 
-func startSCTask() {
-	scTxChan := make(chan scStore, scChanSize)
+func startSCTask(r *runner) {
+	scTxChan := make(chan scStore, r.channelSize(scChanSize))
+	pipeline.trackQueue("sc", func() int { return len(scTxChan) })
 
-	lastPk := db.GetLastTxPkForSC()
+	lastPk := storage.GetLastTxPkForSC()
 
-	go fetchSCTx(scTxChan, lastPk)
-	go handleScTx(scTxChan)
+	r.goTask("fetch_sc_tx", func() { fetchSCTx(r.ctx, scTxChan, lastPk) })
+	r.goTask("handle_sc_tx", func() { handleScTx(scTxChan) })
 }
 
-func fetchSCTx(scTxChan chan<- scStore, lastPk uint) {
-	defer mail.AlertIfErr()
+// fetchSCTx sends scripts of invocation transactions to scTxChan,
+// which is closed once ctx is cancelled.
+func fetchSCTx(ctx context.Context, scTxChan chan<- scStore, lastPk uint) {
+	defer close(scTxChan)
+	defer notify.AlertIfErr()
 
 	nextTxPK := lastPk + 1
 
-	for {
-		txs := db.GetInvocationTxs(nextTxPK, 1000)
-
-		for i := len(txs) - 1; i >= 0; i-- {
-			// cannot be app call
-			if len(txs[i].Script) <= 42 ||
-				txs[i].TxID == "0xb00a0d7b752ba935206e1db67079c186ba38a4696d3afe28814a4834b2254cbe" {
-				txs = append(txs[:i], txs[i+1:]...)
-			}
-		}
-
+	for ctx.Err() == nil {
+		txs := storage.GetInvocationTxs(nextTxPK, 1000)
 		if len(txs) == 0 {
-			time.Sleep(2 * time.Second)
+			sleep(ctx, 2*time.Second)
 			continue
 		}
 
 		nextTxPK = txs[len(txs)-1].ID + 1
+		profile := config.GetProfile()
 
 		scriptInfoList := []scriptInfo{}
 		for _, tx := range txs {
 			scriptInfoList = append(scriptInfoList, scriptInfo{
 				txID:   tx.TxID,
 				script: tx.Script,
+				// cannot be app call
+				skipped: len(tx.Script) <= 42 || profile.IsSkippedTx(tx.TxID),
 			})
 		}
 
-		scTxChan <- scStore{
+		select {
+		case scTxChan <- scStore{
 			scriptInfoList: scriptInfoList,
 			txPK:           txs[len(txs)-1].ID + 1,
+		}:
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
 func handleScTx(scTxChan <-chan scStore) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	for scInfo := range scTxChan {
 		scRegInfos := filterSC(scInfo.scriptInfoList)
-		if len(scRegInfos) > 0 {
-			db.InsertSCInfos(scRegInfos, scInfo.txPK)
+		disassemblies := disassembleScripts(scInfo.scriptInfoList)
+		if err := storage.InsertSCInfos(scRegInfos, disassemblies, scInfo.txPK); err != nil {
+			panic(err)
 		}
 
 		showSCProgress(scInfo.txPK)
@@ -103,6 +109,10 @@ func filterSC(list []scriptInfo) []*nep5.RegInfo {
 	result := []*nep5.RegInfo{}
 
 	for _, info := range list {
+		if info.skipped {
+			continue
+		}
+
 		if !strings.HasSuffix(info.script, "4e656f2e436f6e74726163742e437265617465") &&
 			!isNep5RegistrationTx(info.script) &&
 			!isNep5MigrateTx(info.script) {
@@ -125,11 +135,37 @@ func filterSC(list []scriptInfo) []*nep5.RegInfo {
 	return result
 }
 
+// disassembleScripts returns disassembled scripts of the list,
+// instructions before an invalid one are kept and followed by the error.
+func disassembleScripts(list []scriptInfo) []db.TxDisassembly {
+	result := []db.TxDisassembly{}
+
+	for _, info := range list {
+		if info.script == "" {
+			continue
+		}
+
+		instructions, err := smartcontract.Disassemble(info.script)
+		text := smartcontract.Format(instructions)
+		if err != nil {
+			text += fmt.Sprintf("; %v\n", err)
+		}
+
+		result = append(result, db.TxDisassembly{
+			TxID:        info.txID,
+			Disassembly: text,
+		})
+	}
+
+	return result
+}
+
 func showSCProgress(txPk uint) {
 	if maxScPK == 0 || scMaxPkShouldRefresh {
 		scMaxPkShouldRefresh = false
-		maxScPK = db.GetMaxNonEmptyScriptTxPk()
+		maxScPK = storage.GetMaxNonEmptyScriptTxPk()
 	}
+	pipeline.setPk("sc", txPk, maxScPK)
 
 	now := time.Now()
 	if scProgress.LastOutputTime == (time.Time{}) {
@@ -162,6 +198,6 @@ func showSCProgress(txPk uint) {
 		}
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", scProgress.InitTime, time.Now())
-		mail.SendNotify("NEP5 TX Fully Synced", msg)
+		notify.Send("NEP5 TX Fully Synced", msg)
 	}
 }
@@ -1,45 +1,164 @@
 package tasks
 
 import (
-	"squirrel/buffer"
+	"context"
+	"errors"
+	"fmt"
 	"squirrel/cache"
 	"squirrel/config"
 	"squirrel/db"
 	"squirrel/log"
 	"squirrel/rpc"
+	"squirrel/tx"
+	"strings"
+	"time"
 )
 
-// Run starts several goroutines for block storage, tx/nep5 tx storage, etc.
-func Run(height int) {
-	log.Printf("Init addr asset cache.")
+// storage persists all data of the tasks.
+var storage db.Store
 
-	// Init cache to speed up db queries
-	addrAssetInfo := db.GetAddrAssetInfo()
-	cache.LoadAddrAssetInfo(addrAssetInfo)
-	// dbHeight := db.GetLastHeight()
-	initTask(height)
-	for i := 0; i < config.GetGoroutines(); i++ {
-		go fetchBlock()
+// Selection is the set of tasks to run.
+type Selection map[string]bool
+
+// ParseSelection returns the tasks of a comma separated list,
+// or nil if the list is empty, so tasks enabled in config run.
+func ParseSelection(list string) (Selection, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
 	}
-	blockChannel = make(chan *rpc.RawBlock, bufferSize)
-	go arrangeBlock(height, blockChannel)
-	go storeBlock(blockChannel)
 
-	go startNep5Task()
-	go startTxTask()
-	go startUpdateCounterTask()
+	selection := Selection{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if !config.IsTask(name) {
+			return nil, fmt.Errorf("unknown task '%s', must be one of %s", name, strings.Join(config.TaskNames, ", "))
+		}
+		selection[name] = true
+	}
 
-	// go startNftTask()
-	// go startAssetTxTask()
-	// go startGasBalanceTask()
-	// go startSCTask()
+	return selection, nil
+}
+
+// Run starts the goroutines of enabled tasks for block storage, tx/nep5 tx storage, etc.
+// Tasks are the ones in selection, or the ones enabled in config if it is nil,
+// which are started and stopped as config changes.
+// It blocks until ctx is cancelled and every task has drained its buffered data
+// and updated its counter.
+// Neo N3 networks are indexed by their own block task, see startN3BlockTask.
+func Run(ctx context.Context, s db.Store, height int, selection Selection) {
+	storage = s
+
+	if config.GetNetwork() == config.NetworkN3 {
+		initN3Task(height)
+	} else {
+		log.Printf("Init addr asset cache.")
+
+		// Init cache to speed up db queries
+		addrAssetInfo := storage.GetAddrAssetInfo()
+		cache.LoadAddrAssetInfo(addrAssetInfo)
+		initTask(height)
+	}
+
+	newScheduler(ctx, height, selection).run()
+	log.Printf("All tasks stopped.\n")
+}
+
+// LastHeight returns the highest block index persisted for the configured network.
+func LastHeight(s db.Store) int {
+	if config.GetNetwork() == config.NetworkN3 {
+		return s.GetN3LastHeight()
+	}
+	return s.GetLastHeight()
 }
 
 func initTask(dbHeight int) {
-	blockBuffer = buffer.NewBuffer(dbHeight)
-	bestHeight := rpc.RefreshServers()
+	bestHeight := rpc.RefreshServers(context.Background())
 
 	log.Printf("Current params for block persistance:\n")
 	log.Printf("\tdb block height = %d\n", dbHeight)
 	log.Printf("\trpc best height = %d\n", bestHeight)
 }
+
+// sleep pauses the current goroutine for d, or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Rounds of rpc calls whose retries all failed are repeated after a delay,
+// which doubles from minRoundDelay up to maxRoundDelay.
+const (
+	minRoundDelay = time.Second
+	maxRoundDelay = time.Minute
+)
+
+// roundDelay returns the delay before the given round, 0 for the first one.
+func roundDelay(round int) time.Duration {
+	if round == 0 {
+		return 0
+	}
+
+	d := minRoundDelay << uint(round-1)
+	if d > maxRoundDelay || d <= 0 {
+		return maxRoundDelay
+	}
+	return d
+}
+
+// invokeScript returns result of the 'invokescript' rpc call, or nil if the node failed to run it.
+// Calls no server responded to are repeated with back-off until one does.
+func invokeScript(minHeight int, scripts string) *rpc.RawSmartContractCallResult {
+	for round := 0; ; round++ {
+		time.Sleep(roundDelay(round))
+
+		// Queries are part of handling already fetched transactions,
+		// so they are never interrupted by shutdown.
+		result, err := rpc.SmartContractRPCCall(context.Background(), minHeight, scripts)
+
+		var transportErr *rpc.TransportError
+		if errors.As(err, &transportErr) {
+			log.With(log.Fields{"height": minHeight, "round": round}).Errorf("No server responded to invokescript, retrying: %v\n", err)
+			continue
+		}
+		if err != nil {
+			log.With(log.Fields{"height": minHeight, "script": scripts}).Errorf("Failed to invoke script: %v\n", err)
+		}
+
+		return result
+	}
+}
+
+// fetchAppLogs stores application logs of txs by store.
+// Logs failed to be fetched are requested again with back-off until all are stored or ctx is cancelled,
+// since the task waits for the log of every queued transaction.
+func fetchAppLogs(ctx context.Context, task string, txs []*tx.Transaction, store func(txID string, appLog *rpc.RawApplicationLogResult)) {
+	for round := 0; len(txs) > 0; round++ {
+		if sleep(ctx, roundDelay(round)); ctx.Err() != nil {
+			return
+		}
+
+		txIDs := make([]string, len(txs))
+		for i, t := range txs {
+			txIDs[i] = t.TxID
+		}
+
+		appLogResults, err := rpc.GetApplicationLogs(ctx, int(txs[len(txs)-1].BlockIndex), txIDs)
+
+		missing := []*tx.Transaction{}
+		for i, t := range txs {
+			if i < len(appLogResults) && appLogResults[i] != nil {
+				store(t.TxID, appLogResults[i])
+			} else {
+				missing = append(missing, t)
+			}
+		}
+		txs = missing
+
+		if len(txs) > 0 && ctx.Err() == nil {
+			log.With(log.Fields{"task": task, "txid": txs[0].TxID, "missing": len(txs), "round": round}).
+				Errorf("Failed to fetch application logs, retrying: %v\n", err)
+		}
+	}
+}
@@ -1,35 +1,18 @@
 /*
-To restart this task from beginning, execute the following sqls:
-
-TRUNCATE TABLE `utxo`;
-DELETE FROM `addr_asset` WHERE LENGTH(`asset_id`) = 66;
-DELETE FROM `addr_tx` WHERE `asset_type` = 'asset';
-UPDATE `counter` SET
-    `last_tx_pk` = 0,
-    `cnt_tx_reg` = 0,
-    `cnt_tx_miner` = 0,
-    `cnt_tx_issue` = 0,
-    `cnt_tx_invocation` = 0,
-    `cnt_tx_contract` = 0,
-    `cnt_tx_claim` = 0,
-    `cnt_tx_publish` = 0,
-	`cnt_tx_enrollment` = 0
-WHERE `id` = 1;
-UPDATE `asset` SET `addresses` = 0, `available` = 0, `transactions` = 0;
-UPDATE `address` SET `trans_asset` = 0;
-TRUNCATE TABLE `asset_tx`;
-UPDATE `counter` SET `last_asset_tx_pk` = 0 WHERE `id` = 1;
+To restart this task from beginning, stop the indexer and run:
+
+./squirrel reset -yes tx
 
 */
 
 package tasks
 
 import (
+	"context"
 	"fmt"
 	"math/big"
-	"squirrel/db"
 	"squirrel/log"
-	"squirrel/mail"
+	"squirrel/notify"
 	"squirrel/tx"
 	"time"
 )
@@ -49,21 +32,27 @@ type txInfo struct {
 	vouts []*tx.TransactionVout
 }
 
-func startTxTask() {
-	txChan := make(chan txInfo, txChanSize)
-	nextPK := db.GetLastTxPkCounter() + 1
+func startTxTask(r *runner) {
+	txChan := make(chan txInfo, r.channelSize(txChanSize))
+	nextPK := storage.GetLastTxPkCounter() + 1
+	pipeline.trackQueue("tx", func() int { return len(txChan) })
 
-	go fetchTx(txChan, nextPK)
-	go handleTx(txChan)
+	r.goTask("fetch_tx", func() { fetchTx(r.ctx, txChan, nextPK) })
+	r.goTask("handle_tx", func() { handleTx(txChan) })
 }
 
-func fetchTx(txChan chan<- txInfo, nextPK uint) {
-	defer mail.AlertIfErr()
+func fetchTx(ctx context.Context, txChan chan<- txInfo, nextPK uint) {
+	defer close(txChan)
+	defer notify.AlertIfErr()
 
 	for {
-		txs := db.GetTxs(nextPK, 1000, "")
+		if ctx.Err() != nil {
+			return
+		}
+
+		txs := storage.GetTxs(nextPK, 1000, "")
 		if len(txs) == 0 {
-			time.Sleep(2 * time.Second)
+			sleep(ctx, 2*time.Second)
 			continue
 		}
 
@@ -74,23 +63,27 @@ func fetchTx(txChan chan<- txInfo, nextPK uint) {
 			txIDs = append(txIDs, tx.TxID)
 		}
 
-		vinMap, voutMap, err := db.GetVinVout(txIDs)
+		vinMap, voutMap, err := storage.GetVinVout(txIDs)
 		if err != nil {
 			panic(err)
 		}
 
 		for _, tx := range txs {
-			txChan <- txInfo{
+			select {
+			case txChan <- txInfo{
 				tx:    tx,
 				vins:  vinMap[tx.TxID],
 				vouts: voutMap[tx.TxID],
+			}:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}
 }
 
 func handleTx(txChan <-chan txInfo) {
-	defer mail.AlertIfErr()
+	defer notify.AlertIfErr()
 
 	// txs := []*tx.Transaction{}
 	// vins := make(map[string][]*tx.TransactionVin)
@@ -104,7 +97,7 @@ func handleTx(txChan <-chan txInfo) {
 	// 		vouts[txInfo.tx.TxID] = txInfo.vouts
 
 	// 		if len(txs) >= 30 {
-	// 			err := db.ApplyVinsVouts(txs, vins, vouts)
+	// 			err := storage.ApplyVinsVouts(txs, vins, vouts)
 	// 			if err != nil {
 	// 				panic(err)
 	// 			}
@@ -117,7 +110,7 @@ func handleTx(txChan <-chan txInfo) {
 
 	// 	case <-time.After(time.Second):
 	// 		if len(txs) > 0 {
-	// 			err := db.ApplyVinsVouts(txs, vins, vouts)
+	// 			err := storage.ApplyVinsVouts(txs, vins, vouts)
 	// 			if err != nil {
 	// 				panic(err)
 	// 			}
@@ -135,12 +128,13 @@ func handleTx(txChan <-chan txInfo) {
 		vins := txInfo.vins
 		vouts := txInfo.vouts
 
-		err := db.ApplyVinsVouts(tx, vins, vouts)
+		err := storage.ApplyVinsVouts(tx, vins, vouts)
 		if err != nil {
 			continue
 			// panic(err)
 		}
 
+		pipeline.setHeight("tx", tx.BlockIndex)
 		showTxProgress(tx.ID)
 	}
 }
@@ -148,8 +142,9 @@ func handleTx(txChan <-chan txInfo) {
 func showTxProgress(currentTxPk uint) {
 	if maxTxPK == 0 || TxMaxPkShouldRefresh {
 		TxMaxPkShouldRefresh = false
-		maxTxPK = db.GetHighestTxPk()
+		maxTxPK = storage.GetHighestTxPk()
 	}
+	pipeline.setPk("tx", currentTxPk, maxTxPK)
 
 	now := time.Now()
 	if tProgress.LastOutputTime == (time.Time{}) {
@@ -183,6 +178,6 @@ func showTxProgress(currentTxPk uint) {
 		}
 
 		msg := fmt.Sprintf("Init time: %v\nEnd Time: %v\n", nProgress.InitTime, time.Now())
-		mail.SendNotify("Transactions Fully Synced", msg)
+		notify.Send("Transactions Fully Synced", msg)
 	}
 }
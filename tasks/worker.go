@@ -36,3 +36,10 @@ func (manager *Worker) add() uint8 {
 
 	return manager.threadCnt
 }
+
+func (manager *Worker) remove() {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.threadCnt--
+}
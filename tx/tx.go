@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/big"
 	"squirrel/asset"
+	"squirrel/config"
 	"squirrel/rpc"
 	"squirrel/smartcontract"
 	"strings"
@@ -95,9 +96,10 @@ type TransactionScripts struct {
 
 // TransactionClaims of transaction.
 type TransactionClaims struct {
-	ID   uint
-	TxID string
-	Vout uint16
+	ID         uint
+	BlockIndex uint
+	TxID       string
+	Vout       uint16
 }
 
 // AddrAssetIDTx is the bundle of address, asset_id and txid.
@@ -143,7 +145,7 @@ func ParseTxs(rawBlocks []*rpc.RawBlock) *Bulk {
 			txs.TXVouts = appendTxVout(txs.TXVouts, &rawTx)
 			txs.TXScripts = appendTxScripts(txs.TXScripts, &rawTx)
 			txs.Assets = appendAsset(rawBlock, txs.Assets, &rawTx)
-			txs.Claims = appendClaims(txs.Claims, &rawTx)
+			txs.Claims = appendClaims(txs.Claims, rawBlock.Index, &rawTx)
 		}
 	}
 
@@ -254,11 +256,12 @@ func appendAsset(rawBlock *rpc.RawBlock, assets []*asset.Asset, rawTx *rpc.RawTx
 	return assets
 }
 
-func appendClaims(claims []*TransactionClaims, rawTx *rpc.RawTx) []*TransactionClaims {
+func appendClaims(claims []*TransactionClaims, blockIndex uint, rawTx *rpc.RawTx) []*TransactionClaims {
 	for _, rawClaim := range rawTx.Claims {
 		claim := TransactionClaims{
-			TxID: rawClaim.TxID,
-			Vout: rawClaim.Vout,
+			BlockIndex: blockIndex,
+			TxID:       rawClaim.TxID,
+			Vout:       rawClaim.Vout,
 		}
 		claims = append(claims, &claim)
 	}
@@ -281,9 +284,10 @@ func parseAssetFromRegisterTransaction(blockIndex uint, rawTx *rpc.RawTx) *asset
 		Frozen:     false,
 	}
 
-	if newAsset.AssetID == asset.NEOAssetID {
+	profile := config.GetProfile()
+	if newAsset.AssetID == profile.NEOAssetID {
 		newAsset.Name = asset.NEO
-	} else if newAsset.AssetID == asset.GASAssetID {
+	} else if newAsset.AssetID == profile.GASAssetID {
 		newAsset.Name = asset.GAS
 	}
 
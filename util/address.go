@@ -5,12 +5,28 @@ import (
 	"reflect"
 )
 
+const (
+	// addressVersion is the address version byte of Neo Legacy, addresses start with 'A'.
+	addressVersion = 0x17
+	// n3AddressVersion is the address version byte of Neo N3, addresses start with 'N'.
+	n3AddressVersion = 0x35
+)
+
 // GetAddressFromScriptHash returns base58 encoded address.
 func GetAddressFromScriptHash(scriptHash []byte) string {
+	return encodeAddress(addressVersion, scriptHash)
+}
+
+// GetN3AddressFromScriptHash returns base58 encoded Neo N3 address.
+func GetN3AddressFromScriptHash(scriptHash []byte) string {
+	return encodeAddress(n3AddressVersion, scriptHash)
+}
+
+func encodeAddress(version byte, scriptHash []byte) string {
 	if len(scriptHash) == 0 {
 		return ""
 	}
-	scriptHashDecode := append([]byte{0x17}, scriptHash...)
+	scriptHashDecode := append([]byte{version}, scriptHash...)
 	scriptHashDecode = append(scriptHashDecode, Hash256(scriptHashDecode)[0:4]...)
 	addr := EncodeBase58(scriptHashDecode)
 	return addr
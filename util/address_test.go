@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/hex"
 	"testing"
 )
 
@@ -21,3 +22,11 @@ func TestAddrConvertion(t *testing.T) {
 		t.Error("Address convertion failed")
 	}
 }
+
+func TestN3Address(t *testing.T) {
+	// Script hash of NEO native contract, in serialized (little-endian) order.
+	scriptHash, _ := hex.DecodeString("f563ea40bc283d4d0e05c48ea305b3f2a07340ef")
+	if addr := GetN3AddressFromScriptHash(scriptHash); addr != "NiHURyS83nX2mpxtA7xq84cGxVbHojj5Wc" {
+		t.Errorf("Expected N3 address of NEO contract, got %s", addr)
+	}
+}
@@ -99,3 +99,13 @@ func BigFloatToString(value *big.Float) string {
 
 	return valueStr
 }
+
+// LEBytesToBigInt returns the value of a little-endian two's complement integer,
+// which is how NeoVM serializes integers.
+func LEBytesToBigInt(data []byte) *big.Int {
+	z := new(big.Int).SetBytes(ReverseBytes(data))
+	if len(data) > 0 && data[len(data)-1]&0x80 != 0 {
+		z.Sub(z, new(big.Int).Lsh(big.NewInt(1), uint(len(data)*8)))
+	}
+	return z
+}
@@ -0,0 +1,135 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"squirrel/config"
+	"squirrel/log"
+	"time"
+)
+
+const (
+	// dispatchInterval is the time to wait for new events once all pending ones are dispatched.
+	dispatchInterval = time.Second
+	// dispatchBatch is the max number of events dispatched at once.
+	dispatchBatch = 100
+	// maxErrorLength is the length of watch_event.last_error.
+	maxErrorLength = 255
+)
+
+// Dispatch posts pending events to their webhooks until ctx is cancelled.
+// Events are retried with exponential backoff until their webhooks respond with 2xx,
+// so they are not in order once retried.
+func Dispatch(ctx context.Context, store Store) {
+	log.Printf("Watchlist webhook dispatcher started\n")
+
+	for ctx.Err() == nil {
+		if dispatch(ctx, store) == dispatchBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(dispatchInterval):
+		}
+	}
+}
+
+// dispatch posts a batch of pending events and returns their number.
+func dispatch(ctx context.Context, store Store) int {
+	c := config.GetWatchlistConfig()
+	if !c.Enabled {
+		return 0
+	}
+
+	events, err := store.GetPendingWatchEvents(time.Now().Unix(), dispatchBatch)
+	if err != nil {
+		log.With(log.Fields{"task": "watchlist"}).Errorf("Failed to get pending events: %v\n", err)
+		return 0
+	}
+
+	client := &http.Client{Timeout: time.Duration(c.Webhook.Timeout) * time.Second}
+
+	for _, e := range events {
+		if ctx.Err() != nil {
+			break
+		}
+
+		err := post(ctx, client, c.Webhook, e)
+		now := time.Now().Unix()
+
+		if err == nil {
+			var marked bool
+			// Rolled back after it was read, revert what receivers already got.
+			if marked, err = store.MarkWatchEventDelivered(e.ID, now); err == nil && !marked {
+				err = store.RevertWatchEvent(e)
+			}
+		} else {
+			attempts := e.Attempts + 1
+			log.With(log.Fields{"task": "watchlist", "event": e.ID, "attempts": attempts}).Warnf("Failed to deliver event: %v\n", err)
+
+			lastError := err.Error()
+			if len(lastError) > maxErrorLength {
+				lastError = lastError[:maxErrorLength]
+			}
+			err = store.RetryWatchEvent(e.ID, attempts, now+backoff(attempts, c.Webhook.MaxBackoff), lastError)
+		}
+
+		if err != nil {
+			log.With(log.Fields{"task": "watchlist", "event": e.ID}).Errorf("Failed to update event: %v\n", err)
+		}
+	}
+
+	return len(events)
+}
+
+// backoff returns the number of seconds to wait before the next attempt, which doubles up to max.
+func backoff(attempts int, max int) int64 {
+	wait := int64(1)
+	for i := 1; i < attempts && wait < int64(max); i++ {
+		wait *= 2
+	}
+
+	if wait > int64(max) {
+		return int64(max)
+	}
+	return wait
+}
+
+// post sends e to its webhook, or the default one.
+func post(ctx context.Context, client *http.Client, c config.WatchWebhookConfig, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	url := e.Webhook
+	if url == "" {
+		url = c.URL
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"squirrel/config"
+	"testing"
+)
+
+// memStore keeps events in memory.
+type memStore struct {
+	events []*Event
+}
+
+func (m *memStore) GetWatchedAddresses() ([]*Entry, error) {
+	return []*Entry{}, nil
+}
+
+func (m *memStore) GetPendingWatchEvents(now int64, limit int) ([]*Event, error) {
+	events := []*Event{}
+	for _, e := range m.events {
+		if e != nil && e.DeliveredAt == 0 && e.NextAttemptAt <= now && len(events) < limit {
+			copied := *e
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func (m *memStore) MarkWatchEventDelivered(id uint, deliveredAt int64) (bool, error) {
+	e := m.events[id-1]
+	if e == nil {
+		return false, nil
+	}
+	e.Attempts++
+	e.DeliveredAt = deliveredAt
+	return true, nil
+}
+
+func (m *memStore) RevertWatchEvent(e *Event) error {
+	reverting := e.Reverting()
+	reverting.ID = uint(len(m.events) + 1)
+	m.events = append(m.events, reverting)
+	return nil
+}
+
+func (m *memStore) RetryWatchEvent(id uint, attempts int, nextAttemptAt int64, lastError string) error {
+	e := m.events[id-1]
+	e.Attempts = attempts
+	e.NextAttemptAt = nextAttemptAt
+	e.LastError = lastError
+	return nil
+}
+
+func loadConfig(t *testing.T, webhook string) func() {
+	dir, err := ioutil.TempDir("", "squirrel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := `{
+		"driver": "sqlite",
+		"database": "squirrel.db",
+		"rpc_url": ["http://127.0.0.1:10332"],
+		"workers": 1,
+		"watchlist": {
+			"enabled": true,
+			"addresses": ["AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"],
+			"webhook": {"url": "` + webhook + `", "headers": {"Authorization": "Bearer secret"}}
+		}
+	}`
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		os.RemoveAll(dir)
+		os.Remove("error.log")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	received := []Event{}
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Missing configured header")
+		}
+		if fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	cleanup := loadConfig(t, srv.URL)
+	defer cleanup()
+
+	store := &memStore{events: []*Event{
+		{ID: 1, Address: "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", AssetType: "nep5", TxID: "0x01", Value: "-2.5"},
+	}}
+
+	if entries, err := Load(store); err != nil || len(entries) != 1 {
+		t.Fatalf("Expected the address of config to be watched, got %v(err=%v)", entries, err)
+	}
+	if _, ok := Match("AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", "0x01"); !ok {
+		t.Fatal("Expected all assets of the address to be watched")
+	}
+
+	// The first attempt fails and is retried a second later.
+	if n := dispatch(context.Background(), store); n != 1 {
+		t.Fatalf("Expected 1 event dispatched, got %d", n)
+	}
+	e := store.events[0]
+	if e.Attempts != 1 || e.DeliveredAt != 0 || e.LastError == "" || e.NextAttemptAt == 0 {
+		t.Fatalf("Expected a failed attempt, got %+v", e)
+	}
+
+	e.NextAttemptAt = 0
+	dispatch(context.Background(), store)
+	if e.Attempts != 2 || e.DeliveredAt == 0 {
+		t.Fatalf("Expected the event to be delivered, got %+v", e)
+	}
+	if len(received) != 1 || received[0].ID != 1 || received[0].Value != "-2.5" {
+		t.Fatalf("Unexpected events received %+v", received)
+	}
+}
+
+func TestDispatchRolledBack(t *testing.T) {
+	received := []Event{}
+	var store *memStore
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received = append(received, e)
+		// The block of the event is rolled back while it is being delivered.
+		store.events[0] = nil
+	}))
+	defer srv.Close()
+
+	cleanup := loadConfig(t, srv.URL)
+	defer cleanup()
+
+	store = &memStore{events: []*Event{
+		{ID: 1, Address: "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", AssetType: "nep5", TxID: "0x01", Value: "-2.5"},
+	}}
+
+	dispatch(context.Background(), store)
+	if len(store.events) != 2 {
+		t.Fatalf("Expected the delivered event to be reverted, got %d events", len(store.events))
+	}
+
+	dispatch(context.Background(), store)
+	if len(received) != 2 || received[1].ID != 2 || received[1].Reverts != 1 || received[1].Value != "2.5" {
+		t.Fatalf("Unexpected events received %+v", received)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, expected := range map[int]int64{1: 1, 2: 2, 5: 16, 20: 600} {
+		if wait := backoff(attempts, 600); wait != expected {
+			t.Errorf("Expected backoff %d after %d attempts, got %d", expected, attempts, wait)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package watch notifies balance changes of watched addresses to webhooks.
+//
+// Events are recorded in the same transactions as the transfers causing them,
+// and delivered at least once by Dispatch, so receivers should deduplicate them by id.
+package watch
+
+import (
+	"math/big"
+	"squirrel/config"
+	"squirrel/util"
+	"strings"
+	"sync"
+)
+
+// Entry is a watched address.
+type Entry struct {
+	Address string `json:"address"`
+	// AssetIDs limits events to the given assets, events of all assets are sent if empty.
+	AssetIDs []string `json:"asset_ids"`
+	// Webhook overrides the url of config 'watchlist.webhook'.
+	Webhook string `json:"webhook"`
+	// Config is true for addresses watched by config, which cannot be changed through the api.
+	Config bool `json:"config"`
+}
+
+// Event is a balance change of a watched address caused by a transaction.
+type Event struct {
+	ID      uint   `json:"id"`
+	Address string `json:"address"`
+	// AssetType is one of 'asset', 'nep5' or 'nft'.
+	AssetType  string `json:"asset_type"`
+	AssetID    string `json:"asset_id"`
+	TokenID    string `json:"token_id,omitempty"`
+	TxID       string `json:"txid"`
+	BlockIndex uint   `json:"block_index"`
+	BlockTime  uint64 `json:"block_time"`
+	// From and To are the parties of nep5 and nft transfers.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Value is the balance change of Address, negative if spent or sent.
+	Value string `json:"value"`
+	// Reverts is the id of a delivered event whose block was rolled back, Value is its negated value.
+	Reverts uint `json:"reverts,omitempty"`
+
+	// Delivery state, not part of the payload.
+	Webhook       string `json:"-"`
+	Attempts      int    `json:"-"`
+	NextAttemptAt int64  `json:"-"`
+	DeliveredAt   int64  `json:"-"`
+	LastError     string `json:"-"`
+}
+
+// Reverting returns the pending event which reverts e.
+func (e *Event) Reverting() *Event {
+	reverting := *e
+	reverting.ID = 0
+	reverting.Reverts = e.ID
+	reverting.Attempts = 0
+	reverting.NextAttemptAt = 0
+	reverting.DeliveredAt = 0
+	reverting.LastError = ""
+
+	switch {
+	case strings.HasPrefix(e.Value, "-"):
+		reverting.Value = e.Value[1:]
+	case strings.Trim(e.Value, "0.") != "":
+		reverting.Value = "-" + e.Value
+	}
+
+	return &reverting
+}
+
+var (
+	mu      sync.RWMutex
+	watched = map[string]*Entry{}
+)
+
+// NormalizeAssetID returns assetID in the form stored by tasks,
+// i.e. 0x prefixed global asset ids and script hashes of nep5 and nft contracts, both lower-cased.
+func NormalizeAssetID(assetID string) (string, bool) {
+	if !config.WatchAssetIDValid(assetID) {
+		return "", false
+	}
+	return strings.ToLower(assetID), true
+}
+
+// Valid checks the address, asset ids and webhook of e, and normalizes its asset ids.
+func (e *Entry) Valid() bool {
+	if !util.AddressValid(e.Address) {
+		return false
+	}
+	if e.Webhook != "" && !config.WatchWebhookValid(e.Webhook) {
+		return false
+	}
+
+	for i, assetID := range e.AssetIDs {
+		id, ok := NormalizeAssetID(assetID)
+		if !ok {
+			return false
+		}
+		e.AssetIDs[i] = id
+	}
+
+	return true
+}
+
+// Watches reports if e covers assetID.
+func (e *Entry) Watches(assetID string) bool {
+	if len(e.AssetIDs) == 0 {
+		return true
+	}
+
+	for _, id := range e.AssetIDs {
+		if id == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// Set replaces the watched addresses.
+func Set(entries []*Entry) {
+	m := make(map[string]*Entry, len(entries))
+	for _, e := range entries {
+		m[e.Address] = e
+	}
+
+	mu.Lock()
+	watched = m
+	mu.Unlock()
+}
+
+// Match returns the entry watching the asset of address.
+func Match(address string, assetID string) (*Entry, bool) {
+	mu.RLock()
+	e, ok := watched[address]
+	mu.RUnlock()
+
+	if !ok || !e.Watches(assetID) {
+		return nil, false
+	}
+	return e, true
+}
+
+// NewEvent returns the event of the balance change of a watched address, or nil if the address is not watched.
+func NewEvent(address string, assetType string, assetID string, value *big.Float) *Event {
+	e, ok := Match(address, assetID)
+	if !ok || value.Sign() == 0 {
+		return nil
+	}
+
+	return &Event{
+		Address:   address,
+		AssetType: assetType,
+		AssetID:   assetID,
+		Value:     util.BigFloatToString(value),
+		Webhook:   e.Webhook,
+	}
+}
+
+// Store keeps watched addresses and events.
+type Store interface {
+	GetWatchedAddresses() ([]*Entry, error)
+	GetPendingWatchEvents(now int64, limit int) ([]*Event, error)
+	// MarkWatchEventDelivered reports false if the event was removed by a rollback meanwhile.
+	MarkWatchEventDelivered(id uint, deliveredAt int64) (bool, error)
+	RetryWatchEvent(id uint, attempts int, nextAttemptAt int64, lastError string) error
+	// RevertWatchEvent records the event which reverts e.
+	RevertWatchEvent(e *Event) error
+}
+
+// Load watches addresses of config and the ones stored through the api, and returns them.
+// Config takes precedence for addresses in both. Nothing is watched if the watchlist is disabled.
+func Load(store Store) ([]*Entry, error) {
+	c := config.GetWatchlistConfig()
+	if !c.Enabled {
+		Set(nil)
+		return []*Entry{}, nil
+	}
+
+	stored, err := store.GetWatchedAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Entry{}
+	inConfig := map[string]bool{}
+
+	for _, w := range c.Addresses {
+		e := &Entry{
+			Address:  w.Address,
+			AssetIDs: append([]string{}, w.AssetIDs...),
+			Webhook:  w.Webhook,
+			Config:   true,
+		}
+		// Configs are checked on load, only asset ids are normalized.
+		e.Valid()
+		entries = append(entries, e)
+		inConfig[e.Address] = true
+	}
+
+	for _, e := range stored {
+		if !inConfig[e.Address] {
+			entries = append(entries, e)
+		}
+	}
+
+	Set(entries)
+
+	return entries, nil
+}